@@ -0,0 +1,66 @@
+package main
+
+import (
+	"image"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/world"
+)
+
+const (
+	chunkMargin = 1 // chunks around the screen requested ahead of the camera
+	chunkKeep   = 3 // chunks around the screen kept in the store, farther ones are evicted
+)
+
+type chunkState int
+
+const (
+	chunkRequested chunkState = iota + 1
+	chunkLoaded
+)
+
+// streamChunks - requests the chunks around the screen which are not loaded yet and evicts the far ones
+func (g *clientGame) streamChunks(rect image.Rectangle) {
+	view := tilesOf(rect)
+	for _, id := range world.ChunksIn(view.Inset(-chunkMargin * world.ChunkSize)) {
+		if _, ok := g.chunks[id]; !ok {
+			g.requestChunk(id)
+		}
+	}
+	if g.spectator {
+		// replays cannot request the evicted chunks again
+		return
+	}
+	keep := view.Inset(-chunkKeep * world.ChunkSize)
+	for id := range g.chunks {
+		if !id.Rect().Overlaps(keep) {
+			delete(g.chunks, id)
+			g.store.RemoveTiles(id.Rect())
+		}
+	}
+}
+
+// tilesOf - tiles of the screen, the screen rect includes its max tiles
+func tilesOf(rect image.Rectangle) image.Rectangle {
+	return image.Rectangle{Min: rect.Min, Max: rect.Max.Add(image.Pt(1, 1))}
+}
+
+func (g *clientGame) requestChunk(id world.ChunkId) {
+	g.chunks[id] = chunkRequested
+	g.enDispatch(game.NewMapLoadAction(id.Rect(), g.playerId))
+}
+
+// chunkLoaded - the server answers with a chunk per MapLoadSuccess, the bounds tell which one
+func (g *clientGame) chunkLoaded(payload game.MapLoadSuccessPayload) {
+	// chunks evicted before they arrived are marked too, the next streaming evicts them again
+	g.chunks[world.ChunkOf(image.Pt(payload.MinX, payload.MinY))] = chunkLoaded
+}
+
+// retryChunks - requests again the chunks which have not arrived
+func (g *clientGame) retryChunks() {
+	for id, state := range g.chunks {
+		if state == chunkRequested {
+			g.requestChunk(id)
+		}
+	}
+}
@@ -1,18 +1,27 @@
 package main
 
 import (
+	"fmt"
 	"image"
 	"image/color"
 	"log"
+	"slices"
+	"time"
 
 	"github.com/bmcszk/fogofgo/pkg/convert"
 	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/world"
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
 const (
-	cameraSpeed = 2
+	cameraSpeed   = 2
+	wreckTTL      = 60  // ticks a destroyed unit stays on the screen
+	noticeTTL     = 180 // ticks an error from the server stays on the screen
+	mapRetryDelay = 120 // ticks before a failed map load is requested again
 )
 
 type clientGame struct {
@@ -24,6 +33,23 @@ type clientGame struct {
 	selectionBox     *image.Rectangle
 	enDispatch       game.DispatchFunc
 	screen           *screen
+	wrecks           []*wreck
+	spectator        bool // sees all units, used by replays
+	notice           string
+	noticeTTL        int
+	mapRetry         int           // ticks until the failed map load is requested again, 0 when none failed
+	latency          time.Duration // round trip of the last answered request
+	chunks           map[world.ChunkId]chunkState
+	fog              *game.Fog
+	formation        game.Formation     // of the selected units moving together
+	resyncs          chan chan struct{} // resyncs requested by the network goroutine, done when closed
+}
+
+// wreck - remains of a destroyed unit
+type wreck struct {
+	position game.PF
+	size     image.Point
+	ttl      int
 }
 
 func newClientGame(playerId game.PlayerIdType, store game.Store, enDispatch game.DispatchFunc) *clientGame {
@@ -34,6 +60,10 @@ func newClientGame(playerId game.PlayerIdType, store game.Store, enDispatch game
 		GameLogic:  g,
 		enDispatch: enDispatch,
 		screen:     &emptyScreen,
+		chunks:     make(map[world.ChunkId]chunkState),
+		formation:  game.FormationBox,
+		fog:        game.NewFog(),
+		resyncs:    make(chan chan struct{}),
 	}
 
 	return cg
@@ -41,13 +71,61 @@ func newClientGame(playerId game.PlayerIdType, store game.Store, enDispatch game
 
 func (g *clientGame) HandleAction(action game.Action, dispatch game.DispatchFunc) {
 	log.Printf("client handle %s", action.GetType())
+	switch a := action.(type) {
+	case game.UnitDestroyedAction:
+		g.addWreck(a.Payload.UnitId)
+		g.fog.Forget(a.Payload.UnitId)
+	case game.ErrorAction:
+		g.notify(a.Payload.Action, a.Payload.Code, a.Payload.Message)
+		if a.Payload.Action == game.MapLoadActionType && a.Payload.Code.Retryable() {
+			g.mapRetry = mapRetryDelay
+		}
+	case game.ActionRejectedAction:
+		g.notify(a.Payload.Action, a.Payload.Code, a.Payload.Message)
+	case game.MapLoadSuccessAction:
+		g.chunkLoaded(a.Payload)
+	case game.UnitLeftVisionAction:
+		g.rememberUnit(a.Payload.UnitId)
+	case game.UnitEnteredVisionAction:
+		g.fog.Forget(a.Payload.Unit.Id)
+	}
 	g.GameLogic.HandleAction(action, dispatch)
 	switch action.(type) {
-	case game.SpawnUnitAction, game.MoveStepAction, game.PlayerJoinSuccessAction, game.MapLoadSuccessAction:
+	case game.SpawnUnitAction, game.MoveStepAction, game.PlayerJoinSuccessAction, game.MapLoadSuccessAction,
+		game.UnitEnteredVisionAction, game.UnitLeftVisionAction:
 		g.updateVisibility()
 	}
 }
 
+// requestResync - resyncs on the game loop, which owns the state, returns when done
+func (g *clientGame) requestResync() {
+	done := make(chan struct{})
+	g.resyncs <- done
+	<-done
+}
+
+// handleResync - resync requested by the network goroutine
+func (g *clientGame) handleResync() {
+	select {
+	case done := <-g.resyncs:
+		g.resync()
+		close(done)
+	default:
+	}
+}
+
+// resync - drops the state of the lost session, the server sends it again after rejoin
+func (g *clientGame) resync() {
+	store := game.NewStoreImpl()
+	g.store = store
+	g.GameLogic = game.NewGameLogic(store)
+	g.fog = game.NewFog()
+	rect := g.screen.rect
+	g.screen = newScreen(rect, store.GetTilesByRect(tilesOf(rect)))
+	g.chunks = make(map[world.ChunkId]chunkState)
+	g.streamChunks(rect)
+}
+
 func (g *clientGame) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
 	/* // Calculate the desired screen size based on the size of the map
 	sw := len(g.Map.Tiles[0]) * tileSize
@@ -71,8 +149,8 @@ func (g *clientGame) Layout(outsideWidth, outsideHeight int) (screenWidth, scree
 
 	// If the map is not loaded, load it
 	if !g.screen.is(rect) {
-		g.queueMapLoadActions(rect)
-		g.screen = newScreen(rect, g.store.GetTilesByRect(rect))
+		g.streamChunks(rect)
+		g.screen = newScreen(rect, g.store.GetTilesByRect(tilesOf(rect)))
 		g.updateVisibility()
 	}
 
@@ -83,6 +161,10 @@ func (g *clientGame) Draw(enScreen *ebiten.Image) {
 	// Draw the map
 	g.screen.draw(enScreen, g.centerX+g.cameraX, g.centerY+g.cameraY)
 
+	g.drawGhosts(enScreen)
+	g.drawWrecks(enScreen)
+	g.drawOrders(enScreen)
+
 	// Draw the selection box
 	if g.selectionBox != nil {
 		r := *g.selectionBox
@@ -92,16 +174,69 @@ func (g *clientGame) Draw(enScreen *ebiten.Image) {
 		col := color.RGBA{0, 255, 0, 128}
 		vector.DrawFilledRect(enScreen, float32(x1), float32(y1), float32(x2-x1), float32(y2-y1), col, false)
 	}
+
+	g.drawHUD(enScreen)
+}
+
+// drawHUD - player's resources and production of the selected buildings in the corner of the screen
+func (g *clientGame) drawHUD(enScreen *ebiten.Image) {
+	player, ok := g.store.GetPlayer(g.playerId)
+	if !ok {
+		return
+	}
+	r := player.Resources
+	hud := fmt.Sprintf("wood %d  stone %d  formation %s  ping %d ms", r.Wood, r.Stone, g.formation, g.latency.Milliseconds())
+	for _, u := range g.store.GetUnitsByPlayerId(g.playerId) {
+		if !u.Selected || !u.Building {
+			continue
+		}
+		hud += fmt.Sprintf("\n%s producing %d/%d", u.Type, len(u.Queue), game.MaxQueue)
+		for i, id := range g.produces(u) {
+			if ut, ok := g.UnitTypes().Get(id); ok {
+				hud += fmt.Sprintf("\n  %d %s  wood %d  stone %d", i+1, ut.Name, ut.Cost.Wood, ut.Cost.Stone)
+			}
+		}
+	}
+	if g.noticeTTL > 0 {
+		hud += "\n" + g.notice
+	}
+	ebitenutil.DebugPrint(enScreen, hud)
+}
+
+// notify - shows why the action did nothing
+func (g *clientGame) notify(action game.ActionType, code game.ErrorCode, message string) {
+	log.Printf("%s failed: %s %s", action, code, message)
+	g.notice = fmt.Sprintf("%s failed: %s", action, code)
+	g.noticeTTL = noticeTTL
 }
 
 func (g *clientGame) Update() error {
+	g.handleResync()
 	g.handleCameraMovement()
 	g.handleUnitSelection()
 	g.handleUnitMovement()
+	g.handleOrderKeys()
+	g.handleProduction()
 	g.updateUnits()
+	g.updateWrecks()
+	g.updateNotices()
 	return nil
 }
 
+// updateNotices - expires the error notice and retries the failed map load
+func (g *clientGame) updateNotices() {
+	if g.noticeTTL > 0 {
+		g.noticeTTL--
+	}
+	if g.mapRetry == 0 {
+		return
+	}
+	g.mapRetry--
+	if g.mapRetry == 0 {
+		g.retryChunks()
+	}
+}
+
 func (g *clientGame) handleCameraMovement() {
 	if ebiten.IsKeyPressed(ebiten.KeyArrowLeft) {
 		g.cameraX -= cameraSpeed
@@ -148,70 +283,288 @@ func (g *clientGame) updateUnitSelectionFromBox() {
 }
 
 func (g *clientGame) handleUnitMovement() {
-	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight) && ebiten.IsFocused() {
-		mx, my := ebiten.CursorPosition()
-		tileX, tileY := g.screenToWorldTiles(mx, my)
+	if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight) || !ebiten.IsFocused() {
+		return
+	}
+	// queued orders, patrols and attack-moves are given once per click
+	once := inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight)
+	queue := ebiten.IsKeyPressed(ebiten.KeyShift)
+	mx, my := ebiten.CursorPosition()
+	tileX, tileY := g.screenToWorldTiles(mx, my)
+	p := image.Pt(tileX, tileY)
+	enemy := g.enemyAt(p)
+	group := g.group(enemy)
+	if len(group) > 0 && once {
+		g.enDispatch(g.groupOrder(group, p, queue))
+	}
+	for _, u := range g.store.GetUnitsByPlayerId(g.playerId) {
+		if !u.Selected || slices.Contains(group, u.Id) {
+			continue
+		}
+		action := g.order(u, p, enemy, queue)
+		if _, ok := action.(game.OrderAction); ok && !once {
+			continue
+		}
+		g.enDispatch(action)
+	}
+}
+
+// group - selected units moving together in formation, none when the click gives them other orders
+func (g *clientGame) group(enemy *game.Unit) []game.UnitIdType {
+	if enemy != nil || ebiten.IsKeyPressed(ebiten.KeyG) || ebiten.IsKeyPressed(ebiten.KeyB) {
+		return nil
+	}
+	var ids []game.UnitIdType
+	for _, u := range g.store.GetUnitsByPlayerId(g.playerId) {
+		if u.Selected && !u.Building {
+			ids = append(ids, u.Id)
+		}
+	}
+	if len(ids) < 2 || len(ids) > game.MaxGroupSize {
+		return nil
+	}
+	return ids
+}
+
+// groupOrder - one order for the whole group, the server gives each unit its slot in the formation
+func (g *clientGame) groupOrder(ids []game.UnitIdType, p image.Point, queue bool) game.GroupMoveAction {
+	order := game.Order{Type: game.OrderMove, Point: p}
+	switch {
+	case ebiten.IsKeyPressed(ebiten.KeyA):
+		order.Type = game.OrderAttackMove
+	case ebiten.IsKeyPressed(ebiten.KeyP):
+		order.Type = game.OrderPatrol
+	}
+	return game.GroupMoveAction{
+		Type: game.GroupMoveActionType,
+		Payload: game.GroupMovePayload{
+			UnitIds:   ids,
+			Order:     order,
+			Formation: g.formation,
+			Queue:     queue,
+		},
+	}
+}
+
+// handleOrderKeys - H holds the position, S stops the selected units, F changes the formation
+func (g *clientGame) handleOrderKeys() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyF) {
+		i := slices.Index(game.Formations, g.formation)
+		g.formation = game.Formations[(i+1)%len(game.Formations)]
+	}
+	hold := inpututil.IsKeyJustPressed(ebiten.KeyH)
+	stop := inpututil.IsKeyJustPressed(ebiten.KeyS)
+	if !hold && !stop {
+		return
+	}
+	for _, u := range g.store.GetUnitsByPlayerId(g.playerId) {
+		if !u.Selected || u.Building {
+			continue
+		}
+		if stop {
+			g.enDispatch(game.StopAction{
+				Type:    game.StopActionType,
+				Payload: game.StopPayload{UnitId: u.Id},
+			})
+			continue
+		}
+		g.enDispatch(unitOrder(u, game.Order{Type: game.OrderHold}, ebiten.IsKeyPressed(ebiten.KeyShift)))
+	}
+}
+
+func unitOrder(u *game.Unit, order game.Order, queue bool) game.OrderAction {
+	return game.OrderAction{
+		Type: game.OrderActionType,
+		Payload: game.OrderPayload{
+			UnitId: u.Id,
+			Order:  order,
+			Queue:  queue,
+		},
+	}
+}
+
+// order - command for the selected unit after right-click on the tile
+func (g *clientGame) order(u *game.Unit, p image.Point, enemy *game.Unit, queue bool) game.Action {
+	switch {
+	case u.Building:
+		return game.SetRallyPointAction{
+			Type: game.SetRallyPointActionType,
+			Payload: game.SetRallyPointPayload{
+				BuildingId: u.Id,
+				Point:      p,
+			},
+		}
+	case enemy != nil:
+		return game.AttackAction{
+			Type: game.AttackActionType,
+			Payload: game.AttackPayload{
+				UnitId:   u.Id,
+				TargetId: enemy.Id,
+			},
+		}
+	case ebiten.IsKeyPressed(ebiten.KeyG):
+		return game.GatherAction{
+			Type: game.GatherActionType,
+			Payload: game.GatherPayload{
+				UnitId: u.Id,
+				Point:  p,
+			},
+		}
+	case ebiten.IsKeyPressed(ebiten.KeyB):
+		if ut, ok := g.UnitTypes().Get(u.Type); ok && len(ut.Builds) > 0 {
+			return game.BuildAction{
+				Type: game.BuildActionType,
+				Payload: game.BuildPayload{
+					UnitId:   u.Id,
+					UnitType: ut.Builds[0],
+					Point:    p,
+				},
+			}
+		}
+	case ebiten.IsKeyPressed(ebiten.KeyA):
+		return unitOrder(u, game.Order{Type: game.OrderAttackMove, Point: p}, queue)
+	case ebiten.IsKeyPressed(ebiten.KeyP):
+		return unitOrder(u, game.Order{Type: game.OrderPatrol, Point: p}, queue)
+	case queue:
+		return unitOrder(u, game.Order{Type: game.OrderMove, Point: p}, queue)
+	}
+	return game.MoveStartAction{
+		Type: game.MoveStartActionType,
+		Payload: game.MoveStartPayload{
+			UnitId: u.Id,
+			Point:  p,
+		},
+	}
+}
+
+// handleProduction - number keys queue the unit types listed in the HUD in the selected buildings
+func (g *clientGame) handleProduction() {
+	for i := range 9 {
+		if !inpututil.IsKeyJustPressed(ebiten.Key1 + ebiten.Key(i)) {
+			continue
+		}
 		for _, u := range g.store.GetUnitsByPlayerId(g.playerId) {
-			if !u.Selected {
+			produces := g.produces(u)
+			if !u.Selected || i >= len(produces) {
 				continue
 			}
-			moveStartAction := game.MoveStartAction{
-				Type: game.MoveStartActionType,
-				Payload: game.MoveStartPayload{
-					UnitId: u.Id,
-					Point:  image.Pt(tileX, tileY),
+			g.enDispatch(game.QueueProductionAction{
+				Type: game.QueueProductionActionType,
+				Payload: game.QueueProductionPayload{
+					BuildingId: u.Id,
+					UnitType:   produces[i],
 				},
-			}
-			g.enDispatch(moveStartAction)
+			})
 		}
 	}
 }
 
-func (g *clientGame) updateUnits() {
-	for _, u := range g.store.GetAllUnits() {
-		u.Update(g.enDispatch)
+// produces - unit types the building can produce
+func (g *clientGame) produces(u *game.Unit) []game.UnitTypeIdType {
+	ut, ok := g.UnitTypes().Get(u.Type)
+	if !ok {
+		return nil
 	}
+	return ut.Produces
 }
 
-func (g *clientGame) updateVisibility() {
-	visibilityMap := g.buildVisibilityMap()
-	g.applyVisibilityMap(visibilityMap)
+// enemyAt - visible unit of another player on the tile
+func (g *clientGame) enemyAt(p image.Point) *game.Unit {
+	t, ok := g.store.GetTile(p)
+	if !ok || t.Unit == nil || !t.Visible() || t.Unit.Owner == g.playerId {
+		return nil
+	}
+	return t.Unit
 }
 
-func (g *clientGame) buildVisibilityMap() map[image.Point]bool {
-	m := make(map[image.Point]bool, 0)
+// updateUnits - local prediction of unit movement, the server sends the authoritative steps
+func (g *clientGame) updateUnits() {
+	for _, u := range g.store.GetAllUnits() {
+		u.Update(discard)
+	}
+}
 
-	g.initializeVisibilityMap(m)
-	g.updateVisibilityFromUnits(m)
+func discard(game.Action) {}
 
-	return m
+func (g *clientGame) addWreck(id game.UnitIdType) {
+	u := g.store.GetUnitById(id)
+	if u == nil {
+		return
+	}
+	g.wrecks = append(g.wrecks, &wreck{
+		position: u.Position,
+		size:     u.Size,
+		ttl:      wreckTTL,
+	})
 }
 
-func (g *clientGame) initializeVisibilityMap(m map[image.Point]bool) {
-	for _, t := range g.screen.tiles {
-		if t.Visible || t.Unit != nil {
-			m[t.Point] = false
+func (g *clientGame) updateWrecks() {
+	wrecks := g.wrecks[:0]
+	for _, w := range g.wrecks {
+		w.ttl--
+		if w.ttl > 0 {
+			wrecks = append(wrecks, w)
 		}
 	}
+	g.wrecks = wrecks
+}
+
+func (g *clientGame) drawWrecks(enScreen *ebiten.Image) {
+	cameraX, cameraY := g.centerX+g.cameraX, g.centerY+g.cameraY
+	for _, w := range g.wrecks {
+		drawWreck(w, enScreen, cameraX, cameraY)
+	}
 }
 
-func (g *clientGame) updateVisibilityFromUnits(m map[image.Point]bool) {
-	for _, unit := range g.store.GetUnitsByPlayerId(g.playerId) {
-		for _, v := range unit.ISee {
-			p := unit.Position.ImagePoint().Add(v)
+// updateVisibility - fog of the tiles on the screen from the sight of the player's units
+func (g *clientGame) updateVisibility() {
+	g.fog.Update(g.sight())
+	for p, t := range g.screen.tiles {
+		t.Fog = g.fog.State(p)
+	}
+}
+
+// sight - tiles seen by the player's units, by all units in replays
+func (g *clientGame) sight() map[image.Point]bool {
+	units := g.store.GetUnitsByPlayerId(g.playerId)
+	if g.spectator {
+		units = g.store.GetAllUnits()
+	}
+	m := make(map[image.Point]bool)
+	for _, unit := range units {
+		for _, p := range game.LineOfSight(g.store, unit) {
 			m[p] = true
 		}
 	}
+	return m
+}
+
+// rememberUnit - enemy units leaving the sight leave their ghosts behind
+func (g *clientGame) rememberUnit(id game.UnitIdType) {
+	u := g.store.GetUnitById(id)
+	if u == nil || u.Owner == g.playerId {
+		return
+	}
+	g.fog.Remember(*u)
 }
 
-func (g *clientGame) applyVisibilityMap(m map[image.Point]bool) {
-	for p, visible := range m {
-		if t, ok := g.screen.tiles[p]; ok {
-			t.Visible = visible
+// drawOrders - waypoints of the selected units
+func (g *clientGame) drawOrders(enScreen *ebiten.Image) {
+	cameraX, cameraY := g.centerX+g.cameraX, g.centerY+g.cameraY
+	for _, u := range g.store.GetUnitsByPlayerId(g.playerId) {
+		if u.Selected {
+			drawOrders(u, enScreen, cameraX, cameraY)
 		}
 	}
 }
 
+func (g *clientGame) drawGhosts(enScreen *ebiten.Image) {
+	cameraX, cameraY := g.centerX+g.cameraX, g.centerY+g.cameraY
+	for _, u := range g.fog.Ghosts() {
+		drawGhost(&u, enScreen, cameraX, cameraY)
+	}
+}
+
 func (g *clientGame) screenToWorld(screenX, screenY int) (worldX, worldY int) {
 	worldX = screenX + g.cameraX
 	worldY = screenY + g.cameraY
@@ -230,26 +583,6 @@ func (g *clientGame) worldToScreen(worldX, worldY int) (screenX, screenY int) {
 	return screenX, screenY
 }
 
-func (g *clientGame) queueMapLoadActions(rect image.Rectangle) {
-	currRect := g.screen.rect
-	if rect.Min.X < currRect.Min.X {
-		action := game.NewMapLoadAction(image.Rect(rect.Min.X, rect.Min.Y, currRect.Min.X, currRect.Max.Y), g.playerId)
-		g.enDispatch(action)
-	}
-	if rect.Min.Y < currRect.Min.Y {
-		action := game.NewMapLoadAction(image.Rect(rect.Min.X, rect.Min.Y, currRect.Max.X, currRect.Min.Y), g.playerId)
-		g.enDispatch(action)
-	}
-	if rect.Max.X > currRect.Max.X {
-		action := game.NewMapLoadAction(image.Rect(currRect.Min.X, currRect.Max.Y, rect.Max.X, rect.Max.Y), g.playerId)
-		g.enDispatch(action)
-	}
-	if rect.Max.Y > currRect.Max.Y {
-		action := game.NewMapLoadAction(image.Rect(currRect.Max.Y, currRect.Min.Y, rect.Max.X, rect.Max.Y), g.playerId)
-		g.enDispatch(action)
-	}
-}
-
 func getRect(u *game.Unit) image.Rectangle {
 	screenPosition := u.Position.Mul(tileSize).ImagePoint()
 	return image.Rectangle{
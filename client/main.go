@@ -2,6 +2,7 @@ package main
 
 import (
 	"crypto/md5"
+	"flag"
 	"image"
 	"image/color"
 	"log"
@@ -9,6 +10,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/bmcszk/fogofgo/pkg/comm"
 	"github.com/bmcszk/fogofgo/pkg/game"
@@ -18,26 +20,35 @@ import (
 )
 
 const (
-	screenWidth  = 640
-	screenHeight = 480
+	screenWidth       = 640
+	screenHeight      = 480
+	reconnectMinDelay = 500 * time.Millisecond
+	reconnectMaxDelay = 30 * time.Second
 )
 
 var (
 	tilesImage       *ebiten.Image
 	backgroundImages map[string]*ebiten.Image = make(map[string]*ebiten.Image)
+	dialer                                    = websocket.Dialer{Subprotocols: comm.Subprotocols}
 )
 
 type client struct {
 	*comm.Client
-	game *clientGame
+	game         *clientGame
+	player       game.Player
+	sessionToken string
+	roomId       game.RoomIdType
+	requests     *game.Requests
 }
 
-func newClient(id game.PlayerIdType, ws *websocket.Conn) *client {
+func newClient(player game.Player, ws *websocket.Conn) *client {
 	c := comm.NewClient(ws)
-	c.PlayerId = id
+	c.PlayerId = player.Id
 
 	return &client{
-		Client: c,
+		Client:   c,
+		player:   player,
+		requests: game.NewRequests(),
 	}
 }
 
@@ -64,6 +75,24 @@ func init() {
 }
 
 func main() {
+	roomId := flag.String("room", "", "id of the room to join, quick match when empty")
+	roomName := flag.String("create", "", "create a new room with the given name")
+	maxPlayers := flag.Int("max-players", 0, "max players of the created room")
+	aiLevels := flag.String("ai", "", "comma separated levels of the AI opponents in the created room: easy, normal or hard")
+	list := flag.Bool("list", false, "list the rooms and exit")
+	replay := flag.String("replay", "", "play back the recorded match from the file")
+	useJSON := flag.Bool("json", false, "use the JSON wire codec, easier to debug than the binary one")
+	flag.Parse()
+
+	if *useJSON {
+		dialer.Subprotocols = []string{comm.JSONProtocol}
+	}
+
+	if *replay != "" {
+		runReplay(*replay)
+		return
+	}
+
 	name := getName()
 	if name == "" {
 		os.Exit(1)
@@ -78,11 +107,27 @@ func main() {
 	if ws == nil {
 		os.Exit(1)
 	}
-	defer closeWebSocket(ws)
 
-	client := setupClient(playerId, ws)
+	if *list {
+		listRooms(comm.NewClient(ws))
+		return
+	}
+
+	player := game.Player{
+		Id:    playerId,
+		Name:  name,
+		Color: nameToColor(name),
+	}
+	client := setupClient(player, ws)
+	defer closeClient(client)
+	client.roomId = game.RoomIdType(*roomId)
 	startMessageHandler(client)
-	sendPlayerJoinAction(client, playerId, name)
+	if *roomName != "" || *aiLevels != "" {
+		client.createRoom(game.RoomConfig{Name: *roomName, MaxPlayers: *maxPlayers, AI: parseLevels(*aiLevels)})
+	} else {
+		client.joinRoom()
+	}
+	client.join()
 
 	runGame(client.game, name)
 }
@@ -91,53 +136,157 @@ func connectToServer() *websocket.Conn {
 	u := url.URL{Scheme: "ws", Host: "localhost:8000", Path: "/ws"}
 	log.Printf("connecting to %s", u.String())
 
-	ws, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	ws, _, err := dialer.Dial(u.String(), nil)
 	if err != nil {
 		log.Printf("dial error: %v", err)
 		return nil
 	}
+	log.Printf("using codec %q", ws.Subprotocol())
 	return ws
 }
 
-func closeWebSocket(ws *websocket.Conn) {
-	if err := ws.Close(); err != nil {
+func closeClient(c *client) {
+	if err := c.Close(); err != nil {
 		log.Printf("Error closing websocket: %v", err)
 	}
 }
 
-func setupClient(playerId game.PlayerIdType, ws *websocket.Conn) *client {
-	c := newClient(playerId, ws)
-	g := newClientGame(playerId, game.NewStoreImpl(), c.processNewAction)
+func setupClient(player game.Player, ws *websocket.Conn) *client {
+	c := newClient(player, ws)
+	g := newClientGame(player.Id, game.NewStoreImpl(), c.processNewAction)
 	c.game = g
 	return c
 }
 
 func startMessageHandler(c *client) {
 	go func() {
-		for c.Connected {
-			action, err := c.HandleInMessages()
-			if err != nil {
-				log.Println(err)
-				continue
+		for {
+			for c.Connected {
+				action, err := c.HandleInMessages()
+				if err != nil {
+					log.Println(err)
+					continue
+				}
+				c.handleServerAction(action)
 			}
-			c.game.HandleAction(action, c.route)
+			c.reconnect()
 		}
 	}()
 }
 
-func sendPlayerJoinAction(c *client, playerId game.PlayerIdType, name string) {
-	if err := c.Send(game.PlayerJoinAction{
-		Type: game.PlayerJoinActionType,
-		Payload: game.Player{
-			Id:    playerId,
-			Name:  name,
-			Color: nameToColor(name),
-		},
-	}); err != nil {
+func (c *client) handleServerAction(action game.Action) {
+	if rtt, ok := c.requests.Answer(action); ok {
+		c.game.latency = rtt
+	}
+	switch a := action.(type) {
+	case game.PlayerJoinSuccessAction:
+		c.sessionToken = a.Payload.SessionToken
+	case game.RoomJoinSuccessAction:
+		c.roomId = a.Payload.Id
+		log.Printf("joined room %s (%s)", a.Payload.Id, a.Payload.Name)
+	case game.RoomJoinFailedAction:
+		log.Printf("cannot join room %s: %s", a.Payload.RoomId, a.Payload.Reason)
+	case game.RoomStateAction:
+		log.Printf("room %s is %s", a.Payload.Room.Id, a.Payload.Room.State)
+	}
+	c.game.HandleAction(action, c.route)
+}
+
+// reconnect - dials the server with exponential backoff and resumes the session
+func (c *client) reconnect() {
+	backoff := comm.NewBackoff(reconnectMinDelay, reconnectMaxDelay)
+	for {
+		delay := backoff.Next()
+		log.Printf("connection lost, reconnecting in %s", delay)
+		time.Sleep(delay)
+		ws := connectToServer()
+		if ws == nil {
+			continue
+		}
+		c.Reconnect(ws)
+		// the old state is dropped before the server sends the new one
+		c.game.requestResync()
+		c.joinRoom()
+		c.join()
+		// the server ignores the requests it handled before the connection was lost
+		for _, action := range c.requests.Pending() {
+			c.send(action)
+		}
+		return
+	}
+}
+
+// join - joins the game, resumes the session when the player has already joined
+func (c *client) join() {
+	var action game.Action = game.PlayerJoinAction{
+		Type:    game.PlayerJoinActionType,
+		Payload: c.player,
+	}
+	if c.sessionToken != "" {
+		action = game.PlayerRejoinAction{
+			Type: game.PlayerRejoinActionType,
+			Payload: game.PlayerRejoinPayload{
+				Player:       c.player,
+				SessionToken: c.sessionToken,
+			},
+		}
+	}
+	c.send(action)
+}
+
+// joinRoom - joins the chosen room, the server picks one on join otherwise
+func (c *client) joinRoom() {
+	if c.roomId == "" {
+		return
+	}
+	c.send(game.RoomJoinAction{
+		Type:    game.RoomJoinActionType,
+		Payload: game.RoomJoinPayload{RoomId: c.roomId},
+	})
+}
+
+// parseLevels - difficulties of the AI opponents, the server drops the unknown ones
+func parseLevels(s string) []game.Difficulty {
+	var levels []game.Difficulty
+	for _, level := range strings.Split(s, ",") {
+		if level = strings.TrimSpace(level); level != "" {
+			levels = append(levels, game.Difficulty(level))
+		}
+	}
+	return levels
+}
+
+func (c *client) createRoom(config game.RoomConfig) {
+	c.send(game.RoomCreateAction{
+		Type:    game.RoomCreateActionType,
+		Payload: config,
+	})
+}
+
+func (c *client) send(action game.Action) {
+	if err := c.Send(action); err != nil {
 		log.Println(err)
 	}
 }
 
+func listRooms(c *comm.Client) {
+	defer closeClient(&client{Client: c})
+	if err := c.Send(game.RoomListAction{Type: game.RoomListActionType}); err != nil {
+		log.Println(err)
+		return
+	}
+	action, err := c.HandleInMessages()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if a, ok := action.(game.RoomListSuccessAction); ok {
+		for _, r := range a.Payload.Rooms {
+			log.Printf("%s %q %s %d/%d", r.Id, r.Name, r.State, r.Players, r.MaxPlayers)
+		}
+	}
+}
+
 func runGame(g *clientGame, name string) {
 	ebiten.SetWindowSize(screenWidth, screenHeight)
 	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
@@ -181,11 +330,11 @@ func randomRGBA() color.RGBA {
 }
 
 func getName() string {
-	if len(os.Args) < 2 {
+	if flag.NArg() < 1 {
 		log.Printf("Error: argument missing")
 		return ""
 	}
-	return os.Args[1]
+	return flag.Arg(0)
 }
 
 func getPlayerId(name string) game.PlayerIdType {
@@ -200,6 +349,7 @@ func getPlayerId(name string) game.PlayerIdType {
 
 // processNewAction - handler of new actions
 func (c *client) processNewAction(action game.Action) {
+	action = c.requests.Stamp(action)
 	if err := c.Send(action); err != nil {
 		log.Println("route %w", err)
 	}
@@ -208,11 +358,16 @@ func (c *client) processNewAction(action game.Action) {
 
 // route - handler of outgoing actions
 func (c *client) route(action game.Action) {
-	if err := c.Send(action); err != nil {
-		log.Println("route %w", err)
-	}
 	switch a := action.(type) {
-	case game.MoveStartAction, game.MoveStepAction, game.MoveStopAction:
+	case game.MoveStepAction, game.MoveStopAction, game.UnitOrdersAction:
+		// movement and order queues are simulated by the server, apply the prediction locally only
 		c.game.HandleAction(a, c.route)
+	case game.ErrorAction:
+		// failures of the prediction, the server reports the ones that matter
+		log.Printf("prediction: %s %s", a.Payload.Code, a.Payload.Message)
+	default:
+		if err := c.Send(action); err != nil {
+			log.Println("route %w", err)
+		}
 	}
 }
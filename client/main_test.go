@@ -0,0 +1,99 @@
+package main
+
+import (
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmcszk/fogofgo/pkg/comm"
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/gorilla/websocket"
+)
+
+// newTestClient - client connected to a test server, the actions the server receives wait in the channel
+func newTestClient(t *testing.T) (*client, chan game.Action) {
+	t.Helper()
+	sent := make(chan game.Action, 64)
+	upgrader := websocket.Upgrader{Subprotocols: comm.Subprotocols}
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn := comm.NewClient(ws)
+		for {
+			action, err := conn.HandleInMessages()
+			if err != nil {
+				return
+			}
+			sent <- action
+		}
+	}))
+	t.Cleanup(hs.Close)
+	ws, _, err := dialer.Dial("ws"+strings.TrimPrefix(hs.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := setupClient(game.Player{Id: getPlayerId("player1"), Name: "player1"}, ws)
+	t.Cleanup(func() { closeClient(c) })
+	return c, sent
+}
+
+// ownUnits - units of the client's player standing at the points
+func (c *client) ownUnits(points ...image.Point) []*game.Unit {
+	units := make([]*game.Unit, 0, len(points))
+	for _, p := range points {
+		u := game.NewUnit(c.player.Id, c.player.Color, game.ToPF(p), 16, 16)
+		c.game.store.StoreUnit(u)
+		units = append(units, u)
+	}
+	return units
+}
+
+// countSent - actions the server got until nothing came for a while
+func countSent(sent chan game.Action) []game.ActionType {
+	var types []game.ActionType
+	for {
+		select {
+		case a := <-sent:
+			types = append(types, a.GetType())
+		case <-time.After(200 * time.Millisecond):
+			return types
+		}
+	}
+}
+
+func TestClient_ProcessNewAction_OrderSendsOneAction(t *testing.T) {
+	c, sent := newTestClient(t)
+	unit := c.ownUnits(image.Pt(0, 0))[0]
+
+	c.processNewAction(unitOrder(unit, game.Order{Type: game.OrderMove, Point: image.Pt(3, 3)}, false))
+
+	if types := countSent(sent); len(types) != 1 || types[0] != game.OrderActionType {
+		t.Errorf("expected the order only sent to the server, got %v", types)
+	}
+	if len(unit.Orders) != 1 {
+		t.Errorf("expected the order predicted, got %v", unit.Orders)
+	}
+}
+
+func TestClient_ProcessNewAction_GroupMoveSendsOneAction(t *testing.T) {
+	c, sent := newTestClient(t)
+	units := c.ownUnits(image.Pt(0, 0), image.Pt(0, 1), image.Pt(0, 2))
+
+	c.processNewAction(game.GroupMoveAction{
+		Type: game.GroupMoveActionType,
+		Payload: game.GroupMovePayload{
+			UnitIds:   []game.UnitIdType{units[0].Id, units[1].Id, units[2].Id},
+			Order:     game.Order{Type: game.OrderMove, Point: image.Pt(5, 5)},
+			Formation: game.FormationBox,
+		},
+	})
+
+	if types := countSent(sent); len(types) != 1 || types[0] != game.GroupMoveActionType {
+		t.Errorf("expected the group move only sent to the server, got %v", types)
+	}
+}
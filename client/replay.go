@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// replayGame - plays a recorded match back, one server tick per frame
+type replayGame struct {
+	*clientGame
+	replay *game.Replay
+}
+
+func runReplay(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Printf("Error opening replay: %v", err)
+		return
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("Error closing replay: %v", err)
+		}
+	}()
+
+	g := newClientGame(game.PlayerIdType{}, game.NewStoreImpl(), func(game.Action) {
+		// nobody to send the actions to, everything comes from the replay
+	})
+	g.spectator = true
+
+	ebiten.SetWindowSize(screenWidth, screenHeight)
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	ebiten.SetWindowTitle("replay " + path)
+
+	if err := ebiten.RunGame(&replayGame{clientGame: g, replay: game.NewReplay(f)}); err != nil {
+		log.Printf("Game error: %v", err)
+	}
+}
+
+func (g *replayGame) Update() error {
+	if !g.replay.Done() {
+		if err := g.replay.Step(g.clientGame); err != nil {
+			log.Printf("replay: %v", err)
+		}
+	}
+	return g.clientGame.Update()
+}
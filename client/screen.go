@@ -12,10 +12,13 @@ import (
 )
 
 const (
-	tileSize       = 16
-	tileSpriteSize = 16
-	tileSpriteXNum = 7
-	selectedBorder = 2
+	tileSize        = 16
+	tileSpriteSize  = 16
+	tileSpriteXNum  = 7
+	selectedBorder  = 2
+	healthBarHeight = 3
+	healthBarMargin = 2
+	ghostAlpha      = 3 // ghosts are drawn with a third of the opacity
 )
 
 type screen struct {
@@ -48,6 +51,8 @@ func (s *screen) draw(enScreen *ebiten.Image, cameraX, cameraY int) {
 }
 
 func (s *screen) drawTiles(enScreen *ebiten.Image, cameraX, cameraY int) {
+	// units move, leave vision and get removed, track them from scratch every frame
+	s.units = make(map[*game.Unit]bool, len(s.units))
 	for _, t := range s.tiles {
 		if t != nil {
 			drawTile(t, enScreen, cameraX, cameraY)
@@ -58,7 +63,7 @@ func (s *screen) drawTiles(enScreen *ebiten.Image, cameraX, cameraY int) {
 
 func (s *screen) trackUnitVisibility(t *game.Tile) {
 	if t.Unit != nil {
-		s.units[t.Unit] = t.Visible
+		s.units[t.Unit] = t.Visible()
 	}
 }
 
@@ -75,7 +80,11 @@ func drawTile(t *game.Tile, enScreen *ebiten.Image, cameraX, cameraY int) {
 
 	op := &ebiten.DrawImageOptions{}
 
-	if !t.Visible {
+	switch t.Fog {
+	case game.FogUnexplored:
+		// left black
+		return
+	case game.FogExplored:
 		// Use ColorScale for dimming instead of deprecated ColorM
 		op.ColorScale.Scale(0.5, 0.5, 0.5, 1.0) // Make the tile darker
 	}
@@ -105,6 +114,82 @@ func drawUnit(u *game.Unit, enScreen *ebiten.Image, cameraX, cameraY int) {
 	}
 
 	vector.DrawFilledRect(enScreen, float32(x), float32(y), float32(u.Size.X), float32(u.Size.Y), u.Color, false)
+	drawHealthBar(u, enScreen, x, y)
+}
+
+// drawGhost - translucent enemy unit where it was seen last time
+func drawGhost(u *game.Unit, enScreen *ebiten.Image, cameraX, cameraY int) {
+	screenPosition := u.Position.Mul(tileSize)
+	x := screenPosition.X - float64(cameraX)
+	y := screenPosition.Y - float64(cameraY)
+
+	col := u.Color
+	col.R, col.G, col.B, col.A = col.R/ghostAlpha, col.G/ghostAlpha, col.B/ghostAlpha, 255/ghostAlpha
+	vector.DrawFilledRect(enScreen, float32(x), float32(y), float32(u.Size.X), float32(u.Size.Y), col, false)
+}
+
+// drawOrders - line through the waypoints of the unit, colored by the order
+func drawOrders(u *game.Unit, enScreen *ebiten.Image, cameraX, cameraY int) {
+	center := func(p game.PF) (float32, float32) {
+		s := p.Mul(tileSize)
+		return float32(s.X) - float32(cameraX) + tileSize/2, float32(s.Y) - float32(cameraY) + tileSize/2
+	}
+	x1, y1 := center(u.Position)
+	for _, o := range u.Orders {
+		if o.Type == game.OrderHold {
+			continue
+		}
+		x2, y2 := center(game.ToPF(o.Point))
+		col := orderColor(o.Type)
+		vector.StrokeLine(enScreen, x1, y1, x2, y2, 1, col, false)
+		vector.DrawFilledRect(enScreen, x2-2, y2-2, 4, 4, col, false)
+		x1, y1 = x2, y2
+	}
+}
+
+func orderColor(t game.OrderType) color.RGBA {
+	switch t {
+	case game.OrderAttackMove:
+		return color.RGBA{255, 64, 64, 255}
+	case game.OrderPatrol:
+		return color.RGBA{64, 128, 255, 255}
+	default:
+		return color.RGBA{64, 255, 64, 255}
+	}
+}
+
+func drawHealthBar(u *game.Unit, enScreen *ebiten.Image, x, y float64) {
+	if u.MaxHealth <= 0 {
+		return
+	}
+	barY := float32(y - healthBarHeight - healthBarMargin)
+	width := float32(u.Size.X)
+	health := width * float32(u.Health) / float32(u.MaxHealth)
+
+	vector.DrawFilledRect(enScreen, float32(x), barY, width, healthBarHeight, color.RGBA{64, 0, 0, 255}, false)
+	vector.DrawFilledRect(enScreen, float32(x), barY, health, healthBarHeight, healthColor(u), false)
+}
+
+func healthColor(u *game.Unit) color.RGBA {
+	switch {
+	case u.Health*3 < u.MaxHealth:
+		return color.RGBA{255, 0, 0, 255}
+	case u.Health*3 < u.MaxHealth*2:
+		return color.RGBA{255, 255, 0, 255}
+	default:
+		return color.RGBA{0, 255, 0, 255}
+	}
+}
+
+func drawWreck(w *wreck, enScreen *ebiten.Image, cameraX, cameraY int) {
+	screenPosition := w.position.Mul(tileSize)
+	x := float32(screenPosition.X - float64(cameraX))
+	y := float32(screenPosition.Y - float64(cameraY))
+	alpha := uint8(255 * w.ttl / wreckTTL)
+	col := color.RGBA{alpha, alpha / 4, 0, alpha}
+
+	vector.StrokeLine(enScreen, x, y, x+float32(w.size.X), y+float32(w.size.Y), 2, col, false)
+	vector.StrokeLine(enScreen, x+float32(w.size.X), y, x, y+float32(w.size.Y), 2, col, false)
 }
 
 func getBackgroundColorImage(className string) *ebiten.Image {
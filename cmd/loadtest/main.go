@@ -0,0 +1,341 @@
+// Command loadtest - plays many headless players against a server and reports how it copes.
+// Without -url the server runs in-process on a locally generated map.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/bmcszk/fogofgo/pkg/bot"
+	"github.com/bmcszk/fogofgo/pkg/comm"
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/server"
+	"github.com/bmcszk/fogofgo/pkg/world"
+	"github.com/google/uuid"
+)
+
+const (
+	// viewport - tiles loaded by a player scrolling the map, about a screen of the client
+	viewportWidth  = 40
+	viewportHeight = 30
+	// moveRadius - how far from its position a unit is sent
+	moveRadius = 10
+)
+
+// options - flags of the load test
+type options struct {
+	url      string
+	debug    string
+	players  int
+	duration time.Duration
+	rampUp   time.Duration
+	tickRate time.Duration
+	report   time.Duration
+	moves    float64
+	scrolls  float64
+	seed     int64
+	json     bool
+	verbose  bool
+}
+
+func main() {
+	var o options
+	flag.StringVar(&o.url, "url", "", "WebSocket endpoint of the server, an in-process server when empty")
+	flag.StringVar(&o.debug, "debug", "",
+		"debug address of the server given by -url, its memory is not reported when empty")
+	flag.IntVar(&o.players, "players", 100, "number of simulated players")
+	flag.DurationVar(&o.duration, "duration", time.Minute, "how long the players play after the ramp up")
+	flag.DurationVar(&o.rampUp, "ramp-up", 10*time.Second, "time over which the players connect")
+	flag.DurationVar(&o.tickRate, "tick", 500*time.Millisecond, "how often each player acts")
+	flag.DurationVar(&o.report, "report", 5*time.Second, "interval of the progress reports")
+	flag.Float64Var(&o.moves, "moves", 0.5, "chance of a player moving a unit on each tick")
+	flag.Float64Var(&o.scrolls, "scrolls", 0.3, "chance of a player scrolling the map on each tick")
+	flag.Int64Var(&o.seed, "seed", 1, "seed of the map of the in-process server")
+	flag.BoolVar(&o.json, "json", false, "use the JSON wire codec instead of the binary one")
+	flag.BoolVar(&o.verbose, "v", false, "keep the logs of the server and the players, they log every message")
+	flag.Parse()
+	if !o.verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	memory := remoteMemory(o.debug)
+	if o.url == "" {
+		addr, stop, err := startServer(o.seed)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer stop()
+		o.url = "ws://" + addr + "/ws"
+		memory = localMemory
+	}
+	s := run(ctx, o, memory)
+	fmt.Println("final:")
+	s.print(os.Stdout, time.Since(s.start), memory)
+}
+
+// startServer - serves a new in-process server on a free local port
+func startServer(seed int64) (string, func(), error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, fmt.Errorf("listen: %w", err)
+	}
+	chunks := world.NewChunkCache(world.NewGenerator(seed), 1024)
+	srv := server.New(chunks, game.DefaultUnitTypes(), "")
+	mux := http.NewServeMux()
+	mux.Handle("/ws", srv)
+	httpServer := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Println(err)
+		}
+	}()
+	stop := func() {
+		if err := httpServer.Close(); err != nil {
+			log.Println(err)
+		}
+		srv.Shutdown()
+	}
+	return listener.Addr().String(), stop, nil
+}
+
+// run - connects the players over the ramp up, lets them play and reports on the way
+func run(ctx context.Context, o options, memory memoryFunc) *stats {
+	ctx, cancel := context.WithTimeout(ctx, o.rampUp+o.duration)
+	defer cancel()
+	s := newStats()
+	protocols := comm.Subprotocols
+	if o.json {
+		protocols = []string{comm.JSONProtocol}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(o.report)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.print(os.Stdout, time.Since(s.start), memory)
+			}
+		}
+	}()
+
+	delay := o.rampUp / time.Duration(max(o.players, 1))
+	for i := range o.players {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			play(ctx, s, bot.Config{
+				URL:       o.url,
+				Player:    game.Player{Id: game.PlayerIdType(uuid.New()), Name: fmt.Sprintf("load-%d", i)},
+				Protocols: protocols,
+				TickRate:  o.tickRate,
+			}, &agent{stats: s, rand: rand.New(rand.NewPCG(uint64(i), uint64(o.seed))), options: o})
+		}()
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+	}
+	wg.Wait()
+	return s
+}
+
+// play - one player from connecting until the end of the test
+func play(ctx context.Context, s *stats, config bot.Config, a *agent) {
+	b, err := bot.Dial(ctx, config)
+	if err != nil {
+		s.failed.Add(1)
+		log.Println(err)
+		return
+	}
+	s.connected.Add(1)
+	s.addBot(b)
+	defer func() {
+		if err := b.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+	err = b.Run(ctx, a)
+	s.connected.Add(-1)
+	if ctx.Err() == nil {
+		s.dropped.Add(1)
+		log.Printf("%s dropped: %v", config.Player.Name, err)
+	}
+}
+
+// agent - player scrolling the map and moving its units at random
+type agent struct {
+	stats   *stats
+	rand    *rand.Rand
+	options options
+	camera  image.Point
+	joined  bool
+}
+
+func (a *agent) Tick(b *bot.Bot) {
+	units := b.Units()
+	if !a.joined {
+		// the camera starts at the player's base like in the client
+		a.joined = true
+		a.stats.joined.Add(1)
+		if len(units) > 0 {
+			a.camera = units[0].Position.ImagePoint()
+		}
+	}
+	if a.rand.Float64() < a.options.scrolls {
+		// a quarter of the screen at most, the way the camera is scrolled with the keys
+		a.camera = a.camera.Add(image.Pt(a.rand.IntN(viewportWidth/2+1)-viewportWidth/4,
+			a.rand.IntN(viewportHeight/2+1)-viewportHeight/4))
+		view := image.Rect(0, 0, viewportWidth, viewportHeight).
+			Add(a.camera.Sub(image.Pt(viewportWidth/2, viewportHeight/2)))
+		b.Send(game.NewMapLoadAction(view, b.PlayerId()))
+	}
+	if a.rand.Float64() < a.options.moves {
+		movable := slices.DeleteFunc(units, func(u *game.Unit) bool { return u.Building })
+		if len(movable) > 0 {
+			u := movable[a.rand.IntN(len(movable))]
+			offset := image.Pt(a.rand.IntN(2*moveRadius+1)-moveRadius, a.rand.IntN(2*moveRadius+1)-moveRadius)
+			b.Move(u.Id, u.Position.ImagePoint().Add(offset))
+		}
+	}
+}
+
+func (a *agent) Observe(_ *bot.Bot, _ game.Action) {
+	a.stats.received.Add(1)
+}
+
+func (a *agent) Answered(_ *bot.Bot, _ game.Action, rtt time.Duration) {
+	a.stats.answered(rtt)
+}
+
+// stats - counters of all players, safe for concurrent use
+type stats struct {
+	start     time.Time
+	connected atomic.Int64
+	joined    atomic.Int64
+	failed    atomic.Int64 // players which could not connect
+	dropped   atomic.Int64 // connections lost before the end
+	received  atomic.Int64
+
+	mux       sync.Mutex
+	bots      []*bot.Bot
+	latencies []time.Duration
+	peak      uint64 // highest heap seen
+}
+
+func newStats() *stats {
+	return &stats{start: time.Now()}
+}
+
+func (s *stats) addBot(b *bot.Bot) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.bots = append(s.bots, b)
+}
+
+func (s *stats) answered(rtt time.Duration) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.latencies = append(s.latencies, rtt)
+}
+
+// print - writes the report of the test so far
+func (s *stats) print(w io.Writer, elapsed time.Duration, memory memoryFunc) {
+	s.mux.Lock()
+	var sent int64
+	for _, b := range s.bots {
+		sent += b.Sent()
+	}
+	latencies := slices.Clone(s.latencies)
+	s.mux.Unlock()
+	slices.Sort(latencies)
+	received := s.received.Load()
+	seconds := max(elapsed.Seconds(), 1)
+
+	_, _ = fmt.Fprintf(w, "%6.0fs players: %d connected, %d joined, %d failed, %d dropped\n",
+		elapsed.Seconds(), s.connected.Load(), s.joined.Load(), s.failed.Load(), s.dropped.Load())
+	_, _ = fmt.Fprintf(w, "        messages: %d sent (%.0f/s), %d received (%.0f/s)\n",
+		sent, float64(sent)/seconds, received, float64(received)/seconds)
+	_, _ = fmt.Fprintf(w, "        latency: p50 %v, p90 %v, p99 %v, max %v of %d requests\n",
+		percentile(latencies, 50), percentile(latencies, 90), percentile(latencies, 99),
+		percentile(latencies, 100), len(latencies))
+	heap, ok := memory()
+	if !ok {
+		_, _ = fmt.Fprintln(w, "        server memory: unknown")
+		return
+	}
+	s.mux.Lock()
+	s.peak = max(s.peak, heap)
+	peak := s.peak
+	s.mux.Unlock()
+	_, _ = fmt.Fprintf(w, "        server memory: %d MiB heap, %d MiB peak\n", heap>>20, peak>>20)
+}
+
+// percentile - p-th percentile of the sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := (len(sorted)*p+99)/100 - 1
+	return sorted[max(i, 0)].Round(time.Microsecond)
+}
+
+// memoryFunc - heap in use by the server, false when it cannot be told
+type memoryFunc func() (uint64, bool)
+
+// localMemory - heap of this process, the in-process server shares it with the players
+func localMemory() (uint64, bool) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc, true
+}
+
+// remoteMemory - heap published by the server on /debug/vars of its debug listener
+func remoteMemory(addr string) memoryFunc {
+	return func() (uint64, bool) {
+		if addr == "" {
+			return 0, false
+		}
+		resp, err := http.Get((&url.URL{Scheme: "http", Host: addr, Path: "/debug/vars"}).String())
+		if err != nil {
+			return 0, false
+		}
+		defer func() { _ = resp.Body.Close() }()
+		var vars struct {
+			Memstats struct {
+				HeapAlloc uint64
+			} `json:"memstats"`
+		}
+		if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&vars) != nil {
+			return 0, false
+		}
+		return vars.Memstats.HeapAlloc, true
+	}
+}
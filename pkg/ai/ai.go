@@ -0,0 +1,419 @@
+// Package ai - computer opponents playing through a bot.
+// The AI sees only what the server shows to its player and gives the same orders as a human,
+// the difficulty decides which of its behaviours are on.
+package ai
+
+import (
+	"cmp"
+	"image"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/bmcszk/fogofgo/pkg/bot"
+	"github.com/bmcszk/fogofgo/pkg/game"
+)
+
+// unit types the AI plays with besides the ones every match has, behaviours needing a missing type are off
+const (
+	scoutType    game.UnitTypeIdType = "scout"
+	soldierType  game.UnitTypeIdType = "soldier"
+	barracksType game.UnitTypeIdType = "barracks"
+)
+
+const (
+	maxWorkers     = 5   // workers the AI trains
+	maxScouts      = 1   // scouts the AI trains
+	scoutRadius    = 40  // how far from home the scouts go
+	scoutTimeout   = 60  // ticks before a scout gives up its point
+	stuckTimeout   = 10  // ticks a gatherer may stand away from its node
+	defendRadius   = 12  // enemies closer to home are attacked
+	attackGroup    = 5   // soldiers sent together on an attack
+	attackRadius   = 6   // distance at which an attacker has reached its target, the formation is wide
+	attackTimeout  = 120 // ticks before the attackers give up and regroup
+	arrivedRadius  = 2   // distance at which a unit has reached its point
+	buildingMargin = 3   // distance between the headquarters and a new building
+)
+
+// taskKind - what an AI unit is busy with
+type taskKind int
+
+const (
+	taskGather taskKind = iota + 1
+	taskScout
+	taskBuild
+	taskAttack
+	taskDefend
+)
+
+// task - job of a unit and its point
+type task struct {
+	kind   taskKind
+	point  image.Point
+	target game.UnitIdType // enemy attacked by a defender
+	ticks  int             // ticks of the AI spent on the task
+}
+
+// Player - agent of a computer opponent, see bot.Agent
+type Player struct {
+	level   game.Difficulty
+	rand    *rand.Rand
+	tasks   map[game.UnitIdType]*task
+	enemies map[game.UnitIdType]image.Point // enemy buildings seen so far
+	drained map[image.Point]bool            // nodes the workers could not gather from
+	home    image.Point
+}
+
+// New - opponent of the difficulty, the seed makes its choices repeatable
+func New(level game.Difficulty, seed uint64) *Player {
+	return &Player{
+		level:   level,
+		rand:    rand.New(rand.NewPCG(seed, seed)),
+		tasks:   make(map[game.UnitIdType]*task),
+		enemies: make(map[game.UnitIdType]image.Point),
+		drained: make(map[image.Point]bool),
+	}
+}
+
+// Level - difficulty of the opponent
+func (p *Player) Level() game.Difficulty {
+	return p.level
+}
+
+// Tick - looks at the state and gives the orders, the host decides how often
+func (p *Player) Tick(b *bot.Bot) {
+	units := byId(b.Units())
+	p.review(units)
+	for _, e := range b.Enemies() {
+		if e.Building {
+			p.enemies[e.Id] = e.Position.ImagePoint()
+		}
+	}
+
+	p.produce(b, units)
+	p.gather(b, units)
+	p.scout(b, units)
+	if p.level == game.DifficultyEasy {
+		return
+	}
+	p.expand(b, units)
+	p.defend(b, units)
+	if p.level == game.DifficultyNormal {
+		return
+	}
+	p.attack(b, units)
+}
+
+// Observe - forgets the enemy buildings destroyed
+func (p *Player) Observe(_ *bot.Bot, action game.Action) {
+	if a, ok := action.(game.UnitDestroyedAction); ok {
+		delete(p.enemies, a.Payload.UnitId)
+	}
+}
+
+// review - finds home, drops the tasks of lost units and counts the time spent on the others
+func (p *Player) review(units []*game.Unit) {
+	alive := make(map[game.UnitIdType]bool, len(units))
+	for _, u := range units {
+		alive[u.Id] = true
+		if u.Type == game.HeadquartersType {
+			p.home = u.Position.ImagePoint()
+		}
+	}
+	for id, t := range p.tasks {
+		if !alive[id] {
+			delete(p.tasks, id)
+			continue
+		}
+		t.ticks++
+	}
+}
+
+// produce - keeps the buildings busy with the units the AI is short of
+func (p *Player) produce(b *bot.Bot, units []*game.Unit) {
+	count := make(map[game.UnitTypeIdType]int)
+	for _, u := range units {
+		count[u.Type]++
+		for _, q := range u.Queue {
+			count[q.Type]++
+		}
+	}
+	for _, building := range units {
+		if !building.Building || len(building.Queue) > 0 {
+			continue
+		}
+		switch building.Type {
+		case game.HeadquartersType:
+			if count[game.WorkerType] < maxWorkers {
+				p.train(b, building, game.WorkerType)
+			} else if count[scoutType] < maxScouts {
+				p.train(b, building, scoutType)
+			}
+		case barracksType:
+			if p.level != game.DifficultyEasy {
+				p.train(b, building, soldierType)
+			}
+		}
+	}
+}
+
+// train - queues the unit when the player can afford it
+func (p *Player) train(b *bot.Bot, building *game.Unit, unitType game.UnitTypeIdType) {
+	if !p.affords(b, unitType) {
+		return
+	}
+	b.Produce(building.Id, unitType)
+}
+
+func (p *Player) affords(b *bot.Bot, unitType game.UnitTypeIdType) bool {
+	ut, ok := b.UnitTypes().Get(unitType)
+	player, joined := b.Player()
+	return ok && joined && player.Resources.Covers(ut.Cost)
+}
+
+// gather - idle workers gather the resource the player has less of, from the nearest node
+func (p *Player) gather(b *bot.Bot, units []*game.Unit) {
+	for _, u := range units {
+		if u.Type != game.WorkerType {
+			continue
+		}
+		if t, ok := p.tasks[u.Id]; ok {
+			if t.kind != taskGather {
+				continue
+			}
+			if p.stuck(u, t) {
+				// gathered by the other players out of sight or out of reach
+				p.drained[t.point] = true
+			} else if left(b, t.point) > 0 {
+				continue
+			}
+		}
+		node, ok := p.nearestNode(b, u.Position.ImagePoint())
+		if !ok {
+			continue
+		}
+		p.tasks[u.Id] = &task{kind: taskGather, point: node}
+		b.Gather(u.Id, node)
+	}
+}
+
+// nearestNode - resource node closest to the point, nodes of the scarcer resource come first
+func (p *Player) nearestNode(b *bot.Bot, from image.Point) (image.Point, bool) {
+	wanted := game.ResourceWood
+	if player, ok := b.Player(); ok && player.Resources.Stone < player.Resources.Wood {
+		wanted = game.ResourceStone
+	}
+	var best image.Point
+	bestDist, found := 0.0, false
+	for _, t := range b.Store().GetAllTiles() {
+		resource, amount := t.Resource()
+		if amount == 0 || p.drained[t.Point] {
+			continue
+		}
+		d := game.Dist(from, t.Point)
+		if resource != wanted {
+			// the other resource only when it is much closer
+			d *= 3
+		}
+		if !found || d < bestDist || d == bestDist && before(t.Point, best) {
+			best, bestDist, found = t.Point, d, true
+		}
+	}
+	return best, found
+}
+
+// stuck - the gatherer stands away from its node, the server has dropped the order
+func (p *Player) stuck(u *game.Unit, t *task) bool {
+	return t.ticks > stuckTimeout && !u.Moving() && game.Dist(u.Position.ImagePoint(), t.point) > 1.5
+}
+
+// left - resources left on the node
+func left(b *bot.Bot, node image.Point) int {
+	t, ok := b.Store().GetTile(node)
+	if !ok {
+		return 0
+	}
+	_, amount := t.Resource()
+	return amount
+}
+
+// scout - scouts visit random points around home
+func (p *Player) scout(b *bot.Bot, units []*game.Unit) {
+	for _, u := range units {
+		if u.Type != scoutType {
+			continue
+		}
+		if t, ok := p.tasks[u.Id]; ok && !p.arrived(u, t) && t.ticks < scoutTimeout {
+			continue
+		}
+		point := p.home.Add(image.Pt(p.rand.IntN(2*scoutRadius+1)-scoutRadius, p.rand.IntN(2*scoutRadius+1)-scoutRadius))
+		p.tasks[u.Id] = &task{kind: taskScout, point: point}
+		b.Move(u.Id, point)
+	}
+}
+
+func (p *Player) arrived(u *game.Unit, t *task) bool {
+	return game.Dist(u.Position.ImagePoint(), t.point) <= arrivedRadius
+}
+
+// expand - a worker builds the barracks next to home
+func (p *Player) expand(b *bot.Bot, units []*game.Unit) {
+	if slices.ContainsFunc(units, func(u *game.Unit) bool { return u.Type == barracksType }) {
+		for id, t := range p.tasks {
+			if t.kind == taskBuild {
+				delete(p.tasks, id)
+			}
+		}
+		return
+	}
+	for _, u := range units {
+		if t, ok := p.tasks[u.Id]; ok && t.kind == taskBuild {
+			p.build(b, u, t)
+			return
+		}
+	}
+	if !p.affords(b, barracksType) {
+		return
+	}
+	site, ok := p.site(b)
+	if !ok {
+		return
+	}
+	for _, u := range units {
+		if u.Type == game.WorkerType {
+			t := &task{kind: taskBuild, point: site}
+			p.tasks[u.Id] = t
+			p.build(b, u, t)
+			return
+		}
+	}
+}
+
+// build - walks the worker to the site and builds there
+func (p *Player) build(b *bot.Bot, u *game.Unit, t *task) {
+	if game.Dist(u.Position.ImagePoint(), t.point) > game.BuildRange {
+		if !u.Moving() {
+			b.Move(u.Id, t.point)
+		}
+		return
+	}
+	if !b.CanPlace(barracksType, t.point) {
+		// taken meanwhile, another site next time
+		delete(p.tasks, u.Id)
+		return
+	}
+	b.Build(u.Id, barracksType, t.point)
+}
+
+// site - free known place for a building around home
+func (p *Player) site(b *bot.Bot) (image.Point, bool) {
+	for r := buildingMargin; r <= 2*buildingMargin+2; r++ {
+		for _, d := range []image.Point{{r, 0}, {0, r}, {-r, 0}, {0, -r}, {r, r}, {-r, r}, {r, -r}, {-r, -r}} {
+			site := p.home.Add(d)
+			if _, known := b.Store().GetTile(site); known && b.CanPlace(barracksType, site) {
+				return site, true
+			}
+		}
+	}
+	return image.Point{}, false
+}
+
+// defend - soldiers attack the enemies coming close to home, the defenders are free again once they are gone
+func (p *Player) defend(b *bot.Bot, units []*game.Unit) {
+	var intruders []*game.Unit
+	for _, e := range byId(b.Enemies()) {
+		if game.Dist(e.Position.ImagePoint(), p.home) <= defendRadius {
+			intruders = append(intruders, e)
+		}
+	}
+	for id, t := range p.tasks {
+		if t.kind == taskDefend && !slices.ContainsFunc(intruders, func(e *game.Unit) bool { return e.Id == t.target }) {
+			delete(p.tasks, id)
+		}
+	}
+	if len(intruders) == 0 {
+		return
+	}
+	for _, u := range p.idle(units, soldierType) {
+		target := slices.MinFunc(intruders, func(a, c *game.Unit) int {
+			return cmp.Compare(u.Position.Dist(a.Position), u.Position.Dist(c.Position))
+		})
+		p.tasks[u.Id] = &task{kind: taskDefend, point: target.Position.ImagePoint(), target: target.Id}
+		b.Attack(u.Id, target.Id)
+	}
+}
+
+// attack - idle soldiers go together for the nearest enemy building, they look for one when none is known
+func (p *Player) attack(b *bot.Bot, units []*game.Unit) {
+	for id, t := range p.tasks {
+		u := b.Store().GetUnitById(id)
+		if t.kind != taskAttack || u == nil {
+			continue
+		}
+		if game.Dist(u.Position.ImagePoint(), t.point) <= attackRadius {
+			p.forgetAround(b, t.point)
+			delete(p.tasks, id)
+		} else if t.ticks > attackTimeout {
+			delete(p.tasks, id)
+		}
+	}
+	soldiers := p.idle(units, soldierType)
+	if len(soldiers) < attackGroup {
+		return
+	}
+	target, ok := p.target()
+	if !ok {
+		target = p.home.Add(image.Pt(p.rand.IntN(4*scoutRadius+1)-2*scoutRadius, p.rand.IntN(4*scoutRadius+1)-2*scoutRadius))
+	}
+	ids := make([]game.UnitIdType, 0, len(soldiers))
+	for _, u := range soldiers {
+		ids = append(ids, u.Id)
+		p.tasks[u.Id] = &task{kind: taskAttack, point: target}
+	}
+	b.GroupMove(ids, game.Order{Type: game.OrderAttackMove, Point: target}, game.FormationWedge)
+}
+
+// forgetAround - enemy buildings remembered near the point are gone when not seen there now
+func (p *Player) forgetAround(b *bot.Bot, point image.Point) {
+	for id, pos := range p.enemies {
+		if game.Dist(pos, point) <= attackRadius && b.Store().GetUnitById(id) == nil {
+			delete(p.enemies, id)
+		}
+	}
+}
+
+// target - known enemy building nearest to home
+func (p *Player) target() (image.Point, bool) {
+	var best image.Point
+	found := false
+	for _, pos := range p.enemies {
+		d, bestDist := game.Dist(p.home, pos), game.Dist(p.home, best)
+		if !found || d < bestDist || d == bestDist && before(pos, best) {
+			best, found = pos, true
+		}
+	}
+	return best, found
+}
+
+// idle - units of the type without a task, in the order of the units
+func (p *Player) idle(units []*game.Unit, unitType game.UnitTypeIdType) []*game.Unit {
+	var r []*game.Unit
+	for _, u := range units {
+		if _, busy := p.tasks[u.Id]; !busy && u.Type == unitType {
+			r = append(r, u)
+		}
+	}
+	return r
+}
+
+// byId - sorts the units read from the store, the seed repeats the choices only in a stable order
+func byId(units []*game.Unit) []*game.Unit {
+	slices.SortFunc(units, func(a, c *game.Unit) int {
+		return slices.Compare(a.Id[:], c.Id[:])
+	})
+	return units
+}
+
+// before - order of the points breaking the ties of equally distant ones
+func before(a, c image.Point) bool {
+	return a.Y < c.Y || a.Y == c.Y && a.X < c.X
+}
@@ -0,0 +1,226 @@
+package ai_test
+
+import (
+	"image"
+	"image/color"
+	"slices"
+	"testing"
+
+	"github.com/bmcszk/fogofgo/pkg/ai"
+	"github.com/bmcszk/fogofgo/pkg/bot"
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/world"
+)
+
+// recorder - conn keeping the actions sent by the bot
+type recorder struct {
+	sent []game.Action
+}
+
+func (r *recorder) Send(action game.Action) error {
+	r.sent = append(r.sent, action)
+	return nil
+}
+
+// sent - actions of the type sent by the bot
+func sent[A game.Action](r *recorder) []A {
+	var found []A
+	for _, action := range r.sent {
+		if a, ok := action.(A); ok {
+			found = append(found, a)
+		}
+	}
+	return found
+}
+
+// joinTestBot - bot of the player which has joined a plain map with a forest at (10,0)
+func joinTestBot(t *testing.T, player game.Player, units ...*game.Unit) (*bot.Bot, *recorder) {
+	t.Helper()
+	conn := &recorder{}
+	b := bot.New(bot.Config{Player: player}, conn)
+	tiles := make([]world.Tile, 0, 41*41)
+	for x := -20; x <= 20; x++ {
+		for y := -20; y <= 20; y++ {
+			land := world.LandPlain
+			if x == 10 && y == 0 {
+				land = world.LandForest
+			}
+			tiles = append(tiles, world.Tile{Point: image.Pt(x, y), LandType: land})
+		}
+	}
+	join := game.PlayerJoinSuccessAction{
+		Type: game.PlayerJoinSuccessActionType,
+		Payload: game.PlayerJoinSuccessPayload{
+			PlayerId:  player.Id,
+			Players:   []game.Player{player},
+			UnitTypes: game.DefaultUnitTypes().All(),
+		},
+	}
+	for _, u := range units {
+		join.Payload.Units = append(join.Payload.Units, *u)
+	}
+	for _, action := range []game.Action{
+		game.MapLoadSuccessAction{
+			Type: game.MapLoadSuccessActionType,
+			Payload: game.MapLoadSuccessPayload{
+				WorldResponse: world.WorldResponse{MinX: -20, MinY: -20, MaxX: 21, MaxY: 21, Tiles: tiles},
+				PlayerId:      player.Id,
+			},
+		},
+		join,
+	} {
+		if err := b.Handle(action, ai.New(game.DifficultyEasy, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return b, conn
+}
+
+func createTestPlayer(resources game.Resources) game.Player {
+	return game.Player{
+		Id:        game.PlayerIdType(game.NewUnitId()),
+		Name:      "ai",
+		Resources: resources,
+	}
+}
+
+func createTestUnit(player game.Player, unitType game.UnitTypeIdType, x, y int) *game.Unit {
+	ut, _ := game.DefaultUnitTypes().Get(unitType)
+	return ut.New(player.Id, color.RGBA{255, 0, 0, 255}, game.NewPF(float64(x), float64(y)))
+}
+
+func TestPlayer_Tick_GathersAndTrainsWorkers(t *testing.T) {
+	player := createTestPlayer(game.StartingResources)
+	hq := createTestUnit(player, game.HeadquartersType, 0, 0)
+	worker := createTestUnit(player, game.WorkerType, 2, 0)
+	b, conn := joinTestBot(t, player, hq, worker)
+
+	b.Step(ai.New(game.DifficultyEasy, 1))
+
+	gathers := sent[game.GatherAction](conn)
+	if len(gathers) != 1 || gathers[0].Payload.UnitId != worker.Id || gathers[0].Payload.Point != image.Pt(10, 0) {
+		t.Errorf("expected the worker to gather in the forest, got %+v", gathers)
+	}
+	queued := sent[game.QueueProductionAction](conn)
+	if len(queued) != 1 || queued[0].Payload.BuildingId != hq.Id || queued[0].Payload.UnitType != game.WorkerType {
+		t.Errorf("expected a worker queued in the headquarters, got %+v", queued)
+	}
+	if builds := sent[game.BuildAction](conn); len(builds) != 0 {
+		t.Errorf("expected the easy opponent not to build, got %+v", builds)
+	}
+}
+
+func TestPlayer_Tick_KeepsGathering(t *testing.T) {
+	player := createTestPlayer(game.Resources{})
+	worker := createTestUnit(player, game.WorkerType, 2, 0)
+	b, conn := joinTestBot(t, player, worker)
+	agent := ai.New(game.DifficultyEasy, 1)
+
+	b.Step(agent)
+	b.Step(agent)
+
+	if gathers := sent[game.GatherAction](conn); len(gathers) != 1 {
+		t.Errorf("expected a single gather order, got %d", len(gathers))
+	}
+}
+
+func TestPlayer_Tick_NormalBuildsBarracks(t *testing.T) {
+	player := createTestPlayer(game.Resources{Wood: 150, Stone: 50})
+	hq := createTestUnit(player, game.HeadquartersType, 0, 0)
+	worker := createTestUnit(player, game.WorkerType, 2, 0)
+	b, conn := joinTestBot(t, player, hq, worker)
+
+	b.Step(ai.New(game.DifficultyNormal, 1))
+
+	builds := sent[game.BuildAction](conn)
+	if len(builds) != 1 {
+		t.Fatalf("expected the worker to build, got %+v", builds)
+	}
+	build := builds[0].Payload
+	if build.UnitId != worker.Id || build.UnitType != "barracks" {
+		t.Errorf("expected the worker to build barracks, got %+v", build)
+	}
+	if d := game.Dist(worker.Position.ImagePoint(), build.Point); d > game.BuildRange {
+		t.Errorf("expected the site in the build range, got %v", build.Point)
+	}
+}
+
+func TestPlayer_Tick_NormalDefendsHome(t *testing.T) {
+	player := createTestPlayer(game.Resources{})
+	enemy := createTestPlayer(game.Resources{})
+	hq := createTestUnit(player, game.HeadquartersType, 0, 0)
+	soldier := createTestUnit(player, "soldier", 3, 3)
+	intruder := createTestUnit(enemy, game.WorkerType, 6, 6)
+	b, conn := joinTestBot(t, player, hq, soldier, intruder)
+
+	b.Step(ai.New(game.DifficultyNormal, 1))
+
+	attacks := sent[game.AttackAction](conn)
+	if len(attacks) != 1 || attacks[0].Payload.UnitId != soldier.Id || attacks[0].Payload.TargetId != intruder.Id {
+		t.Errorf("expected the soldier to attack the intruder, got %+v", attacks)
+	}
+}
+
+func TestPlayer_Tick_HardAttacksKnownBuilding(t *testing.T) {
+	player := createTestPlayer(game.Resources{})
+	enemy := createTestPlayer(game.Resources{})
+	hq := createTestUnit(player, game.HeadquartersType, 0, 0)
+	enemyHq := createTestUnit(enemy, game.HeadquartersType, 18, 18)
+	units := []*game.Unit{hq, enemyHq}
+	for i := range 5 {
+		units = append(units, createTestUnit(player, "soldier", -5, i))
+	}
+	b, conn := joinTestBot(t, player, units...)
+	agent := ai.New(game.DifficultyHard, 1)
+
+	b.Step(agent)
+	b.Step(agent)
+
+	moves := sent[game.GroupMoveAction](conn)
+	if len(moves) != 1 {
+		t.Fatalf("expected a single group attack, got %+v", moves)
+	}
+	move := moves[0].Payload
+	if len(move.UnitIds) != 5 || move.Order.Type != game.OrderAttackMove || move.Order.Point != image.Pt(18, 18) {
+		t.Errorf("expected the soldiers to attack-move to the enemy headquarters, got %+v", move)
+	}
+}
+
+func TestPlayer_Tick_NormalDoesNotAttack(t *testing.T) {
+	player := createTestPlayer(game.Resources{})
+	enemy := createTestPlayer(game.Resources{})
+	units := []*game.Unit{createTestUnit(enemy, game.HeadquartersType, 18, 18)}
+	for i := range 5 {
+		units = append(units, createTestUnit(player, "soldier", -5, i))
+	}
+	b, conn := joinTestBot(t, player, units...)
+
+	b.Step(ai.New(game.DifficultyNormal, 1))
+
+	if moves := sent[game.GroupMoveAction](conn); len(moves) != 0 {
+		t.Errorf("expected the normal opponent not to attack, got %+v", moves)
+	}
+}
+
+func TestPlayer_Tick_SameSeedSameOrders(t *testing.T) {
+	player := createTestPlayer(game.Resources{})
+	var scouts []*game.Unit
+	for x := range 8 {
+		scouts = append(scouts, createTestUnit(player, "scout", x, 5))
+	}
+	moves := func() []game.MoveStartPayload {
+		b, conn := joinTestBot(t, player, scouts...)
+		b.Step(ai.New(game.DifficultyEasy, 7))
+		var payloads []game.MoveStartPayload
+		for _, a := range sent[game.MoveStartAction](conn) {
+			payloads = append(payloads, a.Payload)
+		}
+		return payloads
+	}
+
+	first, second := moves(), moves()
+
+	if len(first) != len(scouts) || !slices.Equal(first, second) {
+		t.Errorf("expected the same scouting with the same seed, got %v and %v", first, second)
+	}
+}
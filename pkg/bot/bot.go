@@ -0,0 +1,288 @@
+// Package bot - headless client of the game for AI opponents, integration tests and load generators.
+// The bot connects over WebSocket, joins the game, keeps the state the server shows to its player
+// in a local store and lets an Agent issue orders every tick.
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/bmcszk/fogofgo/pkg/comm"
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/world"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	DefaultURL      = "ws://localhost:8000/ws"
+	DefaultTickRate = 100 * time.Millisecond
+	// chunkMargin - chunks around the player's units loaded ahead of them
+	chunkMargin = 1
+)
+
+var (
+	// ErrRoomJoinFailed - the server refused the room the bot asked for
+	ErrRoomJoinFailed = errors.New("room join failed")
+	// ErrNotDialed - the bot has no connection of its own to run on
+	ErrNotDialed = errors.New("bot not dialed")
+)
+
+// Agent - brain of the bot, called from the bot's loop so it may read the state and issue orders freely
+type Agent interface {
+	// Tick - called every tick once the bot has joined the game
+	Tick(b *Bot)
+}
+
+// AgentFunc - function used as an Agent
+type AgentFunc func(b *Bot)
+
+func (f AgentFunc) Tick(b *Bot) {
+	f(b)
+}
+
+// Observer - optional interface of the agent, told of every action from the server after it was applied
+type Observer interface {
+	Observe(b *Bot, action game.Action)
+}
+
+// LatencyObserver - optional interface of the agent, told how long the server took to answer each request
+type LatencyObserver interface {
+	Answered(b *Bot, action game.Action, rtt time.Duration)
+}
+
+// Config - who the bot plays as and where
+type Config struct {
+	URL       string           // WebSocket endpoint of the server, DefaultURL when empty
+	Player    game.Player      // id, name and color of the bot's player
+	Room      game.RoomIdType  // room to join, quick match when empty
+	Create    *game.RoomConfig // room created for the bot instead of joining one
+	Protocols []string         // wire codecs in the order of preference, comm.Subprotocols when empty
+	TickRate  time.Duration    // how often the agent is called, DefaultTickRate when zero
+	// SessionToken - resumes the session of the player instead of joining anew
+	SessionToken string
+}
+
+func (c Config) withDefaults() Config {
+	if c.URL == "" {
+		c.URL = DefaultURL
+	}
+	if len(c.Protocols) == 0 {
+		c.Protocols = comm.Subprotocols
+	}
+	if c.TickRate <= 0 {
+		c.TickRate = DefaultTickRate
+	}
+	return c
+}
+
+// Conn - where the bot's actions go, the WebSocket client or a server hosting the bot itself
+type Conn interface {
+	Send(action game.Action) error
+}
+
+// Bot - connection of a headless player and the state it sees
+type Bot struct {
+	config       Config
+	conn         Conn
+	client       *comm.Client // nil when the bot is not dialed
+	store        game.Store
+	logic        *game.GameLogic
+	requests     *game.Requests
+	chunks       map[world.ChunkId]bool
+	sessionToken string
+	room         game.RoomIdType
+	joined       bool
+	tick         int64
+	sent         atomic.Int64
+}
+
+// Dial - connects to the server, the bot joins the game when it is run
+func Dial(ctx context.Context, config Config) (*Bot, error) {
+	config = config.withDefaults()
+	dialer := websocket.Dialer{Subprotocols: config.Protocols}
+	ws, resp, err := dialer.DialContext(ctx, config.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", config.URL, err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		log.Printf("Error closing handshake body: %v", err)
+	}
+	client := comm.NewClient(ws)
+	client.PlayerId = config.Player.Id
+	b := New(config, client)
+	b.client = client
+	return b, nil
+}
+
+// New - bot sending its actions to the conn, the owner of the conn passes the answers to Handle
+// and calls Step every tick
+func New(config Config, conn Conn) *Bot {
+	config = config.withDefaults()
+	store := game.NewStoreImpl()
+	return &Bot{
+		config:       config,
+		conn:         conn,
+		store:        store,
+		logic:        game.NewGameLogic(store),
+		requests:     game.NewRequests(),
+		chunks:       make(map[world.ChunkId]bool),
+		sessionToken: config.SessionToken,
+		room:         config.Room,
+	}
+}
+
+// Run - joins the game and plays until the context is done or the connection is lost,
+// the server's actions and the agent's ticks are handled one at a time
+func (b *Bot) Run(ctx context.Context, agent Agent) error {
+	if b.client == nil {
+		return ErrNotDialed
+	}
+	actions := make(chan game.Action)
+	lost := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go b.read(actions, lost, done)
+
+	if b.config.Create != nil {
+		b.Send(game.RoomCreateAction{Type: game.RoomCreateActionType, Payload: *b.config.Create})
+	} else if b.room != "" {
+		b.Send(game.RoomJoinAction{Type: game.RoomJoinActionType, Payload: game.RoomJoinPayload{RoomId: b.room}})
+	}
+	b.Join()
+
+	ticker := time.NewTicker(b.config.TickRate)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-lost:
+			return fmt.Errorf("connection lost: %w", err)
+		case action := <-actions:
+			if err := b.Handle(action, agent); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			b.Step(agent)
+		}
+	}
+}
+
+// Close - closes the connection, Run returns then
+func (b *Bot) Close() error {
+	if b.client == nil {
+		return nil
+	}
+	return b.client.Close()
+}
+
+// read - passes the server's actions to the loop until the connection is lost
+func (b *Bot) read(actions chan<- game.Action, lost chan<- error, done <-chan struct{}) {
+	for {
+		action, err := b.client.HandleInMessages()
+		if err != nil {
+			if !b.client.Connected {
+				lost <- err
+				return
+			}
+			continue
+		}
+		select {
+		case actions <- action:
+		case <-done:
+			return
+		}
+	}
+}
+
+// Handle - applies the server's action to the bot's state and shows it to the agent,
+// fails when the bot cannot get into the game
+func (b *Bot) Handle(action game.Action, agent Agent) error {
+	if rtt, ok := b.requests.Answer(action); ok {
+		if o, ok := agent.(LatencyObserver); ok {
+			o.Answered(b, action, rtt)
+		}
+	}
+	if tick := action.GetEnvelope().Tick; tick > b.tick {
+		b.tick = tick
+	}
+	switch a := action.(type) {
+	case game.PlayerJoinSuccessAction:
+		b.sessionToken = a.Payload.SessionToken
+		b.joined = true
+	case game.RoomJoinSuccessAction:
+		b.room = a.Payload.Id
+	case game.RoomJoinFailedAction:
+		return fmt.Errorf("%w: %s %s", ErrRoomJoinFailed, a.Payload.RoomId, a.Payload.Reason)
+	case game.ActionRejectedAction:
+		if a.Payload.Action == game.PlayerJoinActionType || a.Payload.Action == game.PlayerRejoinActionType {
+			return fmt.Errorf("join rejected: %s %s", a.Payload.Code, a.Payload.Message)
+		}
+	}
+	// the server simulates the game, the local logic only keeps its state
+	b.logic.HandleAction(action, discard)
+	if o, ok := agent.(Observer); ok {
+		o.Observe(b, action)
+	}
+	return nil
+}
+
+func discard(game.Action) {}
+
+// Step - a tick of the bot, the agent plays once the bot has joined the game
+func (b *Bot) Step(agent Agent) {
+	if !b.joined {
+		return
+	}
+	b.explore()
+	agent.Tick(b)
+}
+
+// Join - joins the game, resumes the session when the player has already joined
+func (b *Bot) Join() {
+	if b.sessionToken == "" {
+		b.Send(game.PlayerJoinAction{Type: game.PlayerJoinActionType, Payload: b.config.Player})
+		return
+	}
+	b.Send(game.PlayerRejoinAction{
+		Type: game.PlayerRejoinActionType,
+		Payload: game.PlayerRejoinPayload{
+			Player:       b.config.Player,
+			SessionToken: b.sessionToken,
+		},
+	})
+}
+
+// explore - loads the map chunks around the player's units
+func (b *Bot) explore() {
+	for _, u := range b.Units() {
+		p := u.Position.ImagePoint()
+		area := image.Rectangle{Min: p, Max: p.Add(image.Pt(1, 1))}.Inset(-chunkMargin * world.ChunkSize)
+		for _, id := range world.ChunksIn(area) {
+			b.LoadChunk(id)
+		}
+	}
+}
+
+// LoadChunk - requests the chunk of the map unless it was requested before
+func (b *Bot) LoadChunk(id world.ChunkId) {
+	if b.chunks[id] {
+		return
+	}
+	b.chunks[id] = true
+	b.Send(game.NewMapLoadAction(id.Rect(), b.PlayerId()))
+}
+
+// Send - numbers the action and sends it to the server, failures show up as ErrorAction or ActionRejected
+func (b *Bot) Send(action game.Action) {
+	if err := b.conn.Send(b.requests.Stamp(action)); err != nil {
+		log.Printf("bot %s: %v", b.config.Player.Name, err)
+		return
+	}
+	b.sent.Add(1)
+}
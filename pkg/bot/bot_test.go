@@ -0,0 +1,185 @@
+package bot_test
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmcszk/fogofgo/pkg/bot"
+	"github.com/bmcszk/fogofgo/pkg/comm"
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{Subprotocols: comm.Subprotocols}
+
+// createTestServer - answers the join with the units, passes the other actions of the bot to the channel
+func createTestServer(t *testing.T, received chan<- game.Action, units ...game.Unit) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Failed to upgrade connection: %v", err)
+			return
+		}
+		c := comm.NewClient(ws)
+		defer c.Close()
+		for {
+			action, err := c.HandleInMessages()
+			if err != nil {
+				return
+			}
+			switch a := action.(type) {
+			case game.PlayerJoinAction:
+				c.PlayerId = a.Payload.Id
+				err = c.Send(game.Reply(game.PlayerJoinSuccessAction{
+					Type: game.PlayerJoinSuccessActionType,
+					Payload: game.PlayerJoinSuccessPayload{
+						PlayerId:     a.Payload.Id,
+						Units:        units,
+						Players:      []game.Player{a.Payload},
+						SessionToken: "token",
+					},
+				}, a.RequestId, 1))
+			case game.RoomJoinAction:
+				err = c.Send(game.RoomJoinFailedAction{
+					Type:    game.RoomJoinFailedActionType,
+					Payload: game.RoomJoinFailedPayload{RoomId: a.Payload.RoomId, Reason: "full"},
+				})
+			default:
+				received <- action
+			}
+			if err != nil {
+				t.Errorf("Failed to send: %v", err)
+				return
+			}
+		}
+	}))
+}
+
+func dialTestBot(t *testing.T, server *httptest.Server, playerId game.PlayerIdType, room game.RoomIdType) *bot.Bot {
+	t.Helper()
+	b, err := bot.Dial(context.Background(), bot.Config{
+		URL:      "ws" + strings.TrimPrefix(server.URL, "http") + "/",
+		Player:   game.Player{Id: playerId, Name: "bot"},
+		Room:     room,
+		TickRate: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = b.Close() })
+	return b
+}
+
+func TestBot_Run_AgentGivesOrders(t *testing.T) {
+	received := make(chan game.Action, 64)
+	playerId := game.PlayerIdType(game.NewUnitId())
+	unit := game.NewUnit(playerId, color.RGBA{255, 0, 0, 255}, game.NewPF(3, 3), 16, 16)
+	server := createTestServer(t, received, *unit)
+	defer server.Close()
+	b := dialTestBot(t, server, playerId, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ordered := false
+	run := make(chan error, 1)
+	go func() {
+		run <- b.Run(ctx, bot.AgentFunc(func(b *bot.Bot) {
+			if ordered {
+				return
+			}
+			for _, u := range b.Units() {
+				b.Move(u.Id, image.Pt(5, 5))
+				ordered = true
+			}
+		}))
+	}()
+
+	move := receiveMove(t, ctx, received)
+	cancel()
+
+	if move.Payload.UnitId != unit.Id || move.Payload.Point != image.Pt(5, 5) {
+		t.Errorf("unexpected move %+v", move.Payload)
+	}
+	if move.RequestId == 0 {
+		t.Error("expected the order to be numbered")
+	}
+	if err := <-run; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the run to be cancelled, got %v", err)
+	}
+}
+
+// receiveMove - first move sent by the bot, the map around its units is loaded before
+func receiveMove(t *testing.T, ctx context.Context, received <-chan game.Action) game.MoveStartAction {
+	t.Helper()
+	loads := 0
+	for {
+		select {
+		case action := <-received:
+			switch a := action.(type) {
+			case game.MapLoadAction:
+				loads++
+			case game.MoveStartAction:
+				if loads == 0 {
+					t.Error("expected the map around the unit to be loaded")
+				}
+				return a
+			}
+		case <-ctx.Done():
+			t.Fatal("expected the agent to move the unit")
+		}
+	}
+}
+
+func TestBot_Run_RoomJoinFailed(t *testing.T) {
+	server := createTestServer(t, make(chan game.Action, 64))
+	defer server.Close()
+	b := dialTestBot(t, server, game.PlayerIdType(game.NewUnitId()), "missing")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := b.Run(ctx, bot.AgentFunc(func(*bot.Bot) {}))
+
+	if !errors.Is(err, bot.ErrRoomJoinFailed) {
+		t.Errorf("expected %v, got %v", bot.ErrRoomJoinFailed, err)
+	}
+}
+
+// latencyAgent - passes the answered requests to the channel
+type latencyAgent chan game.ActionType
+
+func (latencyAgent) Tick(*bot.Bot) {}
+
+func (a latencyAgent) Answered(_ *bot.Bot, action game.Action, _ time.Duration) {
+	a <- action.GetType()
+}
+
+func TestBot_Run_ReportsLatency(t *testing.T) {
+	server := createTestServer(t, make(chan game.Action, 64))
+	defer server.Close()
+	b := dialTestBot(t, server, game.PlayerIdType(game.NewUnitId()), "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	answered := make(latencyAgent, 1)
+	go func() { _ = b.Run(ctx, answered) }()
+
+	select {
+	case actionType := <-answered:
+		if actionType != game.PlayerJoinSuccessActionType {
+			t.Errorf("expected the join to be answered, got %s", actionType)
+		}
+	case <-ctx.Done():
+		t.Fatal("expected the round trip of the join")
+	}
+	if b.Sent() == 0 {
+		t.Error("expected the sent actions to be counted")
+	}
+}
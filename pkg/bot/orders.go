@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"image"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+)
+
+// Move - walks the unit to the point
+func (b *Bot) Move(unitId game.UnitIdType, p image.Point) {
+	b.Send(game.MoveStartAction{
+		Type:    game.MoveStartActionType,
+		Payload: game.MoveStartPayload{UnitId: unitId, Point: p},
+	})
+}
+
+// Attack - the unit chases and attacks the target
+func (b *Bot) Attack(unitId, targetId game.UnitIdType) {
+	b.Send(game.AttackAction{
+		Type:    game.AttackActionType,
+		Payload: game.AttackPayload{UnitId: unitId, TargetId: targetId},
+	})
+}
+
+// Gather - the unit gathers from the resource node
+func (b *Bot) Gather(unitId game.UnitIdType, p image.Point) {
+	b.Send(game.GatherAction{
+		Type:    game.GatherActionType,
+		Payload: game.GatherPayload{UnitId: unitId, Point: p},
+	})
+}
+
+// Build - the worker constructs the building at the point
+func (b *Bot) Build(unitId game.UnitIdType, building game.UnitTypeIdType, p image.Point) {
+	b.Send(game.BuildAction{
+		Type:    game.BuildActionType,
+		Payload: game.BuildPayload{UnitId: unitId, UnitType: building, Point: p},
+	})
+}
+
+// Produce - queues the unit type in the building
+func (b *Bot) Produce(buildingId game.UnitIdType, unitType game.UnitTypeIdType) {
+	b.Send(game.QueueProductionAction{
+		Type:    game.QueueProductionActionType,
+		Payload: game.QueueProductionPayload{BuildingId: buildingId, UnitType: unitType},
+	})
+}
+
+// Order - gives the unit the order, appended to its queue when queue is set
+func (b *Bot) Order(unitId game.UnitIdType, order game.Order, queue bool) {
+	b.Send(game.OrderAction{
+		Type:    game.OrderActionType,
+		Payload: game.OrderPayload{UnitId: unitId, Order: order, Queue: queue},
+	})
+}
+
+// GroupMove - gives the units one order, each one takes its slot in the formation
+func (b *Bot) GroupMove(unitIds []game.UnitIdType, order game.Order, formation game.Formation) {
+	b.Send(game.GroupMoveAction{
+		Type:    game.GroupMoveActionType,
+		Payload: game.GroupMovePayload{UnitIds: unitIds, Order: order, Formation: formation},
+	})
+}
+
+// Stop - the unit drops its orders and stops
+func (b *Bot) Stop(unitId game.UnitIdType) {
+	b.Send(game.StopAction{
+		Type:    game.StopActionType,
+		Payload: game.StopPayload{UnitId: unitId},
+	})
+}
@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"image"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+)
+
+// PlayerId - id of the bot's player
+func (b *Bot) PlayerId() game.PlayerIdType {
+	return b.config.Player.Id
+}
+
+// Player - the bot's player with its resources, false before joining
+func (b *Bot) Player() (*game.Player, bool) {
+	return b.store.GetPlayer(b.PlayerId())
+}
+
+// SessionToken - token resuming the player's session, empty before joining
+func (b *Bot) SessionToken() string {
+	return b.sessionToken
+}
+
+// Room - room the bot plays in, empty until the server tells
+func (b *Bot) Room() game.RoomIdType {
+	return b.room
+}
+
+// Joined - whether the bot has joined the game
+func (b *Bot) Joined() bool {
+	return b.joined
+}
+
+// Tick - latest tick of the server seen by the bot
+func (b *Bot) Tick() int64 {
+	return b.tick
+}
+
+// Sent - actions sent to the server so far, safe to call while the bot runs
+func (b *Bot) Sent() int64 {
+	return b.sent.Load()
+}
+
+// Store - everything the bot's player sees, units of other players out of sight are not there
+func (b *Bot) Store() game.Store {
+	return b.store
+}
+
+// UnitTypes - definitions of the units sent by the server
+func (b *Bot) UnitTypes() *game.UnitTypes {
+	return b.logic.UnitTypes()
+}
+
+// Units - units and buildings of the bot's player
+func (b *Bot) Units() []*game.Unit {
+	return b.store.GetUnitsByPlayerId(b.PlayerId())
+}
+
+// Enemies - visible units and buildings of the other players
+func (b *Bot) Enemies() []*game.Unit {
+	var enemies []*game.Unit
+	for _, u := range b.store.GetAllUnits() {
+		if u.Owner != b.PlayerId() {
+			enemies = append(enemies, u)
+		}
+	}
+	return enemies
+}
+
+// CanPlace - whether a unit of the type fits at the point as far as the bot knows the map
+func (b *Bot) CanPlace(unitType game.UnitTypeIdType, p image.Point) bool {
+	ut, ok := b.UnitTypes().Get(unitType)
+	if !ok {
+		return false
+	}
+	return b.logic.CanPlace(ut.New(b.PlayerId(), b.config.Player.Color, game.ToPF(p)))
+}
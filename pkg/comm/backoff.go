@@ -0,0 +1,33 @@
+package comm
+
+import (
+	"time"
+)
+
+// Backoff - exponentially growing delay between reconnection attempts
+type Backoff struct {
+	min, max time.Duration
+	attempt  int
+}
+
+func NewBackoff(minDelay, maxDelay time.Duration) *Backoff {
+	return &Backoff{
+		min: minDelay,
+		max: maxDelay,
+	}
+}
+
+// Next - delay before the next attempt, doubled with every call up to the maximum
+func (b *Backoff) Next() time.Duration {
+	d := b.min << b.attempt
+	if d > b.max || d <= 0 {
+		return b.max
+	}
+	b.attempt++
+	return d
+}
+
+// Reset - starts again from the minimal delay
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
@@ -0,0 +1,38 @@
+package comm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmcszk/fogofgo/pkg/comm"
+)
+
+func TestBackoff_Next(t *testing.T) {
+	backoff := comm.NewBackoff(100*time.Millisecond, time.Second)
+
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, e := range expected {
+		if d := backoff.Next(); d != e {
+			t.Errorf("attempt %d: expected %s, got %s", i, e, d)
+		}
+	}
+}
+
+func TestBackoff_Reset(t *testing.T) {
+	backoff := comm.NewBackoff(100*time.Millisecond, time.Second)
+	backoff.Next()
+	backoff.Next()
+
+	backoff.Reset()
+
+	if d := backoff.Next(); d != 100*time.Millisecond {
+		t.Errorf("expected minimal delay after reset, got %s", d)
+	}
+}
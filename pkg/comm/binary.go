@@ -0,0 +1,369 @@
+package comm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/world"
+	"github.com/gorilla/websocket"
+)
+
+// frame tags of the binary codec, actions without their own encoding are sent as JSON
+const (
+	tagJSON byte = iota
+	tagMoveStep
+	tagMoveStop
+	tagMapLoad
+	tagMapLoadSuccess
+)
+
+const (
+	tileWater       = 1 << iota // tile has WaterLevel
+	tilePostGlacial             // tile is PostGlacial
+)
+
+var errShortFrame = errors.New("binary frame too short")
+
+// BinaryCodec - compact encoding of the frequent actions,
+// paths and tiles are delta encoded varints, tile strings go through a string table.
+// The frames start with the tag followed by the envelope.
+type BinaryCodec struct{}
+
+func (BinaryCodec) Marshal(action game.Action) ([]byte, error) {
+	w := &binaryWriter{}
+	switch a := action.(type) {
+	case game.MoveStepAction:
+		w.putByte(tagMoveStep)
+		w.envelope(a.Envelope)
+		w.moveStep(a.Payload)
+	case game.MoveStopAction:
+		w.putByte(tagMoveStop)
+		w.envelope(a.Envelope)
+		w.putID(a.Payload)
+	case game.MapLoadAction:
+		w.putByte(tagMapLoad)
+		w.envelope(a.Envelope)
+		w.putID(a.Payload.PlayerId)
+		w.request(a.Payload.WorldRequest)
+	case game.MapLoadSuccessAction:
+		w.putByte(tagMapLoadSuccess)
+		w.envelope(a.Envelope)
+		w.putID(a.Payload.PlayerId)
+		w.response(a.Payload.WorldResponse)
+	default:
+		data, err := JSONCodec{}.Marshal(action)
+		if err != nil {
+			return nil, err
+		}
+		w.putByte(tagJSON)
+		w.buf = append(w.buf, data...)
+	}
+	return w.buf, nil
+}
+
+func (BinaryCodec) Unmarshal(data []byte) (game.Action, error) {
+	if len(data) == 0 {
+		return nil, errShortFrame
+	}
+	if data[0] == tagJSON {
+		return JSONCodec{}.Unmarshal(data[1:])
+	}
+	r := &binaryReader{buf: data[1:]}
+	envelope := r.envelope()
+	var action game.Action
+	switch data[0] {
+	case tagMoveStep:
+		action = game.MoveStepAction{Type: game.MoveStepActionType, Payload: r.moveStep()}
+	case tagMoveStop:
+		action = game.MoveStopAction{Type: game.MoveStopActionType, Payload: game.UnitIdType(r.readID())}
+	case tagMapLoad:
+		action = game.MapLoadAction{Type: game.MapLoadActionType, Payload: game.MapLoadPayload{
+			PlayerId:     game.PlayerIdType(r.readID()),
+			WorldRequest: r.request(),
+		}}
+	case tagMapLoadSuccess:
+		action = game.MapLoadSuccessAction{Type: game.MapLoadSuccessActionType, Payload: game.MapLoadSuccessPayload{
+			PlayerId:      game.PlayerIdType(r.readID()),
+			WorldResponse: r.response(),
+		}}
+	default:
+		return nil, fmt.Errorf("unknown binary frame tag %d", data[0])
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %w", action.GetType(), r.err)
+	}
+	return action.WithEnvelope(envelope), nil
+}
+
+func (BinaryCodec) MessageType() int {
+	return websocket.BinaryMessage
+}
+
+type binaryWriter struct {
+	buf []byte
+}
+
+func (w *binaryWriter) putByte(b byte) {
+	w.buf = append(w.buf, b)
+}
+
+func (w *binaryWriter) putUvarint(v uint64) {
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+func (w *binaryWriter) putVarint(v int) {
+	w.buf = binary.AppendVarint(w.buf, int64(v))
+}
+
+func (w *binaryWriter) putFloat(f float64) {
+	w.buf = binary.LittleEndian.AppendUint64(w.buf, math.Float64bits(f))
+}
+
+func (w *binaryWriter) putID(id [16]byte) {
+	w.buf = append(w.buf, id[:]...)
+}
+
+func (w *binaryWriter) putString(s string) {
+	w.putUvarint(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *binaryWriter) putPoint(p, prev image.Point) {
+	w.putVarint(p.X - prev.X)
+	w.putVarint(p.Y - prev.Y)
+}
+
+func (w *binaryWriter) envelope(e game.Envelope) {
+	w.putUvarint(uint64(e.RequestId))
+	w.putVarint(int(e.Tick))
+}
+
+func (w *binaryWriter) moveStep(p game.MoveStepPayload) {
+	w.putID(p.UnitId)
+	w.putFloat(p.Position.X)
+	w.putFloat(p.Position.Y)
+	w.putUvarint(uint64(p.Step))
+	w.putFloat(p.Pace)
+	w.putUvarint(uint64(len(p.Path)))
+	prev := image.Point{}
+	for _, pt := range p.Path {
+		w.putPoint(pt, prev)
+		prev = pt
+	}
+}
+
+func (w *binaryWriter) request(r world.WorldRequest) {
+	w.putVarint(r.MinX)
+	w.putVarint(r.MinY)
+	w.putVarint(r.MaxX)
+	w.putVarint(r.MaxY)
+}
+
+func (w *binaryWriter) response(r world.WorldResponse) {
+	w.request(world.WorldRequest{MinX: r.MinX, MinY: r.MinY, MaxX: r.MaxX, MaxY: r.MaxY})
+
+	table := make([]string, 0)
+	index := make(map[string]uint64)
+	ref := func(s string) uint64 {
+		i, ok := index[s]
+		if !ok {
+			i = uint64(len(table))
+			index[s] = i
+			table = append(table, s)
+		}
+		return i
+	}
+	tiles := &binaryWriter{}
+	prev := image.Point{}
+	for _, t := range r.Tiles {
+		tiles.putPoint(t.Point, prev)
+		prev = t.Point
+		tiles.putUvarint(ref(t.Value))
+		tiles.putUvarint(ref(t.LandType))
+		tiles.putUvarint(ref(t.FrontStyleClass))
+		tiles.putUvarint(ref(t.BackStyleClass))
+		tiles.putVarint(t.GroundLevel)
+		var flags byte
+		if t.WaterLevel != nil {
+			flags |= tileWater
+		}
+		if t.PostGlacial {
+			flags |= tilePostGlacial
+		}
+		tiles.putByte(flags)
+		if t.WaterLevel != nil {
+			tiles.putVarint(*t.WaterLevel)
+		}
+	}
+
+	w.putUvarint(uint64(len(table)))
+	for _, s := range table {
+		w.putString(s)
+	}
+	w.putUvarint(uint64(len(r.Tiles)))
+	w.buf = append(w.buf, tiles.buf...)
+}
+
+// binaryReader - decodes the frame, the first error stops reading and is kept in err
+type binaryReader struct {
+	buf []byte
+	err error
+}
+
+func (r *binaryReader) fail() {
+	if r.err == nil {
+		r.err = errShortFrame
+	}
+	r.buf = nil
+}
+
+func (r *binaryReader) readByte() byte {
+	if len(r.buf) < 1 {
+		r.fail()
+		return 0
+	}
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b
+}
+
+func (r *binaryReader) readUvarint() uint64 {
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *binaryReader) readVarint() int {
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return int(v)
+}
+
+// length - element count, bounded by the remaining bytes to reject corrupted frames
+func (r *binaryReader) length() int {
+	n := r.readUvarint()
+	if n > uint64(len(r.buf)) {
+		r.fail()
+		return 0
+	}
+	return int(n)
+}
+
+func (r *binaryReader) readFloat() float64 {
+	if len(r.buf) < 8 {
+		r.fail()
+		return 0
+	}
+	f := math.Float64frombits(binary.LittleEndian.Uint64(r.buf))
+	r.buf = r.buf[8:]
+	return f
+}
+
+func (r *binaryReader) readID() [16]byte {
+	var id [16]byte
+	if len(r.buf) < len(id) {
+		r.fail()
+		return id
+	}
+	copy(id[:], r.buf)
+	r.buf = r.buf[len(id):]
+	return id
+}
+
+func (r *binaryReader) readString() string {
+	n := r.length()
+	s := string(r.buf[:n])
+	r.buf = r.buf[n:]
+	return s
+}
+
+func (r *binaryReader) readPoint(prev image.Point) image.Point {
+	x := r.readVarint()
+	y := r.readVarint()
+	return prev.Add(image.Pt(x, y))
+}
+
+func (r *binaryReader) envelope() game.Envelope {
+	return game.Envelope{
+		RequestId: game.RequestIdType(r.readUvarint()),
+		Tick:      int64(r.readVarint()),
+	}
+}
+
+func (r *binaryReader) moveStep() game.MoveStepPayload {
+	p := game.MoveStepPayload{UnitId: game.UnitIdType(r.readID())}
+	p.Position.X = r.readFloat()
+	p.Position.Y = r.readFloat()
+	p.Step = int(r.readUvarint())
+	p.Pace = r.readFloat()
+	n := r.length()
+	if n == 0 {
+		return p
+	}
+	p.Path = make([]image.Point, n)
+	prev := image.Point{}
+	for i := range p.Path {
+		p.Path[i] = r.readPoint(prev)
+		prev = p.Path[i]
+	}
+	return p
+}
+
+func (r *binaryReader) request() world.WorldRequest {
+	return world.WorldRequest{
+		MinX: r.readVarint(),
+		MinY: r.readVarint(),
+		MaxX: r.readVarint(),
+		MaxY: r.readVarint(),
+	}
+}
+
+func (r *binaryReader) response() world.WorldResponse {
+	req := r.request()
+	resp := world.WorldResponse{MinX: req.MinX, MinY: req.MinY, MaxX: req.MaxX, MaxY: req.MaxY}
+
+	table := make([]string, r.length())
+	for i := range table {
+		table[i] = r.readString()
+	}
+	str := func() string {
+		i := r.readUvarint()
+		if i >= uint64(len(table)) {
+			r.fail()
+			return ""
+		}
+		return table[i]
+	}
+
+	resp.Tiles = make([]world.Tile, r.length())
+	prev := image.Point{}
+	for i := range resp.Tiles {
+		t := &resp.Tiles[i]
+		t.Point = r.readPoint(prev)
+		prev = t.Point
+		t.Value = str()
+		t.LandType = str()
+		t.FrontStyleClass = str()
+		t.BackStyleClass = str()
+		t.GroundLevel = r.readVarint()
+		flags := r.readByte()
+		t.PostGlacial = flags&tilePostGlacial != 0
+		if flags&tileWater != 0 {
+			level := r.readVarint()
+			t.WaterLevel = &level
+		}
+	}
+	return resp
+}
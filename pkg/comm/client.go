@@ -4,14 +4,19 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/bmcszk/fogofgo/pkg/game"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
+// writeTimeout - how long a write may take before the connection is given up as stalled
+const writeTimeout = 10 * time.Second
+
 type Client struct {
 	ws        *websocket.Conn
+	codec     Codec
 	Connected bool
 	PlayerId  game.PlayerIdType
 	mux       sync.Mutex
@@ -20,6 +25,7 @@ type Client struct {
 func NewClient(ws *websocket.Conn) *Client {
 	c := &Client{
 		ws:        ws,
+		codec:     CodecFor(ws.Subprotocol()),
 		Connected: true,
 		mux:       sync.Mutex{},
 	}
@@ -28,18 +34,16 @@ func NewClient(ws *websocket.Conn) *Client {
 
 func (c *Client) HandleInMessages() (game.Action, error) {
 	msgType, bytes, err := c.ws.ReadMessage()
-	if msgType == websocket.CloseMessage || (err != nil && err == websocket.ErrCloseSent) {
+	// a failed read leaves the connection unusable
+	if msgType == websocket.CloseMessage || err != nil {
 		if err := c.ws.Close(); err != nil {
 			log.Println(err)
 		}
 		c.Connected = false
-		log.Printf("player %s connection closed", c.PlayerId)
-		return game.GenericAction[any]{}, err
-	} else if err != nil {
-		log.Println(err)
+		log.Printf("player %s connection closed", uuid.UUID(c.PlayerId))
 		return game.GenericAction[any]{}, err
 	}
-	action, err := game.UnmarshalAction(bytes)
+	action, err := codecOf(msgType).Unmarshal(bytes)
 	if err != nil {
 		log.Println(err)
 		return game.GenericAction[any]{}, err
@@ -55,8 +59,43 @@ func (c *Client) Send(action game.Action) error {
 	c.mux.Lock()
 	defer c.mux.Unlock()
 	log.Printf("player %s sending %s", uuid.UUID(c.PlayerId), action.GetType())
-	if err := c.ws.WriteJSON(action); err != nil {
+	data, err := c.codec.Marshal(action)
+	if err != nil {
+		return err
+	}
+	if err := c.ws.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return fmt.Errorf("write deadline %w", err)
+	}
+	if err := c.ws.WriteMessage(c.codec.MessageType(), data); err != nil {
 		return fmt.Errorf("write %w", err)
 	}
 	return nil
 }
+
+// Reconnect - binds the client to a new connection after the previous one was lost
+func (c *Client) Reconnect(ws *websocket.Conn) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.ws = ws
+	c.codec = CodecFor(ws.Subprotocol())
+	c.Connected = true
+}
+
+// Abort - closes the connection without waiting for a write in progress,
+// the reader fails and marks the client disconnected
+func (c *Client) Abort() error {
+	if err := c.ws.Close(); err != nil {
+		return fmt.Errorf("abort %w", err)
+	}
+	return nil
+}
+
+func (c *Client) Close() error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.Connected = false
+	if err := c.ws.Close(); err != nil {
+		return fmt.Errorf("close %w", err)
+	}
+	return nil
+}
@@ -276,3 +276,46 @@ func TestClient_Connected(t *testing.T) {
 		t.Error("expected client to be marked as disconnected")
 	}
 }
+
+func TestClient_HandleInMessages_ConnectionLost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Fatalf("Failed to upgrade connection: %v", err)
+		}
+		// drop the connection without a close handshake
+		ws.NetConn().Close()
+	}))
+	defer server.Close()
+
+	client := setupTestClient(t, server)
+
+	if _, err := client.HandleInMessages(); err == nil {
+		t.Fatal("expected error on lost connection")
+	}
+	if client.Connected {
+		t.Error("expected client to be disconnected")
+	}
+}
+
+func TestClient_Reconnect(t *testing.T) {
+	server := createReceiveTestServer(t)
+	defer server.Close()
+
+	client := setupTestClient(t, server)
+	client.Connected = false
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/"
+	ws, err := dialWebSocket(wsURL)
+	if err != nil {
+		t.Fatalf("Failed to dial WebSocket: %v", err)
+	}
+	client.Reconnect(ws)
+	defer client.Close()
+
+	if !client.Connected {
+		t.Error("expected client to be connected after reconnect")
+	}
+	action := receiveAndValidateAction(t, client)
+	validatePlayerJoinAction(t, action)
+}
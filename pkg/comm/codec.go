@@ -0,0 +1,61 @@
+package comm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket subprotocols selecting the wire codec
+const (
+	JSONProtocol   = "fogofgo.json"
+	BinaryProtocol = "fogofgo.binary"
+)
+
+// Subprotocols - supported codecs in the order of preference
+var Subprotocols = []string{BinaryProtocol, JSONProtocol}
+
+// Codec - wire format of the actions
+type Codec interface {
+	Marshal(action game.Action) ([]byte, error)
+	Unmarshal(data []byte) (game.Action, error)
+	// MessageType - websocket message type of the encoded actions
+	MessageType() int
+}
+
+// CodecFor - codec negotiated with the subprotocol, JSON when none was agreed on
+func CodecFor(protocol string) Codec {
+	if protocol == BinaryProtocol {
+		return BinaryCodec{}
+	}
+	return JSONCodec{}
+}
+
+// codecOf - codec of the received message, peers may send in either format
+func codecOf(messageType int) Codec {
+	if messageType == websocket.BinaryMessage {
+		return BinaryCodec{}
+	}
+	return JSONCodec{}
+}
+
+// JSONCodec - human readable format, handy for debugging
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(action game.Action) ([]byte, error) {
+	data, err := json.Marshal(action)
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s: %w", action.GetType(), err)
+	}
+	return data, nil
+}
+
+func (JSONCodec) Unmarshal(data []byte) (game.Action, error) {
+	return game.UnmarshalAction(data)
+}
+
+func (JSONCodec) MessageType() int {
+	return websocket.TextMessage
+}
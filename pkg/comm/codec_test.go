@@ -0,0 +1,168 @@
+package comm_test
+
+import (
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bmcszk/fogofgo/pkg/comm"
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/world"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+var codecs = map[string]comm.Codec{
+	"json":   comm.JSONCodec{},
+	"binary": comm.BinaryCodec{},
+}
+
+func createTestMoveStepAction() game.MoveStepAction {
+	path := make([]image.Point, 0, 40)
+	for i := range 40 {
+		path = append(path, image.Pt(100+i, 200-i/2))
+	}
+	return game.MoveStepAction{
+		Type: game.MoveStepActionType,
+		Payload: game.MoveStepPayload{
+			UnitId:   game.UnitIdType(uuid.New()),
+			Position: game.NewPF(112.5, 194.25),
+			Path:     path,
+			Step:     12,
+			Pace:     0.05,
+		},
+	}
+}
+
+func createTestMapLoadSuccessAction() game.MapLoadSuccessAction {
+	generator := world.NewGenerator(1)
+	resp, err := generator.Load(world.WorldRequest{MinX: -20, MinY: -15, MaxX: 20, MaxY: 15})
+	if err != nil {
+		panic(err)
+	}
+	return game.MapLoadSuccessAction{
+		Type: game.MapLoadSuccessActionType,
+		Payload: game.MapLoadSuccessPayload{
+			WorldResponse: *resp,
+			PlayerId:      game.PlayerIdType(uuid.New()),
+		},
+	}
+}
+
+func TestCodec_RoundTrip(t *testing.T) {
+	actions := []game.Action{
+		createTestMoveStepAction(),
+		game.MoveStopAction{Type: game.MoveStopActionType, Payload: game.UnitIdType(uuid.New())},
+		game.NewMapLoadAction(image.Rect(-5, -3, 40, 30), game.PlayerIdType(uuid.New())),
+		createTestMapLoadSuccessAction(),
+		createTestPlayerJoinAction(),
+		game.Reply(createTestMoveStepAction(), 7, 1234),
+		game.NewMapLoadAction(image.Rect(0, 0, 10, 10), game.PlayerIdType(uuid.New())).
+			WithEnvelope(game.Envelope{RequestId: 42}),
+		game.Reply(game.NewAckAction(createTestPlayerJoinAction(), true), 9, 5),
+	}
+	for name, codec := range codecs {
+		for _, action := range actions {
+			data, err := codec.Marshal(action)
+			if err != nil {
+				t.Fatalf("%s marshal %s: %v", name, action.GetType(), err)
+			}
+			decoded, err := codec.Unmarshal(data)
+			if err != nil {
+				t.Fatalf("%s unmarshal %s: %v", name, action.GetType(), err)
+			}
+			if !reflect.DeepEqual(action, decoded) {
+				t.Errorf("%s round trip of %s changed the action:\n%+v\n%+v", name, action.GetType(), action, decoded)
+			}
+		}
+	}
+}
+
+func TestBinaryCodec_Unmarshal_Truncated(t *testing.T) {
+	data, err := comm.BinaryCodec{}.Marshal(createTestMapLoadSuccessAction())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range []int{0, 1, 10, len(data) / 2, len(data) - 1} {
+		if _, err := (comm.BinaryCodec{}).Unmarshal(data[:n]); err == nil {
+			t.Errorf("expected error for frame truncated to %d bytes", n)
+		}
+	}
+}
+
+func TestBinaryCodec_IsSmaller(t *testing.T) {
+	for _, action := range []game.Action{createTestMoveStepAction(), createTestMapLoadSuccessAction()} {
+		jsonData, _ := comm.JSONCodec{}.Marshal(action)
+		binaryData, _ := comm.BinaryCodec{}.Marshal(action)
+		if len(binaryData)*4 > len(jsonData) {
+			t.Errorf("expected %s binary frame to be at least 4x smaller, got %d vs %d bytes",
+				action.GetType(), len(binaryData), len(jsonData))
+		}
+	}
+}
+
+func TestClient_NegotiatesBinaryCodec(t *testing.T) {
+	negotiating := websocket.Upgrader{Subprotocols: comm.Subprotocols}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := negotiating.Upgrade(w, r, nil)
+		if err != nil {
+			t.Fatalf("Failed to upgrade connection: %v", err)
+		}
+		defer ws.Close()
+		if err := comm.NewClient(ws).Send(createTestPlayerJoinAction()); err != nil {
+			t.Errorf("Failed to send: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/"
+	dialer := websocket.Dialer{Subprotocols: comm.Subprotocols}
+	ws, _, err := dialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial WebSocket: %v", err)
+	}
+	defer ws.Close()
+
+	if ws.Subprotocol() != comm.BinaryProtocol {
+		t.Errorf("expected %s subprotocol, got %q", comm.BinaryProtocol, ws.Subprotocol())
+	}
+	action := receiveAndValidateAction(t, comm.NewClient(ws))
+	validatePlayerJoinAction(t, action)
+}
+
+func benchmarkCodec(b *testing.B, codec comm.Codec, action game.Action) {
+	b.Helper()
+	data, err := codec.Marshal(action)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		data, _ := codec.Marshal(action)
+		if _, err := codec.Unmarshal(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(data)), "bytes/msg")
+}
+
+func BenchmarkJSONCodec_MoveStep(b *testing.B) {
+	benchmarkCodec(b, comm.JSONCodec{}, createTestMoveStepAction())
+}
+
+func BenchmarkBinaryCodec_MoveStep(b *testing.B) {
+	benchmarkCodec(b, comm.BinaryCodec{}, createTestMoveStepAction())
+}
+
+func BenchmarkJSONCodec_MapLoadSuccess(b *testing.B) {
+	benchmarkCodec(b, comm.JSONCodec{}, createTestMapLoadSuccessAction())
+}
+
+func BenchmarkBinaryCodec_MapLoadSuccess(b *testing.B) {
+	benchmarkCodec(b, comm.BinaryCodec{}, createTestMapLoadSuccessAction())
+}
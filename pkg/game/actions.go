@@ -11,24 +11,67 @@ import (
 type ActionType string
 
 const (
-	PlayerJoinActionType        ActionType = "PlayerJoin"
-	PlayerJoinSuccessActionType ActionType = "PlayerJoinSuccess"
-	SpawnUnitActionType         ActionType = "SpawnUnit"
-	MoveStartActionType         ActionType = "MoveStart"
-	MoveStepActionType          ActionType = "MoveStep"
-	MoveStopActionType          ActionType = "MoveStop"
-	MapLoadActionType           ActionType = "MapLoad"
-	MapLoadSuccessActionType    ActionType = "MapLoadSuccess"
+	PlayerJoinActionType         ActionType = "PlayerJoin"
+	PlayerJoinSuccessActionType  ActionType = "PlayerJoinSuccess"
+	PlayerRejoinActionType       ActionType = "PlayerRejoin"
+	SpawnUnitActionType          ActionType = "SpawnUnit"
+	MoveStartActionType          ActionType = "MoveStart"
+	MoveStepActionType           ActionType = "MoveStep"
+	MoveStopActionType           ActionType = "MoveStop"
+	MapLoadActionType            ActionType = "MapLoad"
+	MapLoadSuccessActionType     ActionType = "MapLoadSuccess"
+	UnitEnteredVisionActionType  ActionType = "UnitEnteredVision"
+	UnitLeftVisionActionType     ActionType = "UnitLeftVision"
+	AttackActionType             ActionType = "Attack"
+	UnitDamagedActionType        ActionType = "UnitDamaged"
+	UnitDestroyedActionType      ActionType = "UnitDestroyed"
+	RoomListActionType           ActionType = "RoomList"
+	RoomListSuccessActionType    ActionType = "RoomListSuccess"
+	RoomCreateActionType         ActionType = "RoomCreate"
+	RoomJoinActionType           ActionType = "RoomJoin"
+	RoomJoinSuccessActionType    ActionType = "RoomJoinSuccess"
+	RoomJoinFailedActionType     ActionType = "RoomJoinFailed"
+	RoomLeaveActionType          ActionType = "RoomLeave"
+	RoomStateActionType          ActionType = "RoomState"
+	GatherActionType             ActionType = "Gather"
+	ResourceGatheredActionType   ActionType = "ResourceGathered"
+	ResourcesSpentActionType     ActionType = "ResourcesSpent"
+	BuildActionType              ActionType = "Build"
+	QueueProductionActionType    ActionType = "QueueProduction"
+	ProductionQueuedActionType   ActionType = "ProductionQueued"
+	ProductionCompleteActionType ActionType = "ProductionComplete"
+	SetRallyPointActionType      ActionType = "SetRallyPoint"
+	ActionRejectedActionType     ActionType = "ActionRejected"
+	ErrorActionType              ActionType = "Error"
+	AckActionType                ActionType = "Ack"
+	OrderActionType              ActionType = "Order"
+	StopActionType               ActionType = "Stop"
+	UnitOrdersActionType         ActionType = "UnitOrders"
+	GroupMoveActionType          ActionType = "GroupMove"
 )
 
 type Action interface {
 	GetType() ActionType
 	GetPayload() any
+	GetEnvelope() Envelope
+	// WithEnvelope - copy of the action with the delivery data
+	WithEnvelope(Envelope) Action
+}
+
+// RequestIdType - sequence number of the client's request, unique per player
+type RequestIdType uint64
+
+// Envelope - delivery data of the action. Clients number their requests,
+// the server echoes the number in the replies and stamps the tick the action was handled in.
+type Envelope struct {
+	RequestId RequestIdType `json:",omitempty"`
+	Tick      int64         `json:",omitempty"`
 }
 
 type GenericAction[T any] struct {
 	Type    ActionType
 	Payload T
+	Envelope
 }
 
 func (a GenericAction[T]) GetType() ActionType {
@@ -39,14 +82,33 @@ func (a GenericAction[T]) GetPayload() any {
 	return a.Payload
 }
 
+func (a GenericAction[T]) GetEnvelope() Envelope {
+	return a.Envelope
+}
+
+func (a GenericAction[T]) WithEnvelope(e Envelope) Action {
+	a.Envelope = e
+	return a
+}
+
 type PlayerJoinAction = GenericAction[Player]
 
 type PlayerJoinSuccessAction = GenericAction[PlayerJoinSuccessPayload]
 
 type PlayerJoinSuccessPayload struct {
-	PlayerId PlayerIdType
-	Units    []Unit
-	Players  []Player
+	PlayerId     PlayerIdType
+	Units        []Unit
+	Players      []Player
+	SessionToken string
+	UnitTypes    []UnitType
+}
+
+// PlayerRejoinAction - resumes the session of a player after the connection was lost
+type PlayerRejoinAction = GenericAction[PlayerRejoinPayload]
+
+type PlayerRejoinPayload struct {
+	Player       Player
+	SessionToken string
 }
 
 type SpawnUnitAction = GenericAction[Unit]
@@ -65,23 +127,20 @@ type MoveStepPayload struct {
 	Position PF
 	Path     []image.Point
 	Step     int
+	Pace     float64 `json:",omitempty"` // speed of the unit's group, zero when moving alone
 }
 
 type MoveStopAction = GenericAction[UnitIdType]
 
 type MapLoadAction = GenericAction[MapLoadPayload]
 
+// NewMapLoadAction - request of the tiles of the rect, the rect's max is exclusive
 func NewMapLoadAction(rect image.Rectangle, playerId PlayerIdType) MapLoadAction {
 	return MapLoadAction{
 		Type: MapLoadActionType,
 		Payload: MapLoadPayload{
-			WorldRequest: world.WorldRequest{
-				MinX: rect.Min.X,
-				MinY: rect.Min.Y,
-				MaxX: rect.Max.X,
-				MaxY: rect.Max.Y,
-			},
-			PlayerId: playerId,
+			WorldRequest: world.NewWorldRequest(rect),
+			PlayerId:     playerId,
 		},
 	}
 }
@@ -98,6 +157,210 @@ type MapLoadSuccessPayload struct {
 	PlayerId PlayerIdType
 }
 
+// UnitEnteredVisionAction - unit became visible to the receiving player
+type UnitEnteredVisionAction = GenericAction[UnitEnteredVisionPayload]
+
+type UnitEnteredVisionPayload struct {
+	Unit Unit
+}
+
+// UnitLeftVisionAction - unit is no longer visible to the receiving player
+type UnitLeftVisionAction = GenericAction[UnitLeftVisionPayload]
+
+type UnitLeftVisionPayload struct {
+	UnitId UnitIdType
+}
+
+type AttackAction = GenericAction[AttackPayload]
+
+type AttackPayload struct {
+	UnitId   UnitIdType
+	TargetId UnitIdType
+}
+
+type UnitDamagedAction = GenericAction[UnitDamagedPayload]
+
+type UnitDamagedPayload struct {
+	UnitId     UnitIdType
+	AttackerId UnitIdType
+	Damage     int
+	Health     int
+}
+
+type UnitDestroyedAction = GenericAction[UnitDestroyedPayload]
+
+type UnitDestroyedPayload struct {
+	UnitId     UnitIdType
+	AttackerId UnitIdType
+}
+
+type RoomListAction = GenericAction[RoomListPayload]
+
+type RoomListPayload struct{}
+
+type RoomListSuccessAction = GenericAction[RoomListSuccessPayload]
+
+type RoomListSuccessPayload struct {
+	Rooms []RoomInfo
+}
+
+type RoomCreateAction = GenericAction[RoomConfig]
+
+type RoomJoinAction = GenericAction[RoomJoinPayload]
+
+type RoomJoinPayload struct {
+	RoomId RoomIdType
+}
+
+type RoomJoinSuccessAction = GenericAction[RoomInfo]
+
+type RoomJoinFailedAction = GenericAction[RoomJoinFailedPayload]
+
+type RoomJoinFailedPayload struct {
+	RoomId RoomIdType
+	Reason string
+}
+
+type RoomLeaveAction = GenericAction[RoomLeavePayload]
+
+type RoomLeavePayload struct{}
+
+// RoomStateAction - lifecycle change of the room, sent to its players
+type RoomStateAction = GenericAction[RoomStatePayload]
+
+type RoomStatePayload struct {
+	Room RoomInfo
+}
+
+// GatherAction - orders the unit to gather resources from the tile
+type GatherAction = GenericAction[GatherPayload]
+
+type GatherPayload struct {
+	UnitId UnitIdType
+	Point  image.Point
+}
+
+// ResourceGatheredAction - unit took resources from the tile into its owner's stockpile,
+// the other players seeing the tile get it without the unit and the player
+type ResourceGatheredAction = GenericAction[ResourceGatheredPayload]
+
+type ResourceGatheredPayload struct {
+	UnitId   UnitIdType
+	PlayerId PlayerIdType
+	Point    image.Point
+	Resource ResourceType
+	Amount   int
+}
+
+// ResourcesSpentAction - player paid from the stockpile
+type ResourcesSpentAction = GenericAction[ResourcesSpentPayload]
+
+type ResourcesSpentPayload struct {
+	PlayerId  PlayerIdType
+	Resources Resources
+}
+
+// BuildAction - orders the unit to construct a building at the point
+type BuildAction = GenericAction[BuildPayload]
+
+type BuildPayload struct {
+	UnitId   UnitIdType
+	UnitType UnitTypeIdType // building to construct
+	Point    image.Point
+}
+
+// QueueProductionAction - asks the building to produce a unit
+type QueueProductionAction = GenericAction[QueueProductionPayload]
+
+type QueueProductionPayload struct {
+	BuildingId UnitIdType
+	UnitType   UnitTypeIdType
+}
+
+// ProductionQueuedAction - production was paid and added to the building's queue
+type ProductionQueuedAction = GenericAction[ProductionQueuedPayload]
+
+type ProductionQueuedPayload struct {
+	BuildingId UnitIdType
+	Production Production
+}
+
+// ProductionCompleteAction - building finished the first unit in its queue
+type ProductionCompleteAction = GenericAction[ProductionCompletePayload]
+
+type ProductionCompletePayload struct {
+	BuildingId UnitIdType
+	Unit       Unit
+}
+
+// SetRallyPointAction - sets where the units produced by the building go
+type SetRallyPointAction = GenericAction[SetRallyPointPayload]
+
+type SetRallyPointPayload struct {
+	BuildingId UnitIdType
+	Point      image.Point
+}
+
+// ActionRejectedAction - server refused the action sent by the client
+type ActionRejectedAction = GenericAction[ActionRejectedPayload]
+
+type ActionRejectedPayload struct {
+	Action  ActionType
+	Code    ErrorCode
+	Message string
+}
+
+// ErrorAction - action of the player failed while handled
+type ErrorAction = GenericAction[ErrorPayload]
+
+type ErrorPayload struct {
+	PlayerId PlayerIdType
+	Action   ActionType
+	Code     ErrorCode
+	Message  string
+}
+
+// AckAction - server handled the request with the id in the envelope
+type AckAction = GenericAction[AckPayload]
+
+type AckPayload struct {
+	Action    ActionType
+	Duplicate bool // request was handled before, the retry was ignored
+}
+
+// OrderAction - gives the unit an order, appended to its queue when Queue is set, replacing the queue otherwise
+type OrderAction = GenericAction[OrderPayload]
+
+type OrderPayload struct {
+	UnitId UnitIdType
+	Order  Order
+	Queue  bool
+}
+
+// StopAction - the unit drops its orders and stops where it is
+type StopAction = GenericAction[StopPayload]
+
+type StopPayload struct {
+	UnitId UnitIdType
+}
+
+// UnitOrdersAction - orders left in the unit's queue, sent to the owner when they change
+type UnitOrdersAction = GenericAction[UnitOrdersPayload]
+
+type UnitOrdersPayload struct {
+	UnitId UnitIdType
+	Orders []Order
+}
+
+type GroupMoveAction = GenericAction[GroupMovePayload]
+
+type GroupMovePayload struct {
+	UnitIds   []UnitIdType
+	Order     Order
+	Formation Formation
+	Queue     bool
+}
+
 func UnmarshalAction(bytes []byte) (Action, error) {
 	actionType, err := extractActionType(bytes)
 	if err != nil {
@@ -108,7 +371,8 @@ func UnmarshalAction(bytes []byte) (Action, error) {
 }
 
 func extractActionType(bytes []byte) (ActionType, error) {
-	var msg GenericAction[any]
+	// the payload is skipped, it is decoded once its type is known
+	var msg struct{ Type ActionType }
 	if err := json.Unmarshal(bytes, &msg); err != nil {
 		return "", err
 	}
@@ -121,6 +385,8 @@ func unmarshalByType(bytes []byte, actionType ActionType) (Action, error) {
 		return unmarshalPlayerJoinAction(bytes)
 	case PlayerJoinSuccessActionType:
 		return unmarshalPlayerJoinSuccessAction(bytes)
+	case PlayerRejoinActionType:
+		return unmarshalPlayerRejoinAction(bytes)
 	case SpawnUnitActionType:
 		return unmarshalSpawnUnitAction(bytes)
 	case MoveStartActionType:
@@ -133,6 +399,62 @@ func unmarshalByType(bytes []byte, actionType ActionType) (Action, error) {
 		return unmarshalMapLoadAction(bytes)
 	case MapLoadSuccessActionType:
 		return unmarshalMapLoadSuccessAction(bytes)
+	case UnitEnteredVisionActionType:
+		return unmarshalUnitEnteredVisionAction(bytes)
+	case UnitLeftVisionActionType:
+		return unmarshalUnitLeftVisionAction(bytes)
+	case AttackActionType:
+		return unmarshalAttackAction(bytes)
+	case UnitDamagedActionType:
+		return unmarshalUnitDamagedAction(bytes)
+	case UnitDestroyedActionType:
+		return unmarshalUnitDestroyedAction(bytes)
+	case RoomListActionType:
+		return unmarshalRoomListAction(bytes)
+	case RoomListSuccessActionType:
+		return unmarshalRoomListSuccessAction(bytes)
+	case RoomCreateActionType:
+		return unmarshalRoomCreateAction(bytes)
+	case RoomJoinActionType:
+		return unmarshalRoomJoinAction(bytes)
+	case RoomJoinSuccessActionType:
+		return unmarshalRoomJoinSuccessAction(bytes)
+	case RoomJoinFailedActionType:
+		return unmarshalRoomJoinFailedAction(bytes)
+	case RoomLeaveActionType:
+		return unmarshalRoomLeaveAction(bytes)
+	case RoomStateActionType:
+		return unmarshalRoomStateAction(bytes)
+	case GatherActionType:
+		return unmarshalGatherAction(bytes)
+	case ResourceGatheredActionType:
+		return unmarshalResourceGatheredAction(bytes)
+	case ResourcesSpentActionType:
+		return unmarshalResourcesSpentAction(bytes)
+	case BuildActionType:
+		return unmarshalBuildAction(bytes)
+	case QueueProductionActionType:
+		return unmarshalQueueProductionAction(bytes)
+	case ProductionQueuedActionType:
+		return unmarshalProductionQueuedAction(bytes)
+	case ProductionCompleteActionType:
+		return unmarshalProductionCompleteAction(bytes)
+	case SetRallyPointActionType:
+		return unmarshalSetRallyPointAction(bytes)
+	case ActionRejectedActionType:
+		return unmarshalActionRejectedAction(bytes)
+	case ErrorActionType:
+		return unmarshalErrorAction(bytes)
+	case AckActionType:
+		return unmarshalAckAction(bytes)
+	case OrderActionType:
+		return unmarshalOrderAction(bytes)
+	case StopActionType:
+		return unmarshalStopAction(bytes)
+	case UnitOrdersActionType:
+		return unmarshalUnitOrdersAction(bytes)
+	case GroupMoveActionType:
+		return unmarshalGroupMoveAction(bytes)
 	default:
 		return nil, errors.New("action type unrecognized")
 	}
@@ -154,6 +476,14 @@ func unmarshalPlayerJoinSuccessAction(bytes []byte) (Action, error) {
 	return action, nil
 }
 
+func unmarshalPlayerRejoinAction(bytes []byte) (Action, error) {
+	var action PlayerRejoinAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
 func unmarshalSpawnUnitAction(bytes []byte) (Action, error) {
 	var action SpawnUnitAction
 	if err := json.Unmarshal(bytes, &action); err != nil {
@@ -201,3 +531,227 @@ func unmarshalMapLoadSuccessAction(bytes []byte) (Action, error) {
 	}
 	return action, nil
 }
+
+func unmarshalUnitEnteredVisionAction(bytes []byte) (Action, error) {
+	var action UnitEnteredVisionAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalUnitLeftVisionAction(bytes []byte) (Action, error) {
+	var action UnitLeftVisionAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalAttackAction(bytes []byte) (Action, error) {
+	var action AttackAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalUnitDamagedAction(bytes []byte) (Action, error) {
+	var action UnitDamagedAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalUnitDestroyedAction(bytes []byte) (Action, error) {
+	var action UnitDestroyedAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalRoomListAction(bytes []byte) (Action, error) {
+	var action RoomListAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalRoomListSuccessAction(bytes []byte) (Action, error) {
+	var action RoomListSuccessAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalRoomCreateAction(bytes []byte) (Action, error) {
+	var action RoomCreateAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalRoomJoinAction(bytes []byte) (Action, error) {
+	var action RoomJoinAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalRoomJoinSuccessAction(bytes []byte) (Action, error) {
+	var action RoomJoinSuccessAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalRoomJoinFailedAction(bytes []byte) (Action, error) {
+	var action RoomJoinFailedAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalRoomLeaveAction(bytes []byte) (Action, error) {
+	var action RoomLeaveAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalRoomStateAction(bytes []byte) (Action, error) {
+	var action RoomStateAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalGatherAction(bytes []byte) (Action, error) {
+	var action GatherAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalResourceGatheredAction(bytes []byte) (Action, error) {
+	var action ResourceGatheredAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalResourcesSpentAction(bytes []byte) (Action, error) {
+	var action ResourcesSpentAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalBuildAction(bytes []byte) (Action, error) {
+	var action BuildAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalQueueProductionAction(bytes []byte) (Action, error) {
+	var action QueueProductionAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalProductionQueuedAction(bytes []byte) (Action, error) {
+	var action ProductionQueuedAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalProductionCompleteAction(bytes []byte) (Action, error) {
+	var action ProductionCompleteAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalSetRallyPointAction(bytes []byte) (Action, error) {
+	var action SetRallyPointAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalActionRejectedAction(bytes []byte) (Action, error) {
+	var action ActionRejectedAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalErrorAction(bytes []byte) (Action, error) {
+	var action ErrorAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalAckAction(bytes []byte) (Action, error) {
+	var action AckAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalOrderAction(bytes []byte) (Action, error) {
+	var action OrderAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalStopAction(bytes []byte) (Action, error) {
+	var action StopAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalUnitOrdersAction(bytes []byte) (Action, error) {
+	var action UnitOrdersAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+func unmarshalGroupMoveAction(bytes []byte) (Action, error) {
+	var action GroupMoveAction
+	if err := json.Unmarshal(bytes, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
@@ -0,0 +1,133 @@
+package game
+
+import (
+	"image"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+const (
+	BuildRange  = 4 // max distance between the builder and the new building
+	MaxQueue    = 5 // max units queued in a building
+	spawnRadius = 4 // how far from the building produced units are placed
+)
+
+// Production - unit being produced by a building
+type Production struct {
+	Type UnitTypeIdType
+	Time int // ticks left
+}
+
+// Tiles - tiles taken by the unit, buildings take their whole footprint
+func (u *Unit) Tiles() []image.Point {
+	p := u.Position.ImagePoint()
+	if u.Footprint.X < 1 || u.Footprint.Y < 1 {
+		return []image.Point{p}
+	}
+	r := make([]image.Point, 0, u.Footprint.X*u.Footprint.Y)
+	for x := range u.Footprint.X {
+		for y := range u.Footprint.Y {
+			r = append(r, p.Add(image.Pt(x, y)))
+		}
+	}
+	return r
+}
+
+// CanPlace - whether all tiles of the unit are passable and free
+func (g *GameLogic) CanPlace(unit *Unit) bool {
+	for _, p := range unit.Tiles() {
+		if !g.pathfinder.free(unit, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// FreeTileAround - nearest tile around the building where the unit can be placed
+func (g *GameLogic) FreeTileAround(building, unit *Unit) (image.Point, bool) {
+	tiles := building.Tiles()
+	area := image.Rectangle{Min: tiles[0], Max: tiles[len(tiles)-1].Add(image.Pt(1, 1))}
+	for r := 1; r <= spawnRadius; r++ {
+		ring := area.Inset(-r)
+		for x := ring.Min.X; x < ring.Max.X; x++ {
+			for y := ring.Min.Y; y < ring.Max.Y; y++ {
+				p := image.Pt(x, y)
+				if p.In(ring.Inset(1)) {
+					continue
+				}
+				if g.pathfinder.free(unit, p) {
+					return p, true
+				}
+			}
+		}
+	}
+	return image.Point{}, false
+}
+
+func (g *GameLogic) handleProductionQueuedAction(action ProductionQueuedAction) {
+	building := g.store.GetUnitById(action.Payload.BuildingId)
+	if building == nil {
+		log.Printf("production queued: unknown building %s", uuid.UUID(action.Payload.BuildingId))
+		return
+	}
+	building.Queue = append(building.Queue, action.Payload.Production)
+}
+
+func (g *GameLogic) handleProductionCompleteAction(action ProductionCompleteAction) {
+	if building := g.store.GetUnitById(action.Payload.BuildingId); building != nil && len(building.Queue) > 0 {
+		building.Queue = building.Queue[1:]
+	}
+	unit := action.Payload.Unit
+	g.freeTiles(unit.Id)
+	g.store.StoreUnit(&unit)
+	if err := g.placeUnit(&unit); err != nil {
+		log.Println(err)
+	}
+}
+
+func (g *GameLogic) handleSetRallyPointAction(action SetRallyPointAction) {
+	building := g.store.GetUnitById(action.Payload.BuildingId)
+	if building == nil || !building.Building {
+		return
+	}
+	rally := action.Payload.Point
+	building.Rally = &rally
+}
+
+// updateProduction - produces the first unit in the building's queue
+func (g *GameLogic) updateProduction(building *Unit, dispatch DispatchFunc) {
+	if len(building.Queue) == 0 {
+		return
+	}
+	if building.Queue[0].Time > 0 {
+		building.Queue[0].Time--
+		return
+	}
+	ut, ok := g.types.Get(building.Queue[0].Type)
+	if !ok {
+		log.Printf("production: unknown unit type %s", building.Queue[0].Type)
+		building.Queue = building.Queue[1:]
+		return
+	}
+	unit := ut.New(building.Owner, building.Color, building.Position)
+	p, ok := g.FreeTileAround(building, unit)
+	if !ok {
+		// blocked, tries again in the next tick
+		return
+	}
+	unit.Position = ToPF(p)
+	dispatch(ProductionCompleteAction{
+		Type: ProductionCompleteActionType,
+		Payload: ProductionCompletePayload{
+			BuildingId: building.Id,
+			Unit:       *unit,
+		},
+	})
+	if building.Rally == nil {
+		return
+	}
+	if produced := g.store.GetUnitById(unit.Id); produced != nil {
+		g.walk(produced, *building.Rally, dispatch)
+	}
+}
@@ -0,0 +1,136 @@
+package game_test
+
+import (
+	"image"
+	"testing"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/world"
+)
+
+func TestGameLogic_HandleAction_SpawnBuildingTakesFootprint(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	building := createTestBuilding(player, game.NewPF(2, 2))
+
+	logic.HandleAction(game.SpawnUnitAction{Type: game.SpawnUnitActionType, Payload: *building}, nil)
+
+	tiles := store.GetTilesByUnitId(building.Id)
+	if len(tiles) != 4 {
+		t.Fatalf("expected building to take 4 tiles, got %d", len(tiles))
+	}
+	for _, p := range []image.Point{image.Pt(2, 2), image.Pt(3, 2), image.Pt(2, 3), image.Pt(3, 3)} {
+		if tile, ok := store.GetTile(p); !ok || tile.Unit == nil || tile.Unit.Id != building.Id {
+			t.Errorf("tile %v should be taken by the building", p)
+		}
+	}
+}
+
+func TestGameLogic_CanPlace(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	unit := game.NewUnit(player.Id, player.Color, game.NewPF(3, 3), 16, 16)
+	logic.HandleAction(game.SpawnUnitAction{Type: game.SpawnUnitActionType, Payload: *unit}, nil)
+	store.StoreTile(world.Tile{Point: image.Pt(11, 10), LandType: world.LandLake})
+
+	if logic.CanPlace(createTestBuilding(player, game.NewPF(2, 2))) {
+		t.Error("building should not be placed over a unit")
+	}
+	if logic.CanPlace(createTestBuilding(player, game.NewPF(10, 10))) {
+		t.Error("building should not be placed on water")
+	}
+	if !logic.CanPlace(createTestBuilding(player, game.NewPF(5, 5))) {
+		t.Error("building should be placed on free tiles")
+	}
+}
+
+func TestGameLogic_FreeTileAround(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	building := createTestBuilding(player, game.NewPF(2, 2))
+	logic.HandleAction(game.SpawnUnitAction{Type: game.SpawnUnitActionType, Payload: *building}, nil)
+
+	p, ok := logic.FreeTileAround(building, game.NewUnit(player.Id, player.Color, building.Position, 16, 16))
+
+	if !ok {
+		t.Fatal("expected a free tile")
+	}
+	if p.In(image.Rect(2, 2, 4, 4)) || !p.In(image.Rect(1, 1, 5, 5)) {
+		t.Errorf("expected a tile next to the building, got %v", p)
+	}
+}
+
+func TestGameLogic_Update_ProducesUnit(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	building := createTestBuilding(player, game.NewPF(2, 2))
+	logic.HandleAction(game.SpawnUnitAction{Type: game.SpawnUnitActionType, Payload: *building}, nil)
+	logic.HandleAction(game.ProductionQueuedAction{
+		Type: game.ProductionQueuedActionType,
+		Payload: game.ProductionQueuedPayload{
+			BuildingId: building.Id,
+			Production: game.Production{Type: game.WorkerType, Time: 2},
+		},
+	}, nil)
+	rally := image.Pt(8, 2)
+	logic.HandleAction(game.SetRallyPointAction{
+		Type:    game.SetRallyPointActionType,
+		Payload: game.SetRallyPointPayload{BuildingId: building.Id, Point: rally},
+	}, nil)
+
+	var dispatchedActions []game.Action
+	dispatchFunc := func(action game.Action) {
+		dispatchedActions = append(dispatchedActions, action)
+		logic.HandleAction(action, nil)
+	}
+	for range 3 {
+		logic.Update(dispatchFunc)
+	}
+
+	if len(dispatchedActions) != 2 {
+		t.Fatalf("expected 2 dispatched actions, got %d: %v", len(dispatchedActions), dispatchedActions)
+	}
+	complete, ok := dispatchedActions[0].(game.ProductionCompleteAction)
+	if !ok {
+		t.Fatalf("expected ProductionCompleteAction, got %T", dispatchedActions[0])
+	}
+	produced := store.GetUnitById(complete.Payload.Unit.Id)
+	if produced == nil || produced.Owner != player.Id {
+		t.Fatal("produced unit should be stored for the building's owner")
+	}
+	if produced.Type != game.WorkerType {
+		t.Errorf("expected a %s to be produced, got %s", game.WorkerType, produced.Type)
+	}
+	if len(store.GetUnitById(building.Id).Queue) != 0 {
+		t.Error("production should leave the queue")
+	}
+	if dest, _ := produced.Destination(); dest != rally {
+		t.Errorf("produced unit should head to the rally point %v, got %v", rally, dest)
+	}
+}
+
+func TestGameLogic_HandleAction_BuildingDoesNotMove(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	building := createTestBuilding(player, game.NewPF(2, 2))
+	logic.HandleAction(game.SpawnUnitAction{Type: game.SpawnUnitActionType, Payload: *building}, nil)
+
+	logic.HandleAction(game.MoveStartAction{
+		Type:    game.MoveStartActionType,
+		Payload: game.MoveStartPayload{UnitId: building.Id, Point: image.Pt(9, 9)},
+	}, nil)
+
+	if store.GetUnitById(building.Id).Moving() {
+		t.Error("building should not move")
+	}
+}
+
+func createTestBuilding(player game.Player, position game.PF) *game.Unit {
+	hq, _ := game.DefaultUnitTypes().Get(game.HeadquartersType)
+	return hq.New(player.Id, player.Color, position)
+}
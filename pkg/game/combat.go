@@ -0,0 +1,103 @@
+package game
+
+import (
+	"log"
+
+	"github.com/google/uuid"
+)
+
+func (g *GameLogic) handleAttackAction(action AttackAction, dispatch DispatchFunc) {
+	unit := g.store.GetUnitById(action.Payload.UnitId)
+	if unit == nil {
+		log.Printf("attack: unknown unit %s", uuid.UUID(action.Payload.UnitId))
+		return
+	}
+	target := g.store.GetUnitById(action.Payload.TargetId)
+	if unit.Damage == 0 || target == nil || target.Owner == unit.Owner {
+		return
+	}
+	unit.Target = target.Id
+	unit.Gather = nil
+	g.clearOrders(unit, dispatch)
+}
+
+func (g *GameLogic) handleUnitDamagedAction(action UnitDamagedAction) {
+	unit := g.store.GetUnitById(action.Payload.UnitId)
+	if unit == nil {
+		return
+	}
+	unit.Health = action.Payload.Health
+}
+
+func (g *GameLogic) handleUnitDestroyedAction(action UnitDestroyedAction) {
+	id := action.Payload.UnitId
+	g.freeTiles(id)
+	g.store.RemoveUnit(id)
+	for _, u := range g.store.GetAllUnits() {
+		if u.Target == id {
+			u.Target = ZeroUnitId
+		}
+	}
+}
+
+// updateCombat - chases and attacks the unit's target
+func (g *GameLogic) updateCombat(unit *Unit, dispatch DispatchFunc) {
+	if unit.Reload > 0 {
+		unit.Reload--
+	}
+	if unit.Target == ZeroUnitId {
+		return
+	}
+	target := g.store.GetUnitById(unit.Target)
+	if target == nil {
+		unit.Target = ZeroUnitId
+		return
+	}
+
+	if unit.Position.Dist(target.Position) > unit.AttackRange {
+		g.chase(unit, target, dispatch)
+		return
+	}
+	if unit.Moving() {
+		dispatch(MoveStopAction{
+			Type:    MoveStopActionType,
+			Payload: unit.Id,
+		})
+	}
+	if unit.Reload > 0 {
+		return
+	}
+	unit.Reload = unit.Cooldown
+	g.hit(unit, target, dispatch)
+}
+
+// chase - moves towards the target once the previous path is finished
+func (g *GameLogic) chase(unit, target *Unit, dispatch DispatchFunc) {
+	if unit.Moving() {
+		return
+	}
+	g.walk(unit, target.Position.ImagePoint(), dispatch)
+}
+
+func (g *GameLogic) hit(unit, target *Unit, dispatch DispatchFunc) {
+	health := max(target.Health-unit.Damage, 0)
+	dispatch(UnitDamagedAction{
+		Type: UnitDamagedActionType,
+		Payload: UnitDamagedPayload{
+			UnitId:     target.Id,
+			AttackerId: unit.Id,
+			Damage:     unit.Damage,
+			Health:     health,
+		},
+	})
+	if health > 0 {
+		return
+	}
+	dispatch(UnitDestroyedAction{
+		Type: UnitDestroyedActionType,
+		Payload: UnitDestroyedPayload{
+			UnitId:     target.Id,
+			AttackerId: unit.Id,
+		},
+	})
+}
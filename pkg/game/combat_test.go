@@ -0,0 +1,138 @@
+package game_test
+
+import (
+	"image"
+	"testing"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+)
+
+func TestGameLogic_HandleAction_AttackAction(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+
+	player1 := createTestPlayer("player1")
+	player2 := createTestPlayer("player2")
+	attacker := game.NewUnit(player1.Id, player1.Color, game.NewPF(0, 0), 16, 16)
+	friend := game.NewUnit(player1.Id, player1.Color, game.NewPF(1, 0), 16, 16)
+	enemy := game.NewUnit(player2.Id, player2.Color, game.NewPF(2, 0), 16, 16)
+	store.StoreUnit(attacker)
+	store.StoreUnit(friend)
+	store.StoreUnit(enemy)
+
+	logic.HandleAction(newAttackAction(attacker.Id, friend.Id), nil)
+	if attacker.Target != game.ZeroUnitId {
+		t.Error("unit should not target a friendly unit")
+	}
+
+	logic.HandleAction(newAttackAction(attacker.Id, enemy.Id), nil)
+	if attacker.Target != enemy.Id {
+		t.Error("unit should target the enemy unit")
+	}
+}
+
+func TestGameLogic_Update_AttacksTargetInRange(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+
+	player1 := createTestPlayer("player1")
+	player2 := createTestPlayer("player2")
+	attacker := game.NewUnit(player1.Id, player1.Color, game.NewPF(0, 0), 16, 16)
+	enemy := game.NewUnit(player2.Id, player2.Color, game.NewPF(2, 0), 16, 16)
+	enemy.Health = attacker.Damage * 2
+	store.StoreUnit(attacker)
+	store.StoreUnit(enemy)
+	logic.HandleAction(newAttackAction(attacker.Id, enemy.Id), nil)
+
+	var dispatchedActions []game.Action
+	dispatchFunc := func(action game.Action) {
+		dispatchedActions = append(dispatchedActions, action)
+		logic.HandleAction(action, nil)
+	}
+
+	for range attacker.Cooldown + 1 {
+		logic.Update(dispatchFunc)
+	}
+
+	if len(dispatchedActions) != 3 {
+		t.Fatalf("expected 3 dispatched actions, got %d: %v", len(dispatchedActions), dispatchedActions)
+	}
+	damaged, ok := dispatchedActions[1].(game.UnitDamagedAction)
+	if !ok {
+		t.Fatalf("expected UnitDamagedAction, got %T", dispatchedActions[1])
+	}
+	if damaged.Payload.Health != 0 {
+		t.Errorf("expected health 0, got %d", damaged.Payload.Health)
+	}
+	if _, ok := dispatchedActions[2].(game.UnitDestroyedAction); !ok {
+		t.Fatalf("expected UnitDestroyedAction, got %T", dispatchedActions[2])
+	}
+
+	if store.GetUnitById(enemy.Id) != nil {
+		t.Error("destroyed unit should be removed from the store")
+	}
+	if attacker.Target != game.ZeroUnitId {
+		t.Error("attacker should drop the destroyed target")
+	}
+}
+
+func TestGameLogic_Update_ChasesTargetOutOfRange(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+
+	player1 := createTestPlayer("player1")
+	player2 := createTestPlayer("player2")
+	attacker := game.NewUnit(player1.Id, player1.Color, game.NewPF(0, 0), 16, 16)
+	enemy := game.NewUnit(player2.Id, player2.Color, game.NewPF(10, 0), 16, 16)
+	store.StoreUnit(attacker)
+	store.StoreUnit(enemy)
+	logic.HandleAction(newAttackAction(attacker.Id, enemy.Id), nil)
+
+	var dispatchedActions []game.Action
+	logic.Update(func(action game.Action) {
+		dispatchedActions = append(dispatchedActions, action)
+	})
+
+	if len(dispatchedActions) == 0 {
+		t.Fatal("expected movement towards the target")
+	}
+	step, ok := dispatchedActions[0].(game.MoveStepAction)
+	if !ok {
+		t.Fatalf("expected MoveStepAction, got %T", dispatchedActions[0])
+	}
+	last := step.Payload.Path[len(step.Payload.Path)-1]
+	if game.Dist(last, image.Pt(10, 0)) > 1 {
+		t.Errorf("expected path to lead to the target, got %v", step.Payload.Path)
+	}
+}
+
+func TestGameLogic_HandleAction_UnitDestroyedAction(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+
+	player := createTestPlayer("player1")
+	unit := createTestUnit(player.Id, image.Pt(3, 3))
+	logic.HandleAction(game.SpawnUnitAction{Type: game.SpawnUnitActionType, Payload: *unit}, nil)
+
+	logic.HandleAction(game.UnitDestroyedAction{
+		Type:    game.UnitDestroyedActionType,
+		Payload: game.UnitDestroyedPayload{UnitId: unit.Id},
+	}, nil)
+
+	if store.GetUnitById(unit.Id) != nil {
+		t.Error("unit should be removed from the store")
+	}
+	if tile, _ := store.GetTile(image.Pt(3, 3)); tile.Unit != nil {
+		t.Error("tile should be released")
+	}
+}
+
+func newAttackAction(unitId, targetId game.UnitIdType) game.AttackAction {
+	return game.AttackAction{
+		Type: game.AttackActionType,
+		Payload: game.AttackPayload{
+			UnitId:   unitId,
+			TargetId: targetId,
+		},
+	}
+}
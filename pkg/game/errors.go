@@ -0,0 +1,74 @@
+package game
+
+import "fmt"
+
+// ErrorCode - machine readable reason why an action did nothing
+type ErrorCode string
+
+// codes of actions refused before handling, sent with ActionRejectedAction
+const (
+	ErrorNotAllowed    ErrorCode = "not_allowed"    // clients cannot send the action
+	ErrorNotJoined     ErrorCode = "not_joined"     // action sent before joining the game
+	ErrorImpersonation ErrorCode = "impersonation"  // action on behalf of another player
+	ErrorBadSession    ErrorCode = "bad_session"    // rejoin with a wrong session token
+	ErrorUnknownUnit   ErrorCode = "unknown_unit"   // unit does not exist
+	ErrorNotOwner      ErrorCode = "not_owner"      // unit belongs to another player
+	ErrorOutOfBounds   ErrorCode = "out_of_bounds"  // point or rect outside of the accepted range
+	ErrorInvalid       ErrorCode = "invalid_action" // malformed payload
+)
+
+// codes of actions that failed while handled, sent with ErrorAction
+const (
+	ErrorPlacement     ErrorCode = "placement"      // tile is taken or cannot be entered
+	ErrorMapLoad       ErrorCode = "map_load"       // world provider failed, worth retrying
+	ErrorCannotAfford  ErrorCode = "cannot_afford"  // stockpile does not cover the cost
+	ErrorOutOfRange    ErrorCode = "out_of_range"   // point too far from the unit
+	ErrorCannotBuild   ErrorCode = "cannot_build"   // unit type does not construct the building
+	ErrorCannotProduce ErrorCode = "cannot_produce" // building type does not produce the unit
+	ErrorQueueFull     ErrorCode = "queue_full"     // production queue has MaxQueue units
+	ErrorNoSpawnPoint  ErrorCode = "no_spawn_point" // all starting points are taken
+)
+
+// Retryable - whether sending the same action again later may succeed
+func (c ErrorCode) Retryable() bool {
+	return c == ErrorMapLoad
+}
+
+// Error - failure of an action with its code
+type Error struct {
+	Code    ErrorCode
+	Message string
+}
+
+func NewError(code ErrorCode, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// NewErrorAction - tells the player why the action failed
+func NewErrorAction(playerId PlayerIdType, action Action, err *Error) ErrorAction {
+	return ErrorAction{
+		Type: ErrorActionType,
+		Payload: ErrorPayload{
+			PlayerId: playerId,
+			Action:   action.GetType(),
+			Code:     err.Code,
+			Message:  err.Message,
+		},
+	}
+}
+
+// NewActionRejectedAction - reply to the client whose action failed validation
+func NewActionRejectedAction(action Action, err *Error) ActionRejectedAction {
+	return ActionRejectedAction{
+		Type: ActionRejectedActionType,
+		Payload: ActionRejectedPayload{
+			Action:  action.GetType(),
+			Code:    err.Code,
+			Message: err.Message,
+		},
+	}
+}
@@ -0,0 +1,77 @@
+package game_test
+
+import (
+	"encoding/json"
+	"errors"
+	"image"
+	"testing"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+)
+
+func TestGameLogic_HandleAction_SpawnOnTakenTileDispatchesError(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player1 := createTestPlayer("player1")
+	player2 := createTestPlayer("player2")
+	first := game.NewUnit(player1.Id, player1.Color, game.NewPF(3, 3), 16, 16)
+	second := game.NewUnit(player2.Id, player2.Color, game.NewPF(3, 3), 16, 16)
+	logic.HandleAction(game.SpawnUnitAction{Type: game.SpawnUnitActionType, Payload: *first}, nil)
+
+	var dispatchedActions []game.Action
+	dispatchFunc := func(action game.Action) {
+		dispatchedActions = append(dispatchedActions, action)
+	}
+	logic.HandleAction(game.SpawnUnitAction{Type: game.SpawnUnitActionType, Payload: *second}, dispatchFunc)
+
+	if len(dispatchedActions) != 1 {
+		t.Fatalf("expected 1 dispatched action, got %d", len(dispatchedActions))
+	}
+	errorAction, ok := dispatchedActions[0].(game.ErrorAction)
+	if !ok {
+		t.Fatalf("expected ErrorAction, got %T", dispatchedActions[0])
+	}
+	if errorAction.Payload.Code != game.ErrorPlacement {
+		t.Errorf("expected %s, got %s", game.ErrorPlacement, errorAction.Payload.Code)
+	}
+	if errorAction.Payload.PlayerId != player2.Id || errorAction.Payload.Action != game.SpawnUnitActionType {
+		t.Errorf("expected the error to reference the spawn of player2, got %+v", errorAction.Payload)
+	}
+}
+
+func TestErrorAction_RoundTrip(t *testing.T) {
+	player := createTestPlayer("player1")
+	action := game.NewErrorAction(player.Id, game.NewMapLoadAction(image.Rect(0, 0, 10, 10), player.Id),
+		game.NewError(game.ErrorMapLoad, "world service down"))
+
+	bytes, err := json.Marshal(action)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := game.UnmarshalAction(bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.(game.ErrorAction) != action {
+		t.Errorf("expected %+v, got %+v", action, decoded)
+	}
+	if !action.Payload.Code.Retryable() {
+		t.Error("failed map loads should be retryable")
+	}
+}
+
+func TestError(t *testing.T) {
+	var err error = game.NewError(game.ErrorCannotAfford, "cost %d", 50)
+
+	var gameErr *game.Error
+	if !errors.As(err, &gameErr) || gameErr.Code != game.ErrorCannotAfford {
+		t.Fatalf("expected game error with code, got %v", err)
+	}
+	if err.Error() != "cannot_afford: cost 50" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+	if gameErr.Code.Retryable() {
+		t.Error("only failures outside of the player's control are retryable")
+	}
+}
@@ -0,0 +1,79 @@
+package game
+
+import (
+	"image"
+)
+
+// FogState - what the player knows about the tile
+type FogState uint8
+
+const (
+	FogUnexplored FogState = iota // never seen
+	FogExplored                   // seen before, out of sight now
+	FogVisible                    // in sight of the player's units
+)
+
+// Fog - player's memory of the world, explored tiles and ghosts of the enemy units seen last time
+type Fog struct {
+	visible  map[image.Point]bool
+	explored map[image.Point]bool
+	ghosts   map[UnitIdType]Unit
+}
+
+func NewFog() *Fog {
+	return &Fog{
+		visible:  make(map[image.Point]bool),
+		explored: make(map[image.Point]bool),
+		ghosts:   make(map[UnitIdType]Unit),
+	}
+}
+
+// Update - the tiles in sight become visible and explored,
+// ghosts in sight are dropped, the area is re-scouted and shows what is really there
+func (f *Fog) Update(sight map[image.Point]bool) {
+	f.visible = sight
+	for p := range sight {
+		f.explored[p] = true
+	}
+	for id, ghost := range f.ghosts {
+		for _, p := range ghost.Tiles() {
+			if sight[p] {
+				delete(f.ghosts, id)
+				break
+			}
+		}
+	}
+}
+
+// State - fog of the tile
+func (f *Fog) State(p image.Point) FogState {
+	switch {
+	case f.visible[p]:
+		return FogVisible
+	case f.explored[p]:
+		return FogExplored
+	default:
+		return FogUnexplored
+	}
+}
+
+// Remember - the unit left the sight, its ghost stays where it was seen last time
+func (f *Fog) Remember(unit Unit) {
+	unit.Selected = false
+	unit.Path = nil
+	f.ghosts[unit.Id] = unit
+}
+
+// Forget - the unit is seen again or known to be gone, drops its ghost
+func (f *Fog) Forget(id UnitIdType) {
+	delete(f.ghosts, id)
+}
+
+// Ghosts - last seen state of the enemy units out of sight
+func (f *Fog) Ghosts() []Unit {
+	r := make([]Unit, 0, len(f.ghosts))
+	for _, g := range f.ghosts {
+		r = append(r, g)
+	}
+	return r
+}
@@ -0,0 +1,63 @@
+package game_test
+
+import (
+	"image"
+	"testing"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+)
+
+func TestFog_State(t *testing.T) {
+	fog := game.NewFog()
+
+	fog.Update(map[image.Point]bool{image.Pt(1, 1): true})
+	fog.Update(map[image.Point]bool{image.Pt(2, 2): true})
+
+	tests := []struct {
+		point image.Point
+		state game.FogState
+	}{
+		{point: image.Pt(1, 1), state: game.FogExplored},
+		{point: image.Pt(2, 2), state: game.FogVisible},
+		{point: image.Pt(3, 3), state: game.FogUnexplored},
+	}
+	for _, tt := range tests {
+		if state := fog.State(tt.point); state != tt.state {
+			t.Errorf("expected %v to be %d, got %d", tt.point, tt.state, state)
+		}
+	}
+}
+
+func TestFog_GhostStaysUntilRescouted(t *testing.T) {
+	fog := game.NewFog()
+	enemy := createTestUnit(createTestPlayer("enemy").Id, image.Pt(5, 5))
+	enemy.Footprint = image.Pt(2, 2)
+
+	fog.Remember(*enemy)
+	enemy.Position = game.NewPF(9, 9)
+	fog.Update(map[image.Point]bool{image.Pt(1, 1): true})
+
+	ghosts := fog.Ghosts()
+	if len(ghosts) != 1 || ghosts[0].Position != game.NewPF(5, 5) {
+		t.Fatalf("expected ghost at the last seen position, got %+v", ghosts)
+	}
+
+	// any tile of the footprint in sight
+	fog.Update(map[image.Point]bool{image.Pt(6, 6): true})
+
+	if len(fog.Ghosts()) != 0 {
+		t.Error("expected the ghost to be dropped after re-scouting")
+	}
+}
+
+func TestFog_Forget(t *testing.T) {
+	fog := game.NewFog()
+	enemy := createTestUnit(createTestPlayer("enemy").Id, image.Pt(5, 5))
+	fog.Remember(*enemy)
+
+	fog.Forget(enemy.Id)
+
+	if len(fog.Ghosts()) != 0 {
+		t.Error("expected no ghosts")
+	}
+}
@@ -0,0 +1,176 @@
+package game
+
+import (
+	"cmp"
+	"image"
+	"math"
+	"slices"
+)
+
+const (
+	// MaxGroupSize - most units moved by a single group order
+	MaxGroupSize = 64
+	// maxWait - ticks a unit waits for a passing unit before going round it
+	maxWait = 30
+)
+
+// Formation - arrangement of a group around the point of its order
+type Formation string
+
+const (
+	FormationBox   Formation = "box"   // square block
+	FormationLine  Formation = "line"  // side by side, across the direction of the move
+	FormationWedge Formation = "wedge" // arrowhead led by a single unit
+)
+
+// Formations - formations in the order the players cycle through them
+var Formations = []Formation{FormationBox, FormationLine, FormationWedge}
+
+// Valid - whether the formation is known, the empty one is the default box
+func (f Formation) Valid() bool {
+	return f == "" || slices.Contains(Formations, f)
+}
+
+// FormationSlot - i-th of the n slots of the formation around the center, facing the direction
+func FormationSlot(f Formation, center, facing image.Point, i, n int) image.Point {
+	var across, behind int
+	switch f {
+	case FormationLine:
+		across = alternate(i)
+	case FormationWedge:
+		// row r holds r+1 units, the single one leads
+		row := 0
+		for i > row {
+			i -= row + 1
+			row++
+		}
+		across, behind = 2*i-row, row
+	default:
+		cols := int(math.Ceil(math.Sqrt(float64(n))))
+		rows := (n + cols - 1) / cols
+		across, behind = alternate(i%cols), i/cols-(rows-1)/2
+	}
+	forward := image.Pt(sign(facing.X), sign(facing.Y))
+	if forward == ZeroPoint {
+		forward = image.Pt(0, -1)
+	}
+	right := image.Pt(-forward.Y, forward.X)
+	return center.Add(right.Mul(across)).Sub(forward.Mul(behind))
+}
+
+// alternate - 0, 1, -1, 2, -2... spreads the slots to both sides of the center
+func alternate(i int) int {
+	if i%2 == 1 {
+		return (i + 1) / 2
+	}
+	return -i / 2
+}
+
+func (g *GameLogic) handleGroupMoveAction(action GroupMoveAction, dispatch DispatchFunc) {
+	units := g.groupUnits(action.Payload.UnitIds)
+	if len(units) == 0 {
+		return
+	}
+	order := action.Payload.Order
+	slots := make([]image.Point, len(units))
+	if order.Type != OrderHold {
+		slots = assignSlots(units, g.formationSlots(units, action.Payload.Formation, order.Point))
+	}
+	pace := groupPace(units)
+	for i, unit := range units {
+		g.handleOrderAction(OrderAction{
+			Type: OrderActionType,
+			Payload: OrderPayload{
+				UnitId: unit.Id,
+				Order:  Order{Type: order.Type, Point: slots[i], Pace: pace},
+				Queue:  action.Payload.Queue,
+			},
+		}, dispatch)
+	}
+}
+
+// groupUnits - units of the group which can move, each one once
+func (g *GameLogic) groupUnits(ids []UnitIdType) []*Unit {
+	units := make([]*Unit, 0, len(ids))
+	for _, id := range ids {
+		unit := g.store.GetUnitById(id)
+		if unit == nil || unit.Building || slices.Contains(units, unit) {
+			continue
+		}
+		units = append(units, unit)
+	}
+	return units
+}
+
+// formationSlots - slots for the units, skipping the tiles they cannot stand on
+func (g *GameLogic) formationSlots(units []*Unit, f Formation, center image.Point) []image.Point {
+	var centroid PF
+	for _, u := range units {
+		centroid = centroid.Add(u.Position)
+	}
+	centroid = centroid.Mul(1 / float64(len(units)))
+	facing := center.Sub(centroid.Round().ImagePoint())
+
+	slots := make([]image.Point, 0, len(units))
+	for i := 0; len(slots) < len(units) && i < 4*len(units); i++ {
+		p := FormationSlot(f, center, facing, i, len(units))
+		if g.standable(units, p) {
+			slots = append(slots, p)
+		}
+	}
+	for len(slots) < len(units) {
+		// crowded, the rest come as close as they can
+		slots = append(slots, center)
+	}
+	return slots
+}
+
+// standable - the tile is passable and not taken by a unit standing outside of the group
+func (g *GameLogic) standable(units []*Unit, p image.Point) bool {
+	t, ok := g.store.GetTile(p)
+	if !ok {
+		return true
+	}
+	if !t.Passable() {
+		return false
+	}
+	return t.Unit == nil || t.Unit.Moving() || slices.Contains(units, t.Unit)
+}
+
+// assignSlots - slot of each unit, closest pairs first so the paths do not cross much
+func assignSlots(units []*Unit, slots []image.Point) []image.Point {
+	type pair struct {
+		unit, slot int
+		dist       float64
+	}
+	pairs := make([]pair, 0, len(units)*len(slots))
+	for i, u := range units {
+		for j, s := range slots {
+			pairs = append(pairs, pair{unit: i, slot: j, dist: u.Position.Dist(ToPF(s))})
+		}
+	}
+	slices.SortStableFunc(pairs, func(a, b pair) int {
+		return cmp.Compare(a.dist, b.dist)
+	})
+
+	assigned := make([]image.Point, len(units))
+	unitDone := make([]bool, len(units))
+	slotDone := make([]bool, len(slots))
+	for _, p := range pairs {
+		if unitDone[p.unit] || slotDone[p.slot] {
+			continue
+		}
+		assigned[p.unit] = slots[p.slot]
+		unitDone[p.unit], slotDone[p.slot] = true, true
+	}
+	return assigned
+}
+
+// groupPace - speed of the slowest unit, the group keeps together
+func groupPace(units []*Unit) float64 {
+	pace := units[0].Speed
+	for _, u := range units[1:] {
+		pace = math.Min(pace, u.Speed)
+	}
+	return pace
+}
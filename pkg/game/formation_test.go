@@ -0,0 +1,263 @@
+package game_test
+
+import (
+	"image"
+	"testing"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/world"
+)
+
+func TestFormationSlot(t *testing.T) {
+	tests := []struct {
+		name      string
+		formation game.Formation
+		facing    image.Point
+		n         int
+		expected  []image.Point
+	}{
+		{
+			name:      "line across the move east",
+			formation: game.FormationLine,
+			facing:    image.Pt(5, 0),
+			n:         3,
+			expected:  []image.Point{{0, 0}, {0, 1}, {0, -1}},
+		},
+		{
+			name:      "wedge led by one unit north",
+			formation: game.FormationWedge,
+			facing:    image.Pt(0, -5),
+			n:         3,
+			expected:  []image.Point{{0, 0}, {-1, 1}, {1, 1}},
+		},
+		{
+			name:      "box of two rows",
+			formation: game.FormationBox,
+			facing:    image.Pt(0, -5),
+			n:         4,
+			expected:  []image.Point{{0, 0}, {1, 0}, {0, 1}, {1, 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, expected := range tt.expected {
+				if slot := game.FormationSlot(tt.formation, image.Pt(0, 0), tt.facing, i, tt.n); slot != expected {
+					t.Errorf("slot %d: expected %v, got %v", i, expected, slot)
+				}
+			}
+		})
+	}
+}
+
+func TestGameLogic_HandleAction_GroupMoveGivesEachUnitASlot(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	units := createTestGroup(store, player, image.Pt(0, 0), image.Pt(0, 1), image.Pt(0, 2))
+	units[2].Speed = game.UnitSpeed / 2
+	store.StoreTile(world.Tile{Point: image.Pt(10, 0), LandType: world.LandLake})
+
+	logic.HandleAction(newGroupMoveAction(units, game.FormationLine, image.Pt(10, 1)), ignore)
+
+	expected := []image.Point{{10, 3}, {10, 1}, {10, 2}}
+	for i, u := range units {
+		if len(u.Orders) != 1 || u.Orders[0].Point != expected[i] {
+			t.Errorf("unit %d: expected order to %v, got %v", i, expected[i], u.Orders)
+		}
+		if u.Pace != game.UnitSpeed/2 {
+			t.Errorf("unit %d: expected the pace of the slowest unit, got %v", i, u.Pace)
+		}
+	}
+}
+
+func TestGameLogic_Update_GroupArrivesInFormation(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	units := createTestGroup(store, player, image.Pt(0, 0), image.Pt(1, 0), image.Pt(0, 1), image.Pt(1, 1))
+
+	logic.HandleAction(newGroupMoveAction(units, game.FormationBox, image.Pt(0, 8)), ignore)
+	runUpdates(logic, 1000, func() bool {
+		for _, u := range units {
+			if len(u.Orders) > 0 || u.Moving() {
+				return false
+			}
+		}
+		return true
+	})
+
+	taken := map[image.Point]bool{}
+	for i, u := range units {
+		p := u.Position.ImagePoint()
+		if game.Dist(p, image.Pt(0, 8)) > 2 {
+			t.Errorf("unit %d: expected to arrive near (0,8), got %v", i, u.Position)
+		}
+		if taken[p] {
+			t.Errorf("unit %d: expected a tile of its own, got %v", i, p)
+		}
+		taken[p] = true
+		if len(u.Orders) > 0 {
+			t.Errorf("unit %d: expected the orders done, got %v", i, u.Orders)
+		}
+	}
+}
+
+func TestGameLogic_Update_GroupPaceEndsWithTheOrder(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	units := createTestGroup(store, player, image.Pt(0, 0), image.Pt(0, 1))
+	units[1].Speed = game.UnitSpeed / 2
+
+	logic.HandleAction(newGroupMoveAction(units, game.FormationLine, image.Pt(4, 0)), ignore)
+	runUpdates(logic, 1000, func() bool {
+		return len(units[0].Orders) == 0 && !units[0].Moving()
+	})
+
+	if units[0].Pace != 0 {
+		t.Errorf("expected the unit to leave the group pace with its order done, got %v", units[0].Pace)
+	}
+}
+
+func TestGameLogic_HandleAction_QueuedGroupOrderKeepsPaceForLater(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	units := createTestGroup(store, player, image.Pt(0, 0), image.Pt(0, 1))
+	units[1].Speed = game.UnitSpeed / 2
+	logic.HandleAction(newOrderAction(units[0].Id, game.OrderMove, image.Pt(3, 0), false), ignore)
+
+	group := newGroupMoveAction(units, game.FormationLine, image.Pt(6, 0))
+	group.Payload.Queue = true
+	logic.HandleAction(group, ignore)
+
+	if units[0].Pace != 0 {
+		t.Errorf("expected the unit at its own speed until it gets to the group order, got %v", units[0].Pace)
+	}
+	runUpdates(logic, 1000, func() bool { return len(units[0].Orders) == 1 })
+	if units[0].Pace != game.UnitSpeed/2 {
+		t.Errorf("expected the pace of the group with its order, got %v", units[0].Pace)
+	}
+}
+
+func TestGameLogic_HandleAction_AttackAndGatherLeaveTheGroupPace(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	units := createTestGroup(store, player, image.Pt(0, 0), image.Pt(0, 1), image.Pt(0, 2))
+	units[2].Speed = game.UnitSpeed / 2
+	units[0].Damage = 1
+	enemy := createTestGroup(store, createTestPlayer("player2"), image.Pt(5, 5))[0]
+	logic.HandleAction(newGroupMoveAction(units, game.FormationLine, image.Pt(0, 8)), ignore)
+
+	logic.HandleAction(game.AttackAction{
+		Type:    game.AttackActionType,
+		Payload: game.AttackPayload{UnitId: units[0].Id, TargetId: enemy.Id},
+	}, ignore)
+	logic.HandleAction(game.GatherAction{
+		Type:    game.GatherActionType,
+		Payload: game.GatherPayload{UnitId: units[1].Id, Point: image.Pt(3, 3)},
+	}, ignore)
+
+	for i, u := range units[:2] {
+		if u.Pace != 0 {
+			t.Errorf("unit %d: expected its own speed, got the pace %v", i, u.Pace)
+		}
+	}
+}
+
+func TestGameLogic_MoveStepAction_WaitsForPassingUnit(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	unit := createTestUnit(player.Id, image.Pt(0, 0))
+	passing := createTestUnit(player.Id, image.Pt(1, 0))
+	passing.Path = []image.Point{image.Pt(1, 0), image.Pt(1, 1)}
+	passing.Step = 1
+	store.StoreUnit(unit)
+	store.StoreUnit(passing)
+	store.SetTileUnit(store.CreateTile(image.Pt(1, 0)), passing)
+
+	var dispatchedActions []game.Action
+	logic.HandleAction(newMoveStepAction(unit, image.Pt(0, 0), image.Pt(1, 0), image.Pt(2, 0)), func(action game.Action) {
+		dispatchedActions = append(dispatchedActions, action)
+	})
+
+	if len(dispatchedActions) != 1 {
+		t.Fatalf("expected 1 dispatched action, got %d: %v", len(dispatchedActions), dispatchedActions)
+	}
+	step, ok := dispatchedActions[0].(game.MoveStepAction)
+	if !ok {
+		t.Fatalf("expected MoveStepAction, got %T", dispatchedActions[0])
+	}
+	if step.Payload.Path[step.Payload.Step] != image.Pt(0, 0) || len(step.Payload.Path) != 4 {
+		t.Errorf("expected the unit to wait on its tile, got %v at step %d", step.Payload.Path, step.Payload.Step)
+	}
+}
+
+func TestGameLogic_MoveStepAction_SidestepsHeadOn(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	unit := createTestUnit(player.Id, image.Pt(0, 0))
+	oncoming := createTestUnit(player.Id, image.Pt(1, 0))
+	oncoming.Path = []image.Point{image.Pt(1, 0), image.Pt(0, 0)}
+	oncoming.Step = 1
+	store.StoreUnit(unit)
+	store.StoreUnit(oncoming)
+	store.SetTileUnit(store.CreateTile(image.Pt(1, 0)), oncoming)
+
+	var dispatchedActions []game.Action
+	logic.HandleAction(newMoveStepAction(unit, image.Pt(0, 0), image.Pt(1, 0), image.Pt(2, 0)), func(action game.Action) {
+		dispatchedActions = append(dispatchedActions, action)
+	})
+
+	if len(dispatchedActions) != 1 {
+		t.Fatalf("expected 1 dispatched action, got %d: %v", len(dispatchedActions), dispatchedActions)
+	}
+	step, ok := dispatchedActions[0].(game.MoveStepAction)
+	if !ok {
+		t.Fatalf("expected MoveStepAction, got %T", dispatchedActions[0])
+	}
+	if next := step.Payload.Path[step.Payload.Step]; next == image.Pt(1, 0) || next == image.Pt(0, 0) {
+		t.Errorf("expected the unit to step aside, got %v", step.Payload.Path)
+	}
+}
+
+func createTestGroup(store game.Store, player game.Player, positions ...image.Point) []*game.Unit {
+	units := make([]*game.Unit, 0, len(positions))
+	for _, p := range positions {
+		u := game.NewUnit(player.Id, player.Color, game.ToPF(p), 16, 16)
+		store.StoreUnit(u)
+		store.SetTileUnit(store.CreateTile(p), u)
+		units = append(units, u)
+	}
+	return units
+}
+
+func newGroupMoveAction(units []*game.Unit, formation game.Formation, p image.Point) game.GroupMoveAction {
+	ids := make([]game.UnitIdType, 0, len(units))
+	for _, u := range units {
+		ids = append(ids, u.Id)
+	}
+	return game.GroupMoveAction{
+		Type: game.GroupMoveActionType,
+		Payload: game.GroupMovePayload{
+			UnitIds:   ids,
+			Order:     game.Order{Type: game.OrderMove, Point: p},
+			Formation: formation,
+		},
+	}
+}
+
+func newMoveStepAction(unit *game.Unit, path ...image.Point) game.MoveStepAction {
+	return game.MoveStepAction{
+		Type: game.MoveStepActionType,
+		Payload: game.MoveStepPayload{
+			UnitId:   unit.Id,
+			Position: unit.Position,
+			Path:     path,
+			Step:     1,
+		},
+	}
+}
@@ -1,9 +1,11 @@
 package game
 
 import (
-	"errors"
 	"image"
 	"log"
+	"slices"
+
+	"github.com/google/uuid"
 )
 
 type DispatchFunc func(Action)
@@ -13,15 +15,29 @@ type ActionsHandler interface {
 }
 
 type GameLogic struct {
-	store Store
+	store      Store
+	pathfinder *Pathfinder
+	types      *UnitTypes
 }
 
 func NewGameLogic(store Store) *GameLogic {
 	return &GameLogic{
-		store: store,
+		store:      store,
+		pathfinder: NewPathfinder(store),
+		types:      DefaultUnitTypes(),
 	}
 }
 
+// UnitTypes - definitions of the units in the game
+func (g *GameLogic) UnitTypes() *UnitTypes {
+	return g.types
+}
+
+// SetUnitTypes - replaces the definitions of the units, used when the server loads its own
+func (g *GameLogic) SetUnitTypes(types *UnitTypes) {
+	g.types = types
+}
+
 func (g *GameLogic) HandleAction(action Action, dispatch DispatchFunc) {
 	switch a := action.(type) {
 	case PlayerJoinSuccessAction:
@@ -29,17 +45,74 @@ func (g *GameLogic) HandleAction(action Action, dispatch DispatchFunc) {
 	case SpawnUnitAction:
 		g.handleSpawnUnitAction(a, dispatch)
 	case MoveStartAction:
-		g.handleMoveStartAction(a)
+		g.handleMoveStartAction(a, dispatch)
 	case MoveStepAction:
 		g.handleMoveStepAction(a, dispatch)
 	case MoveStopAction:
 		g.handleMoveStopAction(a)
 	case MapLoadSuccessAction:
 		g.handleMapLoadSuccessAction(a)
+	case UnitEnteredVisionAction:
+		g.handleUnitEnteredVisionAction(a)
+	case UnitLeftVisionAction:
+		g.handleUnitLeftVisionAction(a)
+	case AttackAction:
+		g.handleAttackAction(a, dispatch)
+	case UnitDamagedAction:
+		g.handleUnitDamagedAction(a)
+	case UnitDestroyedAction:
+		g.handleUnitDestroyedAction(a)
+	case GatherAction:
+		g.handleGatherAction(a, dispatch)
+	case ResourceGatheredAction:
+		g.handleResourceGatheredAction(a)
+	case ResourcesSpentAction:
+		g.handleResourcesSpentAction(a)
+	case ProductionQueuedAction:
+		g.handleProductionQueuedAction(a)
+	case ProductionCompleteAction:
+		g.handleProductionCompleteAction(a)
+	case SetRallyPointAction:
+		g.handleSetRallyPointAction(a)
+	case OrderAction:
+		g.handleOrderAction(a, dispatch)
+	case StopAction:
+		g.handleStopAction(a, dispatch)
+	case UnitOrdersAction:
+		g.handleUnitOrdersAction(a)
+	case GroupMoveAction:
+		g.handleGroupMoveAction(a, dispatch)
+	}
+}
+
+// Update - advances every unit in the store by one simulation tick
+func (g *GameLogic) Update(dispatch DispatchFunc) {
+	for _, u := range g.store.GetAllUnits() {
+		if g.store.GetUnitById(u.Id) == nil {
+			// destroyed earlier in this tick
+			continue
+		}
+		if u.Building {
+			g.updateProduction(u, dispatch)
+			continue
+		}
+		g.updateOrders(u, dispatch)
+		g.updateCombat(u, dispatch)
+		g.updateGathering(u, dispatch)
+		u.Update(dispatch)
 	}
 }
 
 func (g *GameLogic) handlePlayerJoinSuccessAction(action PlayerJoinSuccessAction, _ DispatchFunc) {
+	if len(action.Payload.UnitTypes) > 0 {
+		// the server's definitions win over the bundled ones
+		types, err := NewUnitTypes(action.Payload.UnitTypes)
+		if err != nil {
+			log.Println(err)
+		} else {
+			g.types = types
+		}
+	}
 	for _, u := range action.Payload.Units {
 		unit := &u
 		g.store.StoreUnit(unit)
@@ -53,59 +126,139 @@ func (g *GameLogic) handlePlayerJoinSuccessAction(action PlayerJoinSuccessAction
 	}
 }
 
-func (g *GameLogic) handleSpawnUnitAction(action SpawnUnitAction, _ DispatchFunc) {
+func (g *GameLogic) handleSpawnUnitAction(action SpawnUnitAction, dispatch DispatchFunc) {
 	unit := &action.Payload
 	g.store.StoreUnit(unit)
 	if err := g.placeUnit(unit); err != nil {
 		log.Println(err)
-		// dispatch error action
+		dispatch(NewErrorAction(unit.Owner, action, err))
 	}
 }
 
-func (g *GameLogic) handleMoveStartAction(action MoveStartAction) {
+func (g *GameLogic) handleMoveStartAction(action MoveStartAction, dispatch DispatchFunc) {
 	unit := g.store.GetUnitById(action.Payload.UnitId)
+	if unit == nil {
+		log.Printf("move start: unknown unit %s", uuid.UUID(action.Payload.UnitId))
+		return
+	}
+	if unit.Building {
+		return
+	}
 
-	unit.MoveTo(action.Payload.Point)
+	unit.Target = ZeroUnitId
+	unit.Gather = nil
+	g.clearOrders(unit, dispatch)
+	target := action.Payload.Point
+	if dest, ok := unit.Destination(); ok && dest == target {
+		return
+	}
+	path, _ := g.pathfinder.FindPath(unit.Id, unit.Position.ImagePoint(), target)
+	unit.FollowPath(path)
 }
 
 func (g *GameLogic) handleMoveStepAction(action MoveStepAction, dispatch DispatchFunc) {
 	// clean position
-	for _, tile := range g.store.GetTilesByUnitId(action.Payload.UnitId) {
-		tile.Unit = nil
-	}
+	g.freeTiles(action.Payload.UnitId)
 	unit := g.store.GetUnitById(action.Payload.UnitId)
+	if unit == nil {
+		return
+	}
 
 	unit.Position = action.Payload.Position
 	unit.Path = action.Payload.Path
 	unit.Step = action.Payload.Step
+	unit.Pace = action.Payload.Pace
 
 	if err := g.placeUnit(unit); err != nil {
 		log.Println(err)
-		// dispatch error action
+		dispatch(NewErrorAction(unit.Owner, action, err))
 	}
 	// reserve next step
 	if len(action.Payload.Path) > action.Payload.Step {
 		nextStep := action.Payload.Path[action.Payload.Step]
 		if err := g.placeUnit(unit, nextStep); err != nil {
-			dispatch(MoveStopAction{
-				Type:    MoveStopActionType,
-				Payload: unit.Id,
-			})
-			/*
-				Retry moving to target in 1s
-				go func(a MoveUnitAction) {
-					time.Sleep(1 * time.Second)
-					a.Step -= 1
-					if err := g.handleAction(a); err != nil {
-						log.Println(err)
-					}
-				}(action) */
+			dispatch(g.giveWay(unit, nextStep))
+		} else if nextStep != unit.Position.ImagePoint() {
+			unit.Waiting = 0
+		}
+	}
+}
+
+// giveWay - resolves a blocked step, waits for a unit passing by and goes round a standing one
+func (g *GameLogic) giveWay(unit *Unit, next image.Point) Action {
+	t, _ := g.store.GetTile(next)
+	blocker := t.Unit
+	headOn := blocker.Moving() && blocker.Path[blocker.Step] == unit.Position.ImagePoint()
+	if blocker.Moving() && !headOn && unit.Waiting < maxWait {
+		unit.Waiting++
+		return g.wait(unit)
+	}
+	unit.Waiting = 0
+	return g.detour(unit)
+}
+
+// wait - stays on the tile for a step, the blocked one is tried again then
+func (g *GameLogic) wait(unit *Unit) Action {
+	return MoveStepAction{
+		Type: MoveStepActionType,
+		Payload: MoveStepPayload{
+			UnitId:   unit.Id,
+			Position: unit.Position,
+			Path:     slices.Insert(slices.Clone(unit.Path), unit.Step, unit.Position.ImagePoint()),
+			Step:     unit.Step,
+			Pace:     unit.Pace,
+		},
+	}
+}
+
+// detour - plans around a blocked step, when the destination is taken the unit gets as close as it can,
+// it stops next to the destination or when there is no way at all
+func (g *GameLogic) detour(unit *Unit) Action {
+	dest, _ := unit.Destination()
+	start := unit.Position.ImagePoint()
+	path, ok := g.pathfinder.FindPath(unit.Id, start, dest)
+	if len(path) < 2 || !ok && Dist(start, dest) < 2 {
+		return MoveStopAction{
+			Type:    MoveStopActionType,
+			Payload: unit.Id,
 		}
 	}
+	return MoveStepAction{
+		Type: MoveStepActionType,
+		Payload: MoveStepPayload{
+			UnitId:   unit.Id,
+			Position: unit.Position,
+			Path:     path,
+			Step:     1,
+			Pace:     unit.Pace,
+		},
+	}
+}
+
+// walk - starts walking towards the goal, returns false when the unit cannot get any closer
+func (g *GameLogic) walk(unit *Unit, goal image.Point, dispatch DispatchFunc) bool {
+	path, _ := g.pathfinder.FindPath(unit.Id, unit.Position.ImagePoint(), goal)
+	if len(path) < 2 {
+		return false
+	}
+	dispatch(MoveStepAction{
+		Type: MoveStepActionType,
+		Payload: MoveStepPayload{
+			UnitId:   unit.Id,
+			Position: unit.Position,
+			Path:     path,
+			Step:     0,
+			Pace:     unit.Pace,
+		},
+	})
+	return true
 }
 
 func (g *GameLogic) handleMoveStopAction(action MoveStopAction) {
 	unit := g.store.GetUnitById(action.Payload)
+	if unit == nil {
+		return
+	}
 
 	unit.Path = []image.Point{}
 	unit.Step = 0
@@ -117,9 +270,30 @@ func (g *GameLogic) handleMapLoadSuccessAction(action MapLoadSuccessAction) {
 	}
 }
 
-func (g *GameLogic) placeUnit(unit *Unit, positions ...image.Point) error {
+func (g *GameLogic) handleUnitEnteredVisionAction(action UnitEnteredVisionAction) {
+	unit := &action.Payload.Unit
+	g.freeTiles(unit.Id)
+	g.store.StoreUnit(unit)
+	if err := g.placeUnit(unit); err != nil {
+		log.Println(err)
+	}
+}
+
+func (g *GameLogic) handleUnitLeftVisionAction(action UnitLeftVisionAction) {
+	g.freeTiles(action.Payload.UnitId)
+	g.store.RemoveUnit(action.Payload.UnitId)
+}
+
+// freeTiles - releases all tiles taken or reserved by the unit
+func (g *GameLogic) freeTiles(id UnitIdType) {
+	for _, tile := range g.store.GetTilesByUnitId(id) {
+		g.store.SetTileUnit(tile, nil)
+	}
+}
+
+func (g *GameLogic) placeUnit(unit *Unit, positions ...image.Point) *Error {
 	if len(positions) == 0 {
-		positions = []image.Point{unit.Position.ImagePoint()}
+		positions = unit.Tiles()
 	}
 	for _, p := range positions {
 		t, ok := g.store.GetTile(p)
@@ -129,9 +303,9 @@ func (g *GameLogic) placeUnit(unit *Unit, positions ...image.Point) error {
 
 		// set position
 		if t.Unit != nil && t.Unit.Id != unit.Id {
-			return errors.New("position")
+			return NewError(ErrorPlacement, "tile %v is taken by unit %s", p, uuid.UUID(t.Unit.Id))
 		}
-		t.Unit = unit
+		g.store.SetTileUnit(t, unit)
 	}
 
 	return nil
@@ -155,7 +155,7 @@ func TestGameLogic_HandleAction_MoveStepAction(t *testing.T) {
 
 	// Place unit on initial tile
 	tile := store.CreateTile(image.Pt(0, 0))
-	tile.Unit = unit
+	store.SetTileUnit(tile, unit)
 	store.StoreUnit(unit)
 
 	newPosition := game.NewPF(1.5, 1.5)
@@ -224,7 +224,7 @@ func TestGameLogic_HandleAction_MoveStepAction_WithCollision(t *testing.T) {
 
 	// Place unit2 on the next step tile
 	nextStepTile := store.CreateTile(image.Pt(1, 1))
-	nextStepTile.Unit = unit2
+	store.SetTileUnit(nextStepTile, unit2)
 
 	newPosition := game.NewPF(0.5, 0.5)
 	path := []image.Point{image.Pt(1, 1)} // This will collide with unit2
@@ -471,12 +471,12 @@ func TestGameLogic_MoveStepAction_CollisionHandling(t *testing.T) {
 	// Place unit1 initially
 	store.StoreUnit(unit1)
 	tile1 := store.CreateTile(image.Pt(0, 0))
-	tile1.Unit = unit1
+	store.SetTileUnit(tile1, unit1)
 
 	// Place unit2 on the target position
 	store.StoreUnit(unit2)
 	targetTile := store.CreateTile(image.Pt(1, 1))
-	targetTile.Unit = unit2
+	store.SetTileUnit(targetTile, unit2)
 
 	// Try to move unit1 to where unit2 is (should cause collision)
 	action := game.MoveStepAction{
@@ -516,6 +516,50 @@ func TestGameLogic_MoveStepAction_CollisionHandling(t *testing.T) {
 	}
 }
 
+func TestGameLogic_MoveStepAction_DetoursAroundBlockedStep(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+
+	player := createTestPlayer("testplayer")
+	unit1 := createTestUnit(player.Id, image.Pt(0, 0))
+	unit2 := createTestUnit(player.Id, image.Pt(1, 0))
+	store.StoreUnit(unit1)
+	store.StoreUnit(unit2)
+	store.SetTileUnit(store.CreateTile(image.Pt(1, 0)), unit2)
+
+	action := game.MoveStepAction{
+		Type: game.MoveStepActionType,
+		Payload: game.MoveStepPayload{
+			UnitId:   unit1.Id,
+			Position: game.NewPF(0, 0),
+			Path:     []image.Point{image.Pt(0, 0), image.Pt(1, 0), image.Pt(2, 0)},
+			Step:     1,
+		},
+	}
+
+	var dispatchedActions []game.Action
+	dispatchFunc := func(action game.Action) {
+		dispatchedActions = append(dispatchedActions, action)
+	}
+
+	logic.HandleAction(action, dispatchFunc)
+
+	if len(dispatchedActions) != 1 {
+		t.Fatalf("expected 1 dispatched action, got %d", len(dispatchedActions))
+	}
+	stepAction, ok := dispatchedActions[0].(game.MoveStepAction)
+	if !ok {
+		t.Fatalf("expected MoveStepAction, got %T", dispatchedActions[0])
+	}
+	path := stepAction.Payload.Path
+	if path[len(path)-1] != image.Pt(2, 0) {
+		t.Errorf("expected detour to end at (2,0), got %v", path)
+	}
+	if path[stepAction.Payload.Step] == image.Pt(1, 0) {
+		t.Errorf("expected detour to avoid (1,0), got %v", path)
+	}
+}
+
 func TestGameLogic_MoveStepAction_SameUnitCanMoveToSamePosition(t *testing.T) {
 	t.Helper()
 	store := game.NewStoreImpl()
@@ -527,7 +571,7 @@ func TestGameLogic_MoveStepAction_SameUnitCanMoveToSamePosition(t *testing.T) {
 	// Place unit initially
 	store.StoreUnit(unit)
 	tile := store.CreateTile(image.Pt(0, 0))
-	tile.Unit = unit
+	store.SetTileUnit(tile, unit)
 
 	// Move unit to the same position (should not cause collision)
 	action := game.MoveStepAction{
@@ -560,6 +604,41 @@ func TestGameLogic_MoveStepAction_SameUnitCanMoveToSamePosition(t *testing.T) {
 	}
 }
 
+func TestGameLogic_Update_AdvancesUnits(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+
+	player := createTestPlayer("testplayer")
+	unit := createTestUnit(player.Id, image.Pt(0, 0))
+	unit.Path = []image.Point{image.Pt(0, 0), image.Pt(1, 0)}
+	store.StoreUnit(unit)
+
+	var dispatchedActions []game.Action
+	dispatchFunc := func(action game.Action) {
+		dispatchedActions = append(dispatchedActions, action)
+	}
+
+	for range 20 {
+		logic.Update(dispatchFunc)
+	}
+
+	if unit.Position != game.NewPF(1, 0) {
+		t.Errorf("expected unit at (1,0), got %v", unit.Position)
+	}
+
+	if len(dispatchedActions) != 2 {
+		t.Fatalf("expected 2 dispatched actions, got %d", len(dispatchedActions))
+	}
+
+	stepAction, ok := dispatchedActions[1].(game.MoveStepAction)
+	if !ok {
+		t.Fatalf("expected MoveStepAction, got %T", dispatchedActions[1])
+	}
+	if stepAction.Payload.Step != 2 {
+		t.Errorf("expected step 2, got %d", stepAction.Payload.Step)
+	}
+}
+
 // Helper functions
 func createTestPlayer(name string) game.Player {
 	return game.Player{
@@ -577,6 +656,7 @@ func createTestUnit(owner game.PlayerIdType, position image.Point) *game.Unit {
 		Color:    color.RGBA{0, 255, 0, 255},
 		Position: game.NewPF(float64(position.X), float64(position.Y)),
 		Size:     image.Pt(16, 16),
+		Speed:    game.UnitSpeed,
 		Selected: false,
 		Path:     []image.Point{},
 		Step:     0,
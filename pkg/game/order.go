@@ -0,0 +1,206 @@
+package game
+
+import (
+	"image"
+	"log"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxOrders - longest queue of orders of a unit
+	MaxOrders = 16
+	// acquireRange - distance at which units on attack-move or patrol engage enemies
+	acquireRange = float64(defaultSight)
+)
+
+// OrderType - kind of the unit's order
+type OrderType string
+
+const (
+	OrderMove       OrderType = "move"        // walk to the point
+	OrderAttackMove OrderType = "attack_move" // walk to the point, attacking enemies met on the way
+	OrderPatrol     OrderType = "patrol"      // attack-move between the points of the queue, over and over
+	OrderHold       OrderType = "hold"        // stay, attacking enemies in range only
+)
+
+// Order - command waiting in the unit's queue, the first one is being carried out
+type Order struct {
+	Type  OrderType
+	Point image.Point `json:",omitempty"`
+	Pace  float64     `json:",omitempty"` // speed of the group given the order, zero when given alone
+}
+
+func (g *GameLogic) handleOrderAction(action OrderAction, dispatch DispatchFunc) {
+	unit := g.store.GetUnitById(action.Payload.UnitId)
+	if unit == nil {
+		log.Printf("order: unknown unit %s", uuid.UUID(action.Payload.UnitId))
+		return
+	}
+	if unit.Building {
+		return
+	}
+	order := action.Payload.Order
+	if !action.Payload.Queue {
+		unit.Target = ZeroUnitId
+		unit.Gather = nil
+		unit.Orders = nil
+		if order.Type == OrderPatrol {
+			// back and forth between the starting point and the patrolled one
+			unit.Orders = append(unit.Orders, order,
+				Order{Type: OrderPatrol, Point: unit.Position.ImagePoint(), Pace: order.Pace})
+			g.setOrders(unit, unit.Orders, dispatch)
+			return
+		}
+	} else if len(unit.Orders) == 0 && unit.Target == ZeroUnitId && unit.Gather == nil {
+		// queued after a plain move, the move comes first
+		if dest, ok := unit.Destination(); ok && unit.Moving() {
+			unit.Orders = append(unit.Orders, Order{Type: OrderMove, Point: dest, Pace: unit.Pace})
+		}
+	}
+	if len(unit.Orders) >= MaxOrders {
+		return
+	}
+	g.setOrders(unit, append(unit.Orders, order), dispatch)
+}
+
+func (g *GameLogic) handleStopAction(action StopAction, dispatch DispatchFunc) {
+	unit := g.store.GetUnitById(action.Payload.UnitId)
+	if unit == nil {
+		return
+	}
+	unit.Target = ZeroUnitId
+	unit.Gather = nil
+	g.clearOrders(unit, dispatch)
+	if unit.Moving() {
+		dispatch(MoveStopAction{
+			Type:    MoveStopActionType,
+			Payload: unit.Id,
+		})
+	}
+}
+
+func (g *GameLogic) handleUnitOrdersAction(action UnitOrdersAction) {
+	unit := g.store.GetUnitById(action.Payload.UnitId)
+	if unit == nil {
+		return
+	}
+	unit.Orders = action.Payload.Orders
+}
+
+// setOrders - replaces the unit's queue and tells its owner, the unit keeps the pace of its current order
+func (g *GameLogic) setOrders(unit *Unit, orders []Order, dispatch DispatchFunc) {
+	unit.Orders = orders
+	unit.Pace = 0
+	if len(orders) > 0 {
+		unit.Pace = orders[0].Pace
+	}
+	dispatch(UnitOrdersAction{
+		Type: UnitOrdersActionType,
+		Payload: UnitOrdersPayload{
+			UnitId: unit.Id,
+			Orders: slices.Clone(orders),
+		},
+	})
+}
+
+// clearOrders - drops the queue and leaves the group when another command replaces them
+func (g *GameLogic) clearOrders(unit *Unit, dispatch DispatchFunc) {
+	unit.Pace = 0
+	if len(unit.Orders) == 0 {
+		return
+	}
+	g.setOrders(unit, nil, dispatch)
+}
+
+// nextOrder - the current order is done
+func (g *GameLogic) nextOrder(unit *Unit, dispatch DispatchFunc) {
+	done := unit.Orders[0]
+	orders := unit.Orders[1:]
+	if done.Type == OrderPatrol {
+		// patrol points go round
+		orders = append(slices.Clone(orders), done)
+	}
+	g.setOrders(unit, orders, dispatch)
+}
+
+// updateOrders - carries out the first order of the unit's queue
+func (g *GameLogic) updateOrders(unit *Unit, dispatch DispatchFunc) {
+	if len(unit.Orders) == 0 {
+		return
+	}
+	order := unit.Orders[0]
+	switch order.Type {
+	case OrderHold:
+		g.hold(unit, dispatch)
+	case OrderMove:
+		if g.reach(unit, order.Point, dispatch) {
+			g.nextOrder(unit, dispatch)
+		}
+	case OrderAttackMove, OrderPatrol:
+		if unit.Target != ZeroUnitId {
+			// fighting, combat takes over until the target is gone
+			return
+		}
+		if enemy := g.enemyNear(unit, acquireRange); enemy != nil {
+			unit.Target = enemy.Id
+			return
+		}
+		if g.reach(unit, order.Point, dispatch) {
+			g.nextOrder(unit, dispatch)
+		}
+	default:
+		log.Printf("unknown order %s of unit %s", order.Type, uuid.UUID(unit.Id))
+		g.nextOrder(unit, dispatch)
+	}
+}
+
+// reach - walks to the point, true when there or it cannot get any closer
+func (g *GameLogic) reach(unit *Unit, p image.Point, dispatch DispatchFunc) bool {
+	if unit.Moving() {
+		return false
+	}
+	if unit.Position.ImagePoint() == p {
+		return true
+	}
+	return !g.walk(unit, p, dispatch)
+}
+
+// hold - attacks enemies in range without leaving the position
+func (g *GameLogic) hold(unit *Unit, dispatch DispatchFunc) {
+	if unit.Moving() {
+		dispatch(MoveStopAction{
+			Type:    MoveStopActionType,
+			Payload: unit.Id,
+		})
+	}
+	if unit.Target != ZeroUnitId {
+		target := g.store.GetUnitById(unit.Target)
+		if target != nil && unit.Position.Dist(target.Position) <= unit.AttackRange {
+			return
+		}
+		unit.Target = ZeroUnitId
+	}
+	if enemy := g.enemyNear(unit, unit.AttackRange); enemy != nil {
+		unit.Target = enemy.Id
+	}
+}
+
+// enemyNear - closest unit of another player within the range
+func (g *GameLogic) enemyNear(unit *Unit, r float64) *Unit {
+	if unit.Damage == 0 {
+		return nil
+	}
+	var closest *Unit
+	for _, u := range g.store.GetUnitsInRadius(unit.Position, r) {
+		if u.Owner == unit.Owner {
+			continue
+		}
+		d := unit.Position.Dist(u.Position)
+		if d <= r && (closest == nil || d < unit.Position.Dist(closest.Position)) {
+			closest = u
+		}
+	}
+	return closest
+}
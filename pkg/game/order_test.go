@@ -0,0 +1,159 @@
+package game_test
+
+import (
+	"image"
+	"testing"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+)
+
+func TestGameLogic_Update_FollowsQueuedOrders(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	unit := game.NewUnit(player.Id, player.Color, game.NewPF(0, 0), 16, 16)
+	store.StoreUnit(unit)
+
+	logic.HandleAction(newOrderAction(unit.Id, game.OrderMove, image.Pt(3, 0), false), ignore)
+	logic.HandleAction(newOrderAction(unit.Id, game.OrderMove, image.Pt(3, 3), true), ignore)
+	if len(unit.Orders) != 2 {
+		t.Fatalf("expected 2 orders, got %v", unit.Orders)
+	}
+
+	visited := false
+	runUpdates(logic, 200, func() bool {
+		visited = visited || unit.Position == game.NewPF(3, 0)
+		return len(unit.Orders) == 0
+	})
+
+	if !visited {
+		t.Error("expected the unit to walk through the first waypoint")
+	}
+	if unit.Position != game.NewPF(3, 3) {
+		t.Errorf("expected unit at (3,3), got %v", unit.Position)
+	}
+	if len(unit.Orders) != 0 {
+		t.Errorf("expected no orders left, got %v", unit.Orders)
+	}
+}
+
+func TestGameLogic_Update_PatrolGoesRound(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	unit := game.NewUnit(player.Id, player.Color, game.NewPF(0, 0), 16, 16)
+	store.StoreUnit(unit)
+
+	logic.HandleAction(newOrderAction(unit.Id, game.OrderPatrol, image.Pt(2, 0), false), ignore)
+
+	reached := false
+	runUpdates(logic, 200, func() bool {
+		reached = reached || unit.Position == game.NewPF(2, 0)
+		return reached && unit.Position == game.NewPF(0, 0)
+	})
+
+	if unit.Position != game.NewPF(0, 0) {
+		t.Errorf("expected the unit back at the start, got %v", unit.Position)
+	}
+	if len(unit.Orders) != 2 {
+		t.Errorf("expected the patrol to go on, got %v", unit.Orders)
+	}
+}
+
+func TestGameLogic_Update_AttackMoveEngagesEnemy(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player1 := createTestPlayer("player1")
+	player2 := createTestPlayer("player2")
+	unit := game.NewUnit(player1.Id, player1.Color, game.NewPF(0, 0), 16, 16)
+	enemy := game.NewUnit(player2.Id, player2.Color, game.NewPF(4, 0), 16, 16)
+	store.StoreUnit(unit)
+	store.StoreUnit(enemy)
+
+	logic.HandleAction(newOrderAction(unit.Id, game.OrderAttackMove, image.Pt(20, 0), false), ignore)
+	logic.Update(ignore)
+
+	if unit.Target != enemy.Id {
+		t.Error("expected the unit to engage the enemy met on the way")
+	}
+	if len(unit.Orders) != 1 {
+		t.Errorf("expected the attack-move to resume after the fight, got %v", unit.Orders)
+	}
+}
+
+func TestGameLogic_Update_HoldDoesNotChase(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player1 := createTestPlayer("player1")
+	player2 := createTestPlayer("player2")
+	unit := game.NewUnit(player1.Id, player1.Color, game.NewPF(0, 0), 16, 16)
+	enemy := game.NewUnit(player2.Id, player2.Color, game.NewPF(6, 0), 16, 16)
+	store.StoreUnit(unit)
+	store.StoreUnit(enemy)
+	logic.HandleAction(newAttackAction(unit.Id, enemy.Id), ignore)
+
+	logic.HandleAction(newOrderAction(unit.Id, game.OrderHold, image.Point{}, false), ignore)
+	runUpdates(logic, 20, func() bool { return false })
+
+	if unit.Position != game.NewPF(0, 0) {
+		t.Errorf("expected the unit to hold its position, got %v", unit.Position)
+	}
+	if unit.Target != game.ZeroUnitId {
+		t.Error("expected the unit to drop the target out of range")
+	}
+}
+
+func TestGameLogic_HandleAction_StopClearsOrders(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	unit := game.NewUnit(player.Id, player.Color, game.NewPF(0, 0), 16, 16)
+	store.StoreUnit(unit)
+	logic.HandleAction(newOrderAction(unit.Id, game.OrderPatrol, image.Pt(5, 0), false), ignore)
+
+	var dispatchedActions []game.Action
+	logic.HandleAction(game.StopAction{
+		Type:    game.StopActionType,
+		Payload: game.StopPayload{UnitId: unit.Id},
+	}, func(action game.Action) {
+		dispatchedActions = append(dispatchedActions, action)
+	})
+
+	if len(unit.Orders) != 0 {
+		t.Errorf("expected no orders, got %v", unit.Orders)
+	}
+	if len(dispatchedActions) != 1 {
+		t.Fatalf("expected 1 dispatched action, got %d: %v", len(dispatchedActions), dispatchedActions)
+	}
+	if orders, ok := dispatchedActions[0].(game.UnitOrdersAction); !ok || len(orders.Payload.Orders) != 0 {
+		t.Errorf("expected the owner to be told of the empty queue, got %v", dispatchedActions[0])
+	}
+}
+
+// ignore - drops the dispatched actions
+func ignore(game.Action) {}
+
+// runUpdates - updates the game until done, feeding dispatched actions back
+func runUpdates(logic *game.GameLogic, limit int, done func() bool) {
+	var dispatch game.DispatchFunc
+	dispatch = func(action game.Action) {
+		logic.HandleAction(action, dispatch)
+	}
+	for range limit {
+		logic.Update(dispatch)
+		if done() {
+			return
+		}
+	}
+}
+
+func newOrderAction(unitId game.UnitIdType, orderType game.OrderType, p image.Point, queue bool) game.OrderAction {
+	return game.OrderAction{
+		Type: game.OrderActionType,
+		Payload: game.OrderPayload{
+			UnitId: unitId,
+			Order:  game.Order{Type: orderType, Point: p},
+			Queue:  queue,
+		},
+	}
+}
@@ -0,0 +1,160 @@
+package game
+
+import (
+	"container/heap"
+	"image"
+	"math"
+)
+
+// maxPathNodes - limit of expanded nodes, the world has no borders
+const maxPathNodes = 4096
+
+var neighbours = []image.Point{
+	{1, 0}, {-1, 0}, {0, 1}, {0, -1},
+	{1, 1}, {1, -1}, {-1, 1}, {-1, -1},
+}
+
+type Pathfinder struct {
+	store Store
+}
+
+func NewPathfinder(store Store) *Pathfinder {
+	return &Pathfinder{
+		store: store,
+	}
+}
+
+type pathNode struct {
+	point image.Point
+	g, f  float64
+	index int
+}
+
+type pathQueue []*pathNode
+
+func (q pathQueue) Len() int { return len(q) }
+
+func (q pathQueue) Less(i, j int) bool { return q[i].f < q[j].f }
+
+func (q pathQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *pathQueue) Push(x any) {
+	n := x.(*pathNode)
+	n.index = len(*q)
+	*q = append(*q, n)
+}
+
+func (q *pathQueue) Pop() any {
+	old := *q
+	n := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return n
+}
+
+// FindPath - A* search of the path for the unit, the path starts with the start point.
+// When the goal is unreachable the path leads to the closest reachable tile and ok is false.
+func (pf *Pathfinder) FindPath(unitId UnitIdType, start, goal image.Point) (path []image.Point, ok bool) {
+	open := &pathQueue{}
+	nodes := map[image.Point]*pathNode{}
+	cameFrom := map[image.Point]image.Point{}
+	closed := map[image.Point]bool{}
+
+	startNode := &pathNode{point: start, f: octile(start, goal)}
+	nodes[start] = startNode
+	heap.Push(open, startNode)
+	closest, closestH := start, octile(start, goal)
+	unit := pf.store.GetUnitById(unitId)
+
+	for open.Len() > 0 && len(closed) < maxPathNodes {
+		current := heap.Pop(open).(*pathNode)
+		if current.point == goal {
+			return reconstructPath(cameFrom, start, goal), true
+		}
+		closed[current.point] = true
+		if h := current.f - current.g; h < closestH {
+			closest, closestH = current.point, h
+		}
+		for _, d := range neighbours {
+			next := current.point.Add(d)
+			if closed[next] {
+				continue
+			}
+			cost, passable := pf.stepCost(unit, current.point, d)
+			if !passable {
+				continue
+			}
+			g := current.g + cost
+			n, seen := nodes[next]
+			if seen && g >= n.g {
+				continue
+			}
+			cameFrom[next] = current.point
+			if !seen {
+				n = &pathNode{point: next}
+				nodes[next] = n
+				n.g, n.f = g, g+octile(next, goal)
+				heap.Push(open, n)
+				continue
+			}
+			n.g, n.f = g, g+octile(next, goal)
+			heap.Fix(open, n.index)
+		}
+	}
+
+	return reconstructPath(cameFrom, start, closest), false
+}
+
+// stepCost - cost of a single step in direction d, diagonal steps cannot cut corners
+func (pf *Pathfinder) stepCost(unit *Unit, from, d image.Point) (float64, bool) {
+	if d.X != 0 && d.Y != 0 {
+		if !pf.free(unit, from.Add(image.Pt(d.X, 0))) || !pf.free(unit, from.Add(image.Pt(0, d.Y))) {
+			return 0, false
+		}
+	}
+	to := from.Add(d)
+	if !pf.free(unit, to) {
+		return 0, false
+	}
+	fromTile, _ := pf.store.GetTile(from)
+	toTile, _ := pf.store.GetTile(to)
+	cost, ok := MoveCost(fromTile, toTile)
+	if d.X != 0 && d.Y != 0 {
+		cost *= math.Sqrt2
+	}
+	return cost, ok
+}
+
+// free - tile can be entered by the unit and is not reserved by any other unit
+func (pf *Pathfinder) free(unit *Unit, p image.Point) bool {
+	t, ok := pf.store.GetTile(p)
+	if !ok {
+		return true
+	}
+	if t.Unit != nil && (unit == nil || t.Unit.Id != unit.Id) {
+		return false
+	}
+	return unit.CanEnter(t)
+}
+
+func reconstructPath(cameFrom map[image.Point]image.Point, start, end image.Point) []image.Point {
+	path := []image.Point{end}
+	for p := end; p != start; {
+		p = cameFrom[p]
+		path = append(path, p)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// octile - admissible distance estimate for 8-directional movement
+func octile(a, b image.Point) float64 {
+	dx, dy := math.Abs(float64(a.X-b.X)), math.Abs(float64(a.Y-b.Y))
+	return math.Max(dx, dy) + (math.Sqrt2-1)*math.Min(dx, dy)
+}
@@ -0,0 +1,142 @@
+package game_test
+
+import (
+	"image"
+	"testing"
+
+	"github.com/bmcszk/fogofgo/pkg/convert"
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/world"
+	"github.com/google/uuid"
+)
+
+func TestPathfinder_FindPath_Straight(t *testing.T) {
+	store := game.NewStoreImpl()
+	pf := game.NewPathfinder(store)
+
+	path, ok := pf.FindPath(game.UnitIdType(uuid.New()), image.Pt(0, 0), image.Pt(3, 3))
+	if !ok {
+		t.Fatal("expected goal to be reachable")
+	}
+
+	expected := []image.Point{image.Pt(0, 0), image.Pt(1, 1), image.Pt(2, 2), image.Pt(3, 3)}
+	assertPath(t, expected, path)
+}
+
+func TestPathfinder_FindPath_AroundWater(t *testing.T) {
+	store := game.NewStoreImpl()
+	pf := game.NewPathfinder(store)
+
+	// wall of water between start and goal with a gap at y=3
+	for y := -2; y <= 2; y++ {
+		store.StoreTile(world.Tile{Point: image.Pt(2, y), LandType: world.LandLake, WaterLevel: convert.ToPointer(3)})
+	}
+
+	path, ok := pf.FindPath(game.UnitIdType(uuid.New()), image.Pt(0, 0), image.Pt(4, 0))
+	if !ok {
+		t.Fatal("expected goal to be reachable")
+	}
+	assertWalkable(t, store, path)
+	if path[len(path)-1] != image.Pt(4, 0) {
+		t.Errorf("expected path to end at (4,0), got %v", path[len(path)-1])
+	}
+}
+
+func TestPathfinder_FindPath_AroundUnits(t *testing.T) {
+	store := game.NewStoreImpl()
+	pf := game.NewPathfinder(store)
+
+	player := createTestPlayer("testplayer")
+	blocker := createTestUnit(player.Id, image.Pt(1, 0))
+	store.SetTileUnit(store.CreateTile(image.Pt(1, 0)), blocker)
+
+	path, ok := pf.FindPath(game.UnitIdType(uuid.New()), image.Pt(0, 0), image.Pt(2, 0))
+	if !ok {
+		t.Fatal("expected goal to be reachable")
+	}
+	for _, p := range path {
+		if p == image.Pt(1, 0) {
+			t.Errorf("path should not go through tile occupied by other unit: %v", path)
+		}
+	}
+}
+
+func TestPathfinder_FindPath_PrefersCheaperTerrain(t *testing.T) {
+	store := game.NewStoreImpl()
+	pf := game.NewPathfinder(store)
+
+	// direct route through hills, detour over plains
+	for x := 1; x <= 3; x++ {
+		store.StoreTile(world.Tile{Point: image.Pt(x, 0), LandType: world.LandHill})
+		store.StoreTile(world.Tile{Point: image.Pt(x, 1), LandType: world.LandPlain})
+	}
+
+	path, ok := pf.FindPath(game.UnitIdType(uuid.New()), image.Pt(0, 0), image.Pt(4, 0))
+	if !ok {
+		t.Fatal("expected goal to be reachable")
+	}
+	for _, p := range path[1 : len(path)-1] {
+		if p.Y != 1 {
+			t.Errorf("expected path over plains, got %v", path)
+			break
+		}
+	}
+}
+
+func TestPathfinder_FindPath_Partial(t *testing.T) {
+	store := game.NewStoreImpl()
+	pf := game.NewPathfinder(store)
+
+	// goal surrounded by mountains
+	goal := image.Pt(5, 0)
+	for _, d := range []image.Point{{-1, -1}, {0, -1}, {1, -1}, {-1, 0}, {1, 0}, {-1, 1}, {0, 1}, {1, 1}} {
+		store.StoreTile(world.Tile{Point: goal.Add(d), LandType: world.LandMountain})
+	}
+
+	path, ok := pf.FindPath(game.UnitIdType(uuid.New()), image.Pt(0, 0), goal)
+	if ok {
+		t.Fatal("expected goal to be unreachable")
+	}
+	last := path[len(path)-1]
+	if game.Dist(last, goal) > 2 {
+		t.Errorf("expected partial path to end next to the mountains, got %v", last)
+	}
+	assertWalkable(t, store, path)
+}
+
+func TestPathfinder_FindPath_SteepClimb(t *testing.T) {
+	store := game.NewStoreImpl()
+	pf := game.NewPathfinder(store)
+
+	store.StoreTile(world.Tile{Point: image.Pt(0, 0), GroundLevel: 0})
+	store.StoreTile(world.Tile{Point: image.Pt(1, 0), GroundLevel: 100})
+
+	path, ok := pf.FindPath(game.UnitIdType(uuid.New()), image.Pt(0, 0), image.Pt(1, 0))
+	if !ok {
+		t.Fatal("expected goal to be reachable by going around the cliff")
+	}
+	if len(path) < 3 {
+		t.Errorf("expected detour around the cliff, got %v", path)
+	}
+}
+
+func assertPath(t *testing.T, expected, actual []image.Point) {
+	t.Helper()
+	if len(expected) != len(actual) {
+		t.Fatalf("expected path %v, got %v", expected, actual)
+	}
+	for i := range expected {
+		if expected[i] != actual[i] {
+			t.Fatalf("expected path %v, got %v", expected, actual)
+		}
+	}
+}
+
+func assertWalkable(t *testing.T, store game.Store, path []image.Point) {
+	t.Helper()
+	for _, p := range path {
+		if tile, ok := store.GetTile(p); ok && !tile.Passable() {
+			t.Errorf("path goes through impassable tile %v: %v", p, path)
+		}
+	}
+}
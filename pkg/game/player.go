@@ -13,6 +13,8 @@ type Player struct {
 	Name  string
 	Color color.RGBA
 	Start PF
+
+	Resources Resources
 }
 
 func NewPlayer(name string) *Player {
@@ -84,3 +84,44 @@ func NextStep(s image.Point, target image.Point) image.Point {
 	}
 	return image.Pt(s.X+dx, s.Y+dy)
 }
+
+// Line - tiles on the straight line between the points, both included (Bresenham)
+func Line(from image.Point, to image.Point) []image.Point {
+	dx, dy := abs(to.X-from.X), -abs(to.Y-from.Y)
+	sx, sy := sign(to.X-from.X), sign(to.Y-from.Y)
+	r := make([]image.Point, 0, max(dx, -dy)+1)
+	p := from
+	e := dx + dy
+	for {
+		r = append(r, p)
+		if p == to {
+			return r
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			p.X += sx
+		}
+		if e2 <= dx {
+			e += dx
+			p.Y += sy
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
@@ -175,3 +175,23 @@ func TestNextStep(t *testing.T) {
 		})
 	}
 }
+
+func TestLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to image.Point
+		expected []image.Point
+	}{
+		{"same point", image.Pt(2, 2), image.Pt(2, 2), []image.Point{image.Pt(2, 2)}},
+		{"horizontal", image.Pt(0, 0), image.Pt(-2, 0), []image.Point{image.Pt(0, 0), image.Pt(-1, 0), image.Pt(-2, 0)}},
+		{"diagonal", image.Pt(0, 0), image.Pt(2, 2), []image.Point{image.Pt(0, 0), image.Pt(1, 1), image.Pt(2, 2)}},
+		{"shallow", image.Pt(0, 0), image.Pt(4, 1),
+			[]image.Point{image.Pt(0, 0), image.Pt(1, 0), image.Pt(2, 1), image.Pt(3, 1), image.Pt(4, 1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertPath(t, tt.expected, game.Line(tt.from, tt.to))
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package game
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ReplayEntry - single line of the replay file, action accepted by the server in the tick
+type ReplayEntry struct {
+	Tick   int64
+	Time   time.Time
+	Action json.RawMessage
+}
+
+// Recorder - appends accepted actions to the replay log as JSON lines
+type Recorder struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func NewRecorder(w io.Writer) *Recorder {
+	bw := bufio.NewWriter(w)
+	return &Recorder{
+		w:   bw,
+		enc: json.NewEncoder(bw),
+	}
+}
+
+func (r *Recorder) Record(tick int64, action Action) error {
+	bytes, err := json.Marshal(action)
+	if err != nil {
+		return fmt.Errorf("record %s: %w", action.GetType(), err)
+	}
+	return r.enc.Encode(ReplayEntry{
+		Tick:   tick,
+		Time:   time.Now(),
+		Action: bytes,
+	})
+}
+
+// Flush - writes the buffered entries
+func (r *Recorder) Flush() error {
+	return r.w.Flush()
+}
+
+// Replay - plays the recorded actions back, tick by tick
+type Replay struct {
+	decoder *json.Decoder
+	next    *ReplayEntry
+	tick    int64
+	done    bool
+}
+
+func NewReplay(r io.Reader) *Replay {
+	return &Replay{
+		decoder: json.NewDecoder(r),
+	}
+}
+
+// Tick - last played tick
+func (r *Replay) Tick() int64 {
+	return r.tick
+}
+
+// Done - whether the whole log was played
+func (r *Replay) Done() bool {
+	return r.done
+}
+
+// Step - hands the actions of the next tick to the handler,
+// actions caused by them are recorded as well so nothing is dispatched
+func (r *Replay) Step(handler ActionsHandler) error {
+	r.tick++
+	for !r.done {
+		if r.next == nil {
+			if err := r.read(); err != nil {
+				return err
+			}
+			continue
+		}
+		if r.next.Tick > r.tick {
+			return nil
+		}
+		action, err := UnmarshalAction(r.next.Action)
+		r.next = nil
+		if err != nil {
+			return err
+		}
+		handler.HandleAction(replayed(action), discard)
+	}
+	return nil
+}
+
+// Run - plays the whole log
+func (r *Replay) Run(handler ActionsHandler) error {
+	for !r.done {
+		if err := r.Step(handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Replay) read() error {
+	var entry ReplayEntry
+	err := r.decoder.Decode(&entry)
+	if errors.Is(err, io.EOF) {
+		r.done = true
+		return nil
+	}
+	if err != nil {
+		r.done = true
+		return fmt.Errorf("replay tick %d: %w", r.tick, err)
+	}
+	r.next = &entry
+	return nil
+}
+
+// replayed - players join the game handled by the server, the replay only learns about them
+func replayed(action Action) Action {
+	a, ok := action.(PlayerJoinAction)
+	if !ok {
+		return action
+	}
+	return PlayerJoinSuccessAction{
+		Type: PlayerJoinSuccessActionType,
+		Payload: PlayerJoinSuccessPayload{
+			PlayerId: a.Payload.Id,
+			Players:  []Player{a.Payload},
+		},
+	}
+}
+
+func discard(Action) {}
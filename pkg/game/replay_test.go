@@ -0,0 +1,105 @@
+package game_test
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+)
+
+func TestReplay_ReproducesRecordedMatch(t *testing.T) {
+	var log bytes.Buffer
+	recorder := game.NewRecorder(&log)
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	var tick int64
+	handle := func(action game.Action) {
+		if err := recorder.Record(tick, action); err != nil {
+			t.Fatal(err)
+		}
+		logic.HandleAction(action, nil)
+	}
+
+	player := createTestPlayer("player1")
+	unit := game.NewUnit(player.Id, player.Color, game.NewPF(1, 1), 16, 16)
+	handle(game.PlayerJoinAction{Type: game.PlayerJoinActionType, Payload: player})
+	handle(game.SpawnUnitAction{Type: game.SpawnUnitActionType, Payload: *unit})
+	handle(game.MoveStartAction{
+		Type:    game.MoveStartActionType,
+		Payload: game.MoveStartPayload{UnitId: unit.Id, Point: image.Pt(4, 1)},
+	})
+	for tick = 1; tick <= 60; tick++ {
+		logic.Update(handle)
+	}
+	if err := recorder.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	recorded := store.GetUnitById(unit.Id)
+	if recorded.Position == unit.Position {
+		t.Fatal("unit should move while recording")
+	}
+
+	replayStore := game.NewStoreImpl()
+	replay := game.NewReplay(&log)
+	if err := replay.Run(game.NewGameLogic(replayStore)); err != nil {
+		t.Fatal(err)
+	}
+
+	if !replay.Done() {
+		t.Error("replay should be done")
+	}
+	if _, ok := replayStore.GetPlayer(player.Id); !ok {
+		t.Error("replay should know the player")
+	}
+	replayed := replayStore.GetUnitById(unit.Id)
+	if replayed == nil {
+		t.Fatal("replay should spawn the unit")
+	}
+	if replayed.Position != recorded.Position {
+		t.Errorf("expected unit at %v, got %v", recorded.Position, replayed.Position)
+	}
+}
+
+func TestReplay_Step_PlaysOneTick(t *testing.T) {
+	var log bytes.Buffer
+	recorder := game.NewRecorder(&log)
+	player := createTestPlayer("player1")
+	first := game.NewUnit(player.Id, player.Color, game.NewPF(1, 1), 16, 16)
+	second := game.NewUnit(player.Id, player.Color, game.NewPF(5, 5), 16, 16)
+	if err := recorder.Record(1, game.SpawnUnitAction{Type: game.SpawnUnitActionType, Payload: *first}); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Record(3, game.SpawnUnitAction{Type: game.SpawnUnitActionType, Payload: *second}); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	replay := game.NewReplay(&log)
+
+	for range 2 {
+		if err := replay.Step(logic); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if store.GetUnitById(first.Id) == nil {
+		t.Error("unit of tick 1 should be spawned")
+	}
+	if store.GetUnitById(second.Id) != nil {
+		t.Error("unit of tick 3 should not be spawned yet")
+	}
+
+	if err := replay.Step(logic); err != nil {
+		t.Fatal(err)
+	}
+	if store.GetUnitById(second.Id) == nil {
+		t.Error("unit of tick 3 should be spawned")
+	}
+	if replay.Tick() != 3 {
+		t.Errorf("expected tick 3, got %d", replay.Tick())
+	}
+}
@@ -0,0 +1,148 @@
+package game
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// requestWindow - how many of the latest request ids are remembered per player,
+	// older ones are assumed to be handled
+	requestWindow = 256
+	// requestTimeout - how long an unanswered request is kept, requests the server drops
+	// are not retried forever
+	requestTimeout = 30 * time.Second
+)
+
+// NewAckAction - answer to the handled request
+func NewAckAction(request Action, duplicate bool) AckAction {
+	return AckAction{
+		Type: AckActionType,
+		Payload: AckPayload{
+			Action:    request.GetType(),
+			Duplicate: duplicate,
+		},
+		Envelope: Envelope{RequestId: request.GetEnvelope().RequestId},
+	}
+}
+
+// Reply - stamps the reply with the request id and the tick it was handled in
+func Reply(action Action, requestId RequestIdType, tick int64) Action {
+	return action.WithEnvelope(Envelope{RequestId: requestId, Tick: tick})
+}
+
+// Requests - client side numbering of the requests, kept until the server answers them or they expire
+type Requests struct {
+	mux     sync.Mutex
+	last    RequestIdType
+	pending map[RequestIdType]pendingRequest
+}
+
+type pendingRequest struct {
+	action Action
+	sent   time.Time
+}
+
+func NewRequests() *Requests {
+	return &Requests{
+		pending: make(map[RequestIdType]pendingRequest),
+	}
+}
+
+// Stamp - numbers the request and keeps it until answered
+func (r *Requests) Stamp(action Action) Action {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.last++
+	action = action.WithEnvelope(Envelope{RequestId: r.last})
+	r.pending[r.last] = pendingRequest{action: action, sent: time.Now()}
+	r.expire()
+	return action
+}
+
+// expire - forgets the requests which are too old to be answered
+// or which the server no longer tells apart from the handled ones
+func (r *Requests) expire() {
+	for id, req := range r.pending {
+		if time.Since(req.sent) > requestTimeout || id+requestWindow <= r.last {
+			delete(r.pending, id)
+		}
+	}
+}
+
+// Answer - forgets the request answered by the action, returns the round trip time,
+// false when the action answers no pending request
+func (r *Requests) Answer(action Action) (time.Duration, bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	id := action.GetEnvelope().RequestId
+	req, ok := r.pending[id]
+	if !ok {
+		return 0, false
+	}
+	delete(r.pending, id)
+	return time.Since(req.sent), true
+}
+
+// Pending - unanswered requests in the order they were sent, to be retried on a new connection
+func (r *Requests) Pending() []Action {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.expire()
+	ids := make([]RequestIdType, 0, len(r.pending))
+	for id := range r.pending {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	actions := make([]Action, len(ids))
+	for i, id := range ids {
+		actions[i] = r.pending[id].action
+	}
+	return actions
+}
+
+// RequestLog - server side record of the handled requests, retried requests are not handled twice
+type RequestLog struct {
+	players map[PlayerIdType]*handledRequests
+}
+
+type handledRequests struct {
+	highest RequestIdType
+	ids     map[RequestIdType]bool
+}
+
+func NewRequestLog() *RequestLog {
+	return &RequestLog{
+		players: make(map[PlayerIdType]*handledRequests),
+	}
+}
+
+// Handled - records the request, true when the player's request was handled before.
+// Actions without a request id are never duplicates.
+func (l *RequestLog) Handled(playerId PlayerIdType, action Action) bool {
+	id := action.GetEnvelope().RequestId
+	if id == 0 {
+		return false
+	}
+	h, ok := l.players[playerId]
+	if !ok {
+		h = &handledRequests{ids: make(map[RequestIdType]bool)}
+		l.players[playerId] = h
+	}
+	if id+requestWindow <= h.highest || h.ids[id] {
+		return true
+	}
+	h.ids[id] = true
+	if id > h.highest {
+		h.highest = id
+	}
+	if len(h.ids) > 2*requestWindow {
+		for old := range h.ids {
+			if old+requestWindow <= h.highest {
+				delete(h.ids, old)
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package game_test
+
+import (
+	"encoding/json"
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+)
+
+func TestRequests_StampAndAnswer(t *testing.T) {
+	requests := game.NewRequests()
+	player := createTestPlayer("player1")
+
+	first := requests.Stamp(game.NewMapLoadAction(image.Rect(0, 0, 10, 10), player.Id))
+	second := requests.Stamp(game.MoveStartAction{Type: game.MoveStartActionType})
+
+	if first.GetEnvelope().RequestId != 1 || second.GetEnvelope().RequestId != 2 {
+		t.Fatalf("expected sequential request ids, got %d and %d",
+			first.GetEnvelope().RequestId, second.GetEnvelope().RequestId)
+	}
+	if _, ok := requests.Answer(game.NewAckAction(second, false)); !ok {
+		t.Error("ack should answer the pending request")
+	}
+	if _, ok := requests.Answer(game.NewAckAction(second, false)); ok {
+		t.Error("request should be answered only once")
+	}
+	if _, ok := requests.Answer(game.MoveStopAction{Type: game.MoveStopActionType}); ok {
+		t.Error("actions without request id answer nothing")
+	}
+	pending := requests.Pending()
+	if len(pending) != 1 || pending[0].GetType() != game.MapLoadActionType {
+		t.Errorf("expected the map load to be pending, got %v", pending)
+	}
+}
+
+func TestRequests_Pending_DropsUnanswered(t *testing.T) {
+	requests := game.NewRequests()
+	for range 300 {
+		requests.Stamp(game.MoveStartAction{Type: game.MoveStartActionType})
+	}
+
+	pending := requests.Pending()
+
+	// the server takes the older ids for handled ones
+	if len(pending) != 256 || pending[0].GetEnvelope().RequestId != 45 {
+		t.Errorf("expected the latest 256 requests pending, got %d", len(pending))
+	}
+}
+
+func TestRequestLog_Handled(t *testing.T) {
+	log := game.NewRequestLog()
+	player1 := createTestPlayer("player1")
+	player2 := createTestPlayer("player2")
+	request := game.MoveStartAction{Type: game.MoveStartActionType}.WithEnvelope(game.Envelope{RequestId: 5})
+
+	if log.Handled(player1.Id, request) {
+		t.Error("first copy of the request should be handled")
+	}
+	if !log.Handled(player1.Id, request) {
+		t.Error("retried request should be recognised")
+	}
+	if log.Handled(player2.Id, request) {
+		t.Error("request ids are per player")
+	}
+	unnumbered := game.MoveStartAction{Type: game.MoveStartActionType}
+	if log.Handled(player1.Id, unnumbered) || log.Handled(player1.Id, unnumbered) {
+		t.Error("actions without request id are never duplicates")
+	}
+}
+
+func TestRequestLog_Handled_OldRequests(t *testing.T) {
+	log := game.NewRequestLog()
+	player := createTestPlayer("player1")
+	numbered := func(id game.RequestIdType) game.Action {
+		return game.MoveStartAction{Type: game.MoveStartActionType}.WithEnvelope(game.Envelope{RequestId: id})
+	}
+	for id := game.RequestIdType(2); id <= 1000; id++ {
+		log.Handled(player.Id, numbered(id))
+	}
+
+	if !log.Handled(player.Id, numbered(1)) {
+		t.Error("requests older than the window should be treated as handled")
+	}
+	if log.Handled(player.Id, numbered(1001)) {
+		t.Error("new request should be handled")
+	}
+}
+
+func TestEnvelope_OmittedWhenEmpty(t *testing.T) {
+	bytes, err := json.Marshal(game.MoveStopAction{Type: game.MoveStopActionType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(bytes), "RequestId") || strings.Contains(string(bytes), "Tick") {
+		t.Errorf("empty envelope should not be sent, got %s", bytes)
+	}
+
+	reply := game.Reply(game.MoveStopAction{Type: game.MoveStopActionType}, 3, 120)
+	bytes, err = json.Marshal(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := game.UnmarshalAction(bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.GetEnvelope() != (game.Envelope{RequestId: 3, Tick: 120}) {
+		t.Errorf("envelope lost in round trip, got %+v", decoded.GetEnvelope())
+	}
+}
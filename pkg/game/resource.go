@@ -0,0 +1,171 @@
+package game
+
+import (
+	"image"
+	"log"
+
+	"github.com/bmcszk/fogofgo/pkg/world"
+	"github.com/google/uuid"
+)
+
+type ResourceType string
+
+const (
+	ResourceWood  ResourceType = "wood"
+	ResourceStone ResourceType = "stone"
+)
+
+const (
+	GatherAmount   = 5  // resources gathered at once
+	GatherCooldown = 60 // ticks between gathers
+)
+
+// nodeCapacity - resources of a fresh tile by its land type
+var nodeCapacity = map[string]struct {
+	resource ResourceType
+	amount   int
+}{
+	world.LandForest:   {ResourceWood, 200},
+	world.LandMountain: {ResourceStone, 400},
+}
+
+var StartingResources = Resources{Wood: 200, Stone: 100}
+
+// Resources - stockpile of a player or a cost
+type Resources struct {
+	Wood  int
+	Stone int
+}
+
+func NewResources(resource ResourceType, amount int) Resources {
+	switch resource {
+	case ResourceWood:
+		return Resources{Wood: amount}
+	case ResourceStone:
+		return Resources{Stone: amount}
+	}
+	return Resources{}
+}
+
+func (r Resources) Add(o Resources) Resources {
+	return Resources{
+		Wood:  r.Wood + o.Wood,
+		Stone: r.Stone + o.Stone,
+	}
+}
+
+func (r Resources) Sub(o Resources) Resources {
+	return Resources{
+		Wood:  r.Wood - o.Wood,
+		Stone: r.Stone - o.Stone,
+	}
+}
+
+// Covers - whether the stockpile is enough to pay the cost
+func (r Resources) Covers(cost Resources) bool {
+	return r.Wood >= cost.Wood && r.Stone >= cost.Stone
+}
+
+// Resource - resource of the tile and the amount left
+func (t *Tile) Resource() (ResourceType, int) {
+	if t.Tile == nil {
+		return "", 0
+	}
+	node, ok := nodeCapacity[t.LandType]
+	if !ok {
+		return "", 0
+	}
+	return node.resource, max(node.amount-t.Gathered, 0)
+}
+
+func (g *GameLogic) handleGatherAction(action GatherAction, dispatch DispatchFunc) {
+	unit := g.store.GetUnitById(action.Payload.UnitId)
+	if unit == nil {
+		log.Printf("gather: unknown unit %s", uuid.UUID(action.Payload.UnitId))
+		return
+	}
+	if unit.Building {
+		return
+	}
+	node := action.Payload.Point
+	unit.Target = ZeroUnitId
+	unit.Gather = &node
+	g.clearOrders(unit, dispatch)
+}
+
+func (g *GameLogic) handleResourceGatheredAction(action ResourceGatheredAction) {
+	if t, ok := g.store.GetTile(action.Payload.Point); ok {
+		t.Gathered += action.Payload.Amount
+	}
+	g.addResources(action.Payload.PlayerId, NewResources(action.Payload.Resource, action.Payload.Amount))
+}
+
+func (g *GameLogic) handleResourcesSpentAction(action ResourcesSpentAction) {
+	g.addResources(action.Payload.PlayerId, Resources{}.Sub(action.Payload.Resources))
+}
+
+func (g *GameLogic) addResources(id PlayerIdType, resources Resources) {
+	player, ok := g.store.GetPlayer(id)
+	if !ok {
+		return
+	}
+	player.Resources = player.Resources.Add(resources)
+}
+
+// updateGathering - walks the unit to its resource node and gathers from it
+func (g *GameLogic) updateGathering(unit *Unit, dispatch DispatchFunc) {
+	if unit.Gather == nil {
+		return
+	}
+	node := *unit.Gather
+	t, ok := g.store.GetTile(node)
+	if !ok {
+		unit.Gather = nil
+		return
+	}
+	resource, left := t.Resource()
+	if left == 0 {
+		unit.Gather = nil
+		return
+	}
+
+	if !adjacent(unit.Position.ImagePoint(), node) {
+		g.approach(unit, node, dispatch)
+		return
+	}
+	if unit.Moving() {
+		dispatch(MoveStopAction{
+			Type:    MoveStopActionType,
+			Payload: unit.Id,
+		})
+	}
+	if unit.Reload > 0 {
+		return
+	}
+	unit.Reload = GatherCooldown
+	dispatch(ResourceGatheredAction{
+		Type: ResourceGatheredActionType,
+		Payload: ResourceGatheredPayload{
+			UnitId:   unit.Id,
+			PlayerId: unit.Owner,
+			Point:    node,
+			Resource: resource,
+			Amount:   min(GatherAmount, left),
+		},
+	})
+}
+
+// approach - walks towards the node, gives up when it cannot be reached
+func (g *GameLogic) approach(unit *Unit, node image.Point, dispatch DispatchFunc) {
+	if unit.Moving() {
+		return
+	}
+	if !g.walk(unit, node, dispatch) {
+		unit.Gather = nil
+	}
+}
+
+// adjacent - whether the points are the same or neighbouring tiles
+func adjacent(a, b image.Point) bool {
+	return a.In(image.Rect(b.X-1, b.Y-1, b.X+2, b.Y+2))
+}
@@ -0,0 +1,135 @@
+package game_test
+
+import (
+	"image"
+	"testing"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/world"
+)
+
+func TestResources_Covers(t *testing.T) {
+	stockpile := game.Resources{Wood: 60, Stone: 10}
+
+	if !stockpile.Covers(game.Resources{Wood: 50}) {
+		t.Error("stockpile should cover the cost")
+	}
+	if stockpile.Covers(game.Resources{Wood: 50, Stone: 20}) {
+		t.Error("stockpile should not cover the cost")
+	}
+	left := stockpile.Sub(game.Resources{Wood: 50}).Add(game.NewResources(game.ResourceStone, 5))
+	if left != (game.Resources{Wood: 10, Stone: 15}) {
+		t.Errorf("unexpected stockpile %+v", left)
+	}
+}
+
+func TestTile_Resource(t *testing.T) {
+	store := game.NewStoreImpl()
+	forest := store.StoreTile(world.Tile{Point: image.Pt(0, 0), LandType: world.LandForest})
+	plain := store.StoreTile(world.Tile{Point: image.Pt(1, 0), LandType: world.LandPlain})
+
+	resource, amount := forest.Resource()
+	if resource != game.ResourceWood || amount == 0 {
+		t.Errorf("forest should give wood, got %q %d", resource, amount)
+	}
+	forest.Gathered = amount
+	if _, left := forest.Resource(); left != 0 {
+		t.Errorf("gathered forest should be depleted, got %d left", left)
+	}
+	if _, amount := plain.Resource(); amount != 0 {
+		t.Errorf("plain should have no resources, got %d", amount)
+	}
+}
+
+func TestGameLogic_Update_Gathers(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	store.StorePlayer(player)
+	node := image.Pt(1, 0)
+	store.StoreTile(world.Tile{Point: node, LandType: world.LandMountain})
+	unit := game.NewUnit(player.Id, player.Color, game.NewPF(0, 0), 16, 16)
+	store.StoreUnit(unit)
+	logic.HandleAction(newGatherAction(unit.Id, node), nil)
+
+	var gathered []game.ResourceGatheredAction
+	dispatchFunc := func(action game.Action) {
+		if a, ok := action.(game.ResourceGatheredAction); ok {
+			gathered = append(gathered, a)
+		}
+		logic.HandleAction(action, nil)
+	}
+	for range game.GatherCooldown + 1 {
+		logic.Update(dispatchFunc)
+	}
+
+	if len(gathered) != 2 {
+		t.Fatalf("expected 2 gathers, got %d", len(gathered))
+	}
+	if gathered[0].Payload.Resource != game.ResourceStone || gathered[0].Payload.Amount != game.GatherAmount {
+		t.Errorf("unexpected gather %+v", gathered[0].Payload)
+	}
+	p, _ := store.GetPlayer(player.Id)
+	if p.Resources.Stone != 2*game.GatherAmount {
+		t.Errorf("expected %d stone, got %d", 2*game.GatherAmount, p.Resources.Stone)
+	}
+	tile, _ := store.GetTile(node)
+	if tile.Gathered != 2*game.GatherAmount {
+		t.Errorf("expected %d gathered from the tile, got %d", 2*game.GatherAmount, tile.Gathered)
+	}
+}
+
+func TestGameLogic_Update_WalksToResource(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	node := image.Pt(5, 0)
+	store.StoreTile(world.Tile{Point: node, LandType: world.LandForest})
+	unit := game.NewUnit(player.Id, player.Color, game.NewPF(0, 0), 16, 16)
+	store.StoreUnit(unit)
+	logic.HandleAction(newGatherAction(unit.Id, node), nil)
+
+	var dispatchedActions []game.Action
+	logic.Update(func(action game.Action) {
+		dispatchedActions = append(dispatchedActions, action)
+	})
+
+	if len(dispatchedActions) != 1 {
+		t.Fatalf("expected 1 dispatched action, got %d: %v", len(dispatchedActions), dispatchedActions)
+	}
+	step, ok := dispatchedActions[0].(game.MoveStepAction)
+	if !ok {
+		t.Fatalf("expected MoveStepAction, got %T", dispatchedActions[0])
+	}
+	if dest := step.Payload.Path[len(step.Payload.Path)-1]; dest != node {
+		t.Errorf("expected path to %v, got %v", node, dest)
+	}
+}
+
+func TestGameLogic_HandleAction_MoveStartCancelsGathering(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	unit := game.NewUnit(player.Id, player.Color, game.NewPF(0, 0), 16, 16)
+	store.StoreUnit(unit)
+	logic.HandleAction(newGatherAction(unit.Id, image.Pt(3, 3)), nil)
+
+	logic.HandleAction(game.MoveStartAction{
+		Type:    game.MoveStartActionType,
+		Payload: game.MoveStartPayload{UnitId: unit.Id, Point: image.Pt(2, 0)},
+	}, nil)
+
+	if unit.Gather != nil {
+		t.Error("move order should cancel gathering")
+	}
+}
+
+func newGatherAction(unitId game.UnitIdType, p image.Point) game.GatherAction {
+	return game.GatherAction{
+		Type: game.GatherActionType,
+		Payload: game.GatherPayload{
+			UnitId: unitId,
+			Point:  p,
+		},
+	}
+}
@@ -0,0 +1,114 @@
+package game
+
+import (
+	"image"
+
+	"github.com/google/uuid"
+)
+
+const (
+	roomIdLength     = 8
+	DefaultRoomName  = "quick match"
+	defaultMinPlayer = 1
+	MaxRoomPlayers   = 16 // seat limit of a room, a spawn point each
+)
+
+type RoomIdType string
+
+type RoomState string
+
+const (
+	RoomWaiting  RoomState = "waiting"
+	RoomRunning  RoomState = "running"
+	RoomFinished RoomState = "finished"
+)
+
+// Difficulty - how much an AI opponent does, each level does what the easier ones do
+type Difficulty string
+
+const (
+	DifficultyEasy   Difficulty = "easy"   // gathers and scouts
+	DifficultyNormal Difficulty = "normal" // expands, trains soldiers and defends its base
+	DifficultyHard   Difficulty = "hard"   // attacks the enemies it has found
+)
+
+// Valid - whether the difficulty is known
+func (d Difficulty) Valid() bool {
+	switch d {
+	case DifficultyEasy, DifficultyNormal, DifficultyHard:
+		return true
+	}
+	return false
+}
+
+// DefaultSpawnPoints - starting points of the players when the room does not define its own
+var DefaultSpawnPoints = []image.Point{
+	image.Pt(1, 1),
+	image.Pt(15, 1),
+	image.Pt(1, 15),
+	image.Pt(15, 15),
+}
+
+// RoomConfig - settings of a match, chosen by the player creating the room
+type RoomConfig struct {
+	Name        string
+	MinPlayers  int
+	MaxPlayers  int
+	SpawnPoints []image.Point
+	AI          []Difficulty `json:",omitempty"` // opponents played by the server, they take seats too
+}
+
+// RoomInfo - room as listed in the lobby
+type RoomInfo struct {
+	Id         RoomIdType
+	Name       string
+	State      RoomState
+	Players    int
+	MaxPlayers int
+}
+
+func NewRoomId() RoomIdType {
+	return RoomIdType(uuid.NewString()[:roomIdLength])
+}
+
+// Validate - nil when the room can be created, spawn points come from the client
+func (c RoomConfig) Validate() *Error {
+	if len(c.SpawnPoints) > MaxRoomPlayers {
+		return NewError(ErrorInvalid, "%d spawn points, up to %d allowed", len(c.SpawnPoints), MaxRoomPlayers)
+	}
+	for _, p := range c.SpawnPoints {
+		if !inBounds(p) {
+			return NewError(ErrorInvalid, "spawn point %v out of bounds", p)
+		}
+	}
+	return nil
+}
+
+// WithDefaults - fills missing settings, there can be no more players than spawn points
+func (c RoomConfig) WithDefaults() RoomConfig {
+	if c.Name == "" {
+		c.Name = DefaultRoomName
+	}
+	if len(c.SpawnPoints) == 0 {
+		c.SpawnPoints = DefaultSpawnPoints
+	}
+	if c.MaxPlayers <= 0 || c.MaxPlayers > len(c.SpawnPoints) {
+		c.MaxPlayers = len(c.SpawnPoints)
+	}
+	if c.MinPlayers <= 0 {
+		c.MinPlayers = defaultMinPlayer
+	}
+	c.MinPlayers = min(c.MinPlayers, c.MaxPlayers)
+	// a seat is left for the player creating the room, the match waits for the player
+	var ai []Difficulty
+	for _, d := range c.AI {
+		if d.Valid() && len(ai) < c.MaxPlayers-1 {
+			ai = append(ai, d)
+		}
+	}
+	c.AI = ai
+	if len(ai) > 0 {
+		c.MinPlayers = max(c.MinPlayers, len(ai)+1)
+	}
+	return c
+}
@@ -0,0 +1,81 @@
+package game_test
+
+import (
+	"image"
+	"slices"
+	"testing"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+)
+
+func TestRoomConfig_WithDefaults(t *testing.T) {
+	config := game.RoomConfig{}.WithDefaults()
+
+	if config.Name != game.DefaultRoomName {
+		t.Errorf("expected name %q, got %q", game.DefaultRoomName, config.Name)
+	}
+	if config.MaxPlayers != len(game.DefaultSpawnPoints) {
+		t.Errorf("expected %d max players, got %d", len(game.DefaultSpawnPoints), config.MaxPlayers)
+	}
+	if config.MinPlayers != 1 {
+		t.Errorf("expected 1 min player, got %d", config.MinPlayers)
+	}
+}
+
+func TestRoomConfig_WithDefaults_LimitsPlayersToSpawnPoints(t *testing.T) {
+	config := game.RoomConfig{
+		Name:        "duel",
+		MinPlayers:  5,
+		MaxPlayers:  8,
+		SpawnPoints: []image.Point{image.Pt(0, 0), image.Pt(20, 20)},
+	}.WithDefaults()
+
+	if config.Name != "duel" {
+		t.Errorf("expected name duel, got %q", config.Name)
+	}
+	if config.MaxPlayers != 2 {
+		t.Errorf("expected 2 max players, got %d", config.MaxPlayers)
+	}
+	if config.MinPlayers != 2 {
+		t.Errorf("expected 2 min players, got %d", config.MinPlayers)
+	}
+}
+
+func TestRoomConfig_WithDefaults_SeatsAI(t *testing.T) {
+	config := game.RoomConfig{
+		MaxPlayers: 3,
+		AI:         []game.Difficulty{game.DifficultyHard, "impossible", game.DifficultyEasy, game.DifficultyNormal},
+	}.WithDefaults()
+
+	if !slices.Equal(config.AI, []game.Difficulty{game.DifficultyHard, game.DifficultyEasy}) {
+		t.Errorf("expected the known levels with a seat left for the creator, got %v", config.AI)
+	}
+	if config.MinPlayers != 3 {
+		t.Errorf("expected the match to wait for the creator, got %d min players", config.MinPlayers)
+	}
+}
+
+func TestRoomConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []image.Point
+		valid  bool
+	}{
+		{name: "default spawn points", valid: true},
+		{name: "a point per seat", points: make([]image.Point, game.MaxRoomPlayers), valid: true},
+		{name: "more points than seats", points: make([]image.Point, game.MaxRoomPlayers+1)},
+		{name: "point out of bounds", points: []image.Point{{0, 0}, {game.MaxCoordinate + 1, 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := game.RoomConfig{SpawnPoints: tt.points}.Validate()
+
+			if tt.valid && err != nil {
+				t.Errorf("expected valid, got %v", err)
+			}
+			if !tt.valid && (err == nil || err.Code != game.ErrorInvalid) {
+				t.Errorf("expected %s, got %v", game.ErrorInvalid, err)
+			}
+		})
+	}
+}
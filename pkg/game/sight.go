@@ -0,0 +1,93 @@
+package game
+
+import (
+	"image"
+	"slices"
+
+	"github.com/bmcszk/fogofgo/pkg/world"
+)
+
+const (
+	// eyeLevel - ground levels above its tile the unit looks from
+	eyeLevel = 5
+	// hillSight - tiles units standing on hills see farther
+	hillSight = 2
+)
+
+// LineOfSight - tiles the unit sees, the sight is blocked by ground rising above the line of sight
+// and by forests, the blocking tiles themselves are seen. Units on hills see farther.
+// Tiles not loaded yet do not block the sight, the server loads the terrain of every tile it looks at.
+func LineOfSight(store Store, unit *Unit) []image.Point {
+	origin, offsets, eye := sightOf(store, unit)
+	r := make([]image.Point, 0, len(offsets))
+	for _, o := range offsets {
+		if p := origin.Add(o); inSight(store, origin, p, eye) {
+			r = append(r, p)
+		}
+	}
+	return r
+}
+
+// SeesTile - whether the tile is in the unit's line of sight
+func SeesTile(store Store, unit *Unit, p image.Point) bool {
+	origin, offsets, eye := sightOf(store, unit)
+	return slices.Contains(offsets, p.Sub(origin)) && inSight(store, origin, p, eye)
+}
+
+// sightOf - tile the unit looks from, offsets of the tiles in its sight range and its eye level
+func sightOf(store Store, unit *Unit) (image.Point, []image.Point, int) {
+	origin := unit.Position.ImagePoint()
+	ground := 0
+	offsets := unit.ISee
+	if t, ok := store.GetTile(origin); ok && t.Tile != nil {
+		ground = t.GroundLevel
+		if t.LandType == world.LandHill {
+			offsets = sightOffsets(sightRadius(unit.ISee) + hillSight)
+		}
+	}
+	return origin, offsets, ground + eyeLevel
+}
+
+// inSight - whether nothing between the points blocks the sight from the eye level
+func inSight(store Store, from, to image.Point, eye int) bool {
+	line := Line(from, to)
+	n := len(line) - 1
+	if n < 2 {
+		return true
+	}
+	target := eye
+	if t, ok := loadedTile(store, to); ok {
+		target = t.GroundLevel
+	}
+	for i, p := range line[1:n] {
+		t, ok := loadedTile(store, p)
+		if !ok {
+			continue
+		}
+		if t.LandType == world.LandForest {
+			return false
+		}
+		// height of the line of sight above the tile
+		if t.GroundLevel > eye+(target-eye)*(i+1)/n {
+			return false
+		}
+	}
+	return true
+}
+
+func loadedTile(store Store, p image.Point) (*Tile, bool) {
+	t, ok := store.GetTile(p)
+	if !ok || t.Tile == nil || t.LandType == "" {
+		return nil, false
+	}
+	return t, true
+}
+
+// sightRadius - radius of the sight circle
+func sightRadius(offsets []image.Point) int {
+	r := 0
+	for _, o := range offsets {
+		r = max(r, abs(o.X), abs(o.Y))
+	}
+	return r
+}
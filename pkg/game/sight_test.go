@@ -0,0 +1,95 @@
+package game_test
+
+import (
+	"image"
+	"image/color"
+	"slices"
+	"testing"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/world"
+)
+
+// createTestTerrain - flat plain of the ground level around the origin
+func createTestTerrain(store game.Store, radius, groundLevel int) {
+	for x := -radius; x <= radius; x++ {
+		for y := -radius; y <= radius; y++ {
+			store.StoreTile(world.Tile{Point: image.Pt(x, y), LandType: world.LandPlain, GroundLevel: groundLevel})
+		}
+	}
+}
+
+func TestLineOfSight(t *testing.T) {
+	player := createTestPlayer("player1")
+	tests := []struct {
+		name    string
+		terrain map[image.Point]world.Tile
+		seen    []image.Point
+		hidden  []image.Point
+	}{
+		{
+			name:   "flat plain",
+			seen:   []image.Point{image.Pt(5, 0), image.Pt(-3, 4)},
+			hidden: []image.Point{image.Pt(6, 0)},
+		},
+		{
+			name: "forest hides what is behind",
+			terrain: map[image.Point]world.Tile{
+				image.Pt(2, 0): {LandType: world.LandForest, GroundLevel: 100},
+			},
+			seen:   []image.Point{image.Pt(2, 0), image.Pt(0, 3)},
+			hidden: []image.Point{image.Pt(3, 0), image.Pt(5, 0)},
+		},
+		{
+			name: "ridge hides the valley",
+			terrain: map[image.Point]world.Tile{
+				image.Pt(0, 2): {LandType: world.LandHill, GroundLevel: 140},
+			},
+			seen:   []image.Point{image.Pt(0, 2)},
+			hidden: []image.Point{image.Pt(0, 4)},
+		},
+		{
+			name: "hill top sees farther",
+			terrain: map[image.Point]world.Tile{
+				image.Pt(0, 0): {LandType: world.LandHill, GroundLevel: 140},
+			},
+			seen: []image.Point{image.Pt(7, 0), image.Pt(0, -7)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := game.NewStoreImpl()
+			createTestTerrain(store, 10, 100)
+			for p, tile := range tt.terrain {
+				tile.Point = p
+				store.StoreTile(tile)
+			}
+			unit := createTestUnit(player.Id, image.Pt(0, 0))
+			unit.ISee = game.NewUnit(player.Id, player.Color, game.NewPF(0, 0), 16, 16).ISee
+
+			sight := game.LineOfSight(store, unit)
+
+			for _, p := range tt.seen {
+				if !slices.Contains(sight, p) {
+					t.Errorf("expected %v to be seen", p)
+				}
+			}
+			for _, p := range tt.hidden {
+				if slices.Contains(sight, p) {
+					t.Errorf("expected %v to be hidden", p)
+				}
+			}
+		})
+	}
+}
+
+func TestLineOfSight_UnloadedTilesDoNotBlock(t *testing.T) {
+	store := game.NewStoreImpl()
+	unit := game.NewUnit(createTestPlayer("player1").Id, color.RGBA{}, game.NewPF(0, 0), 16, 16)
+
+	sight := game.LineOfSight(store, unit)
+
+	if len(sight) != len(unit.VisibleTiles()) {
+		t.Errorf("expected the whole sight circle of %d tiles, got %d", len(unit.VisibleTiles()), len(sight))
+	}
+}
@@ -0,0 +1,193 @@
+package game
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bmcszk/fogofgo/pkg/world"
+	"github.com/google/uuid"
+)
+
+const snapshotExt = ".json.gz"
+
+// Snapshot - state of a match, taken periodically so the match survives server restarts
+type Snapshot struct {
+	Room    RoomIdType
+	Config  RoomConfig
+	State   RoomState
+	Tick    int64
+	Time    time.Time
+	Seats   []Seat
+	Players []Player
+	Units   []Unit
+	Tiles   []SnapshotTile
+}
+
+// Seat - player's place in the match, kept by the server
+type Seat struct {
+	PlayerId     PlayerIdType
+	SessionToken string
+	SpawnPoint   *image.Point // nil when the player got none
+	AI           Difficulty   `json:",omitempty"` // level of the AI playing the seat, empty for humans
+}
+
+// SnapshotTile - tile with the state which does not come back with the map
+type SnapshotTile struct {
+	world.Tile
+	Gathered int
+	Unit     UnitIdType // unit taking or reserving the tile, zero when free
+}
+
+// Snapshot - copy of the players, units and tiles in the store sharing nothing with it,
+// encoded while the game goes on; tiles with the terrain only come back with the map
+func (g *GameLogic) Snapshot() *Snapshot {
+	s := &Snapshot{Time: time.Now()}
+	for _, p := range g.store.GetAllPlayers() {
+		s.Players = append(s.Players, *p)
+	}
+	for _, u := range g.store.GetAllUnits() {
+		s.Units = append(s.Units, u.clone())
+	}
+	for _, t := range g.store.GetAllTiles() {
+		if t.Gathered == 0 && t.Unit == nil {
+			continue
+		}
+		st := SnapshotTile{Gathered: t.Gathered}
+		if t.Tile != nil {
+			st.Tile = *t.Tile
+		}
+		if t.Unit != nil {
+			st.Unit = t.Unit.Id
+		}
+		s.Tiles = append(s.Tiles, st)
+	}
+	return s
+}
+
+// Restore - puts the players, units and tiles of the snapshot into the store
+func (g *GameLogic) Restore(s *Snapshot) *Error {
+	for _, p := range s.Players {
+		g.store.StorePlayer(p)
+	}
+	for i := range s.Units {
+		u := s.Units[i]
+		g.store.StoreUnit(&u)
+	}
+	for _, st := range s.Tiles {
+		t := g.store.StoreTile(st.Tile)
+		t.Gathered = st.Gathered
+		if st.Unit == ZeroUnitId {
+			continue
+		}
+		unit := g.store.GetUnitById(st.Unit)
+		if unit == nil {
+			return NewError(ErrorInvalid, "tile %v taken by unknown unit %s", st.Point, uuid.UUID(st.Unit))
+		}
+		g.store.SetTileUnit(t, unit)
+	}
+	return nil
+}
+
+// SnapshotStore - persistence backend of the matches
+type SnapshotStore interface {
+	Save(snapshot *Snapshot) error
+	LoadAll() ([]*Snapshot, error)
+	Delete(id RoomIdType) error
+}
+
+// FileSnapshots - SnapshotStore keeping a gzipped JSON file per room in the directory,
+// files are replaced atomically so a crash while saving leaves the previous snapshot
+type FileSnapshots struct {
+	dir string
+}
+
+func NewFileSnapshots(dir string) (*FileSnapshots, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("snapshots dir: %w", err)
+	}
+	return &FileSnapshots{dir: dir}, nil
+}
+
+func (f *FileSnapshots) path(id RoomIdType) string {
+	return filepath.Join(f.dir, string(id)+snapshotExt)
+}
+
+func (f *FileSnapshots) Save(snapshot *Snapshot) error {
+	tmp, err := os.CreateTemp(f.dir, string(snapshot.Room)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("save snapshot %s: %w", snapshot.Room, err)
+	}
+	defer func() {
+		// gone after the rename, left behind only when saving failed
+		if err := os.Remove(tmp.Name()); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Error removing snapshot temp file: %v", err)
+		}
+	}()
+
+	zw := gzip.NewWriter(tmp)
+	err = errors.Join(json.NewEncoder(zw).Encode(snapshot), zw.Close(), tmp.Sync(), tmp.Close())
+	if err != nil {
+		return fmt.Errorf("save snapshot %s: %w", snapshot.Room, err)
+	}
+	if err := os.Rename(tmp.Name(), f.path(snapshot.Room)); err != nil {
+		return fmt.Errorf("save snapshot %s: %w", snapshot.Room, err)
+	}
+	return nil
+}
+
+// LoadAll - snapshots of all rooms, the unreadable ones are skipped and reported in the error
+func (f *FileSnapshots) LoadAll() ([]*Snapshot, error) {
+	paths, err := filepath.Glob(filepath.Join(f.dir, "*"+snapshotExt))
+	if err != nil {
+		return nil, fmt.Errorf("load snapshots: %w", err)
+	}
+	var snapshots []*Snapshot
+	var errs []error
+	for _, p := range paths {
+		s, err := f.load(RoomIdType(strings.TrimSuffix(filepath.Base(p), snapshotExt)))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, errors.Join(errs...)
+}
+
+func (f *FileSnapshots) load(id RoomIdType) (*Snapshot, error) {
+	file, err := os.Open(f.path(id))
+	if err != nil {
+		return nil, fmt.Errorf("load snapshot %s: %w", id, err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Error closing snapshot: %v", err)
+		}
+	}()
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("load snapshot %s: %w", id, err)
+	}
+	var s Snapshot
+	if err := json.NewDecoder(zr).Decode(&s); err != nil {
+		return nil, fmt.Errorf("load snapshot %s: %w", id, err)
+	}
+	s.Room = id
+	return &s, nil
+}
+
+func (f *FileSnapshots) Delete(id RoomIdType) error {
+	if err := os.Remove(f.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("delete snapshot %s: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package game_test
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/world"
+)
+
+func TestGameLogic_SnapshotRestore(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	player.Resources = game.Resources{Wood: 30, Stone: 5}
+	store.StorePlayer(player)
+	store.StoreTile(world.Tile{Point: image.Pt(4, 4), LandType: world.LandForest})
+	forest, _ := store.GetTile(image.Pt(4, 4))
+	forest.Gathered = 20
+	store.StoreTile(world.Tile{Point: image.Pt(1, 1), LandType: world.LandPlain})
+	unit := createTestUnit(player.Id, image.Pt(1, 1))
+	unit.Health = 7
+	logic.HandleAction(game.SpawnUnitAction{Type: game.SpawnUnitActionType, Payload: *unit}, nil)
+
+	snapshots, err := game.NewFileSnapshots(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := logic.Snapshot()
+	snapshot.Room = "room1"
+	snapshot.Tick = 42
+	snapshot.Seats = []game.Seat{{PlayerId: player.Id, SessionToken: "token"}}
+	if err := snapshots.Save(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := snapshots.LoadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 1 || saved[0].Room != "room1" || saved[0].Tick != 42 || saved[0].Seats[0].SessionToken != "token" {
+		t.Fatalf("unexpected snapshots %+v", saved)
+	}
+
+	restoredStore := game.NewStoreImpl()
+	if err := game.NewGameLogic(restoredStore).Restore(saved[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	restoredPlayer, ok := restoredStore.GetPlayer(player.Id)
+	if !ok || restoredPlayer.Resources != player.Resources {
+		t.Errorf("expected player with resources %+v, got %+v", player.Resources, restoredPlayer)
+	}
+	restoredUnit := restoredStore.GetUnitById(unit.Id)
+	if restoredUnit == nil || restoredUnit.Health != 7 {
+		t.Fatalf("expected unit with 7 health, got %+v", restoredUnit)
+	}
+	taken, _ := restoredStore.GetTile(image.Pt(1, 1))
+	if taken.Unit != restoredUnit {
+		t.Error("expected the unit to take its tile")
+	}
+	restoredForest, _ := restoredStore.GetTile(image.Pt(4, 4))
+	if restoredForest.Gathered != 20 || restoredForest.LandType != world.LandForest {
+		t.Errorf("expected gathered forest, got %+v", restoredForest)
+	}
+}
+
+func TestGameLogic_Snapshot_SharesNothingWithStore(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	store.StorePlayer(player)
+	store.StoreTile(world.Tile{Point: image.Pt(1, 1), LandType: world.LandPlain})
+	store.StoreTile(world.Tile{Point: image.Pt(5, 5), LandType: world.LandPlain})
+	unit := createTestUnit(player.Id, image.Pt(1, 1))
+	unit.Orders = []game.Order{{Type: game.OrderMove, Point: image.Pt(3, 3)}}
+	logic.HandleAction(game.SpawnUnitAction{Type: game.SpawnUnitActionType, Payload: *unit}, nil)
+
+	snapshot := logic.Snapshot()
+	store.GetUnitById(unit.Id).Orders[0].Point = image.Pt(9, 9)
+
+	if got := snapshot.Units[0].Orders[0].Point; got != image.Pt(3, 3) {
+		t.Errorf("expected the order of the snapshot kept, got %v", got)
+	}
+	if len(snapshot.Tiles) != 1 || snapshot.Tiles[0].Point != image.Pt(1, 1) {
+		t.Errorf("expected only the taken tile, got %+v", snapshot.Tiles)
+	}
+}
+
+func TestFileSnapshots_LoadAllSkipsCorruptFiles(t *testing.T) {
+	dir := t.TempDir()
+	snapshots, err := game.NewFileSnapshots(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := snapshots.Save(&game.Snapshot{Room: "good"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bad.json.gz"), []byte("not gzip"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := snapshots.LoadAll()
+
+	if err == nil {
+		t.Error("expected the corrupt snapshot to be reported")
+	}
+	if len(saved) != 1 || saved[0].Room != "good" {
+		t.Errorf("expected the good snapshot, got %+v", saved)
+	}
+}
+
+func TestFileSnapshots_Delete(t *testing.T) {
+	snapshots, err := game.NewFileSnapshots(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := snapshots.Save(&game.Snapshot{Room: "room1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := snapshots.Delete("room1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := snapshots.Delete("room1"); err != nil {
+		t.Errorf("deleting a missing snapshot should succeed, got %v", err)
+	}
+	if saved, _ := snapshots.LoadAll(); len(saved) != 0 {
+		t.Errorf("expected no snapshots, got %d", len(saved))
+	}
+}
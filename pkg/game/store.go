@@ -2,6 +2,7 @@ package game
 
 import (
 	"image"
+	"math"
 	"sync"
 
 	"github.com/bmcszk/fogofgo/pkg/world"
@@ -12,18 +13,28 @@ type Store interface {
 	GetUnitById(id UnitIdType) *Unit
 	GetAllUnits() []*Unit
 	GetUnitsByPlayerId(id PlayerIdType) []*Unit
+	GetUnitsInRect(rect image.Rectangle) []*Unit
+	GetUnitsInRadius(center PF, r float64) []*Unit
+	RemoveUnit(id UnitIdType)
 
 	GetPlayer(id PlayerIdType) (*Player, bool)
 	GetAllPlayers() []*Player
 	StorePlayer(player Player)
 
 	GetTilesByUnitId(id UnitIdType) []*Tile
+	SetTileUnit(tile *Tile, unit *Unit)
 	StoreTile(tile world.Tile) *Tile
 	GetTile(image.Point) (*Tile, bool)
 	CreateTile(image.Point) *Tile
 	GetTilesByRect(rect image.Rectangle) map[image.Point]*Tile
+	GetAllTiles() []*Tile
+	RemoveTiles(rect image.Rectangle)
 }
 
+// positionMargin - how far a unit may move from the position it was indexed at,
+// the units are indexed again when they take a tile on each step
+const positionMargin = 2
+
 type StoreImpl struct {
 	unitMux   *sync.Mutex
 	tilesMux  *sync.Mutex
@@ -31,16 +42,26 @@ type StoreImpl struct {
 	units     map[UnitIdType]*Unit
 	tiles     map[image.Point]*Tile
 	players   map[PlayerIdType]*Player
+
+	// indexes, guarded by the mutex of what they index
+	playerUnits map[PlayerIdType]map[UnitIdType]*Unit
+	chunkUnits  map[world.ChunkId]map[UnitIdType]*Unit // units by the chunk of their indexed position
+	unitChunk   map[UnitIdType]world.ChunkId
+	unitTiles   map[UnitIdType]map[image.Point]*Tile // tiles taken or reserved by each unit
 }
 
 func NewStoreImpl() *StoreImpl {
 	return &StoreImpl{
-		unitMux:   &sync.Mutex{},
-		tilesMux:  &sync.Mutex{},
-		playerMux: &sync.Mutex{},
-		units:     make(map[UnitIdType]*Unit),
-		tiles:     make(map[image.Point]*Tile),
-		players:   make(map[PlayerIdType]*Player),
+		unitMux:     &sync.Mutex{},
+		tilesMux:    &sync.Mutex{},
+		playerMux:   &sync.Mutex{},
+		units:       make(map[UnitIdType]*Unit),
+		tiles:       make(map[image.Point]*Tile),
+		players:     make(map[PlayerIdType]*Player),
+		playerUnits: make(map[PlayerIdType]map[UnitIdType]*Unit),
+		chunkUnits:  make(map[world.ChunkId]map[UnitIdType]*Unit),
+		unitChunk:   make(map[UnitIdType]world.ChunkId),
+		unitTiles:   make(map[UnitIdType]map[image.Point]*Tile),
 	}
 }
 
@@ -57,19 +78,105 @@ func (s *StoreImpl) GetAllUnits() []*Unit {
 func (s *StoreImpl) GetUnitsByPlayerId(id PlayerIdType) []*Unit {
 	s.unitMux.Lock()
 	defer s.unitMux.Unlock()
-	r := make([]*Unit, 0, len(s.units))
-	for _, u := range s.units {
-		if u.Owner == id {
-			r = append(r, u)
+	r := make([]*Unit, 0, len(s.playerUnits[id]))
+	for _, u := range s.playerUnits[id] {
+		r = append(r, u)
+	}
+	return r
+}
+
+// GetUnitsInRect - units standing in the rect, the max is exclusive
+func (s *StoreImpl) GetUnitsInRect(rect image.Rectangle) []*Unit {
+	return s.unitsNear(rect, func(u *Unit) bool {
+		return u.Position.ImagePoint().In(rect)
+	})
+}
+
+// GetUnitsInRadius - units not farther from the center than r
+func (s *StoreImpl) GetUnitsInRadius(center PF, r float64) []*Unit {
+	rect := image.Rect(
+		int(math.Floor(center.X-r)), int(math.Floor(center.Y-r)),
+		int(math.Ceil(center.X+r))+1, int(math.Ceil(center.Y+r))+1)
+	return s.unitsNear(rect, func(u *Unit) bool {
+		return u.Position.Dist(center) <= r
+	})
+}
+
+// unitsNear - units of the chunks around the rect passing the filter, the chunks hold the indexed positions
+func (s *StoreImpl) unitsNear(rect image.Rectangle, filter func(*Unit) bool) []*Unit {
+	s.unitMux.Lock()
+	defer s.unitMux.Unlock()
+	r := make([]*Unit, 0)
+	for _, id := range world.ChunksIn(rect.Inset(-positionMargin)) {
+		for _, u := range s.chunkUnits[id] {
+			if filter(u) {
+				r = append(r, u)
+			}
 		}
 	}
 	return r
 }
 
+// StoreUnit - adds or replaces the unit, indexed by its owner and position
 func (s *StoreImpl) StoreUnit(unit *Unit) {
 	s.unitMux.Lock()
 	defer s.unitMux.Unlock()
+	if old, ok := s.units[unit.Id]; ok {
+		s.unindexUnit(old)
+	}
 	s.units[unit.Id] = unit
+	owned, ok := s.playerUnits[unit.Owner]
+	if !ok {
+		owned = make(map[UnitIdType]*Unit)
+		s.playerUnits[unit.Owner] = owned
+	}
+	owned[unit.Id] = unit
+	s.indexPosition(unit)
+}
+
+func (s *StoreImpl) RemoveUnit(id UnitIdType) {
+	s.unitMux.Lock()
+	defer s.unitMux.Unlock()
+	if u, ok := s.units[id]; ok {
+		s.unindexUnit(u)
+	}
+	delete(s.units, id)
+}
+
+// indexPosition - files the unit under the chunk of its current position
+func (s *StoreImpl) indexPosition(unit *Unit) {
+	chunk := world.ChunkOf(unit.Position.ImagePoint())
+	if old, ok := s.unitChunk[unit.Id]; ok && old != chunk {
+		s.removeFromChunk(unit.Id, old)
+	}
+	units, ok := s.chunkUnits[chunk]
+	if !ok {
+		units = make(map[UnitIdType]*Unit)
+		s.chunkUnits[chunk] = units
+	}
+	units[unit.Id] = unit
+	s.unitChunk[unit.Id] = chunk
+}
+
+func (s *StoreImpl) unindexUnit(unit *Unit) {
+	if owned, ok := s.playerUnits[unit.Owner]; ok {
+		delete(owned, unit.Id)
+		if len(owned) == 0 {
+			delete(s.playerUnits, unit.Owner)
+		}
+	}
+	if chunk, ok := s.unitChunk[unit.Id]; ok {
+		s.removeFromChunk(unit.Id, chunk)
+		delete(s.unitChunk, unit.Id)
+	}
+}
+
+func (s *StoreImpl) removeFromChunk(id UnitIdType, chunk world.ChunkId) {
+	units := s.chunkUnits[chunk]
+	delete(units, id)
+	if len(units) == 0 {
+		delete(s.chunkUnits, chunk)
+	}
 }
 
 func (s *StoreImpl) GetUnitById(id UnitIdType) *Unit {
@@ -103,18 +210,48 @@ func (s *StoreImpl) StorePlayer(player Player) {
 	s.players[player.Id] = &player
 }
 
+// GetTilesByUnitId - tiles taken or reserved by the unit
 func (s *StoreImpl) GetTilesByUnitId(id UnitIdType) []*Tile {
 	s.tilesMux.Lock()
 	defer s.tilesMux.Unlock()
-	r := make([]*Tile, 0)
-	for _, t := range s.tiles {
-		if t.Unit != nil && t.Unit.Id == id {
-			r = append(r, t)
-		}
+	r := make([]*Tile, 0, len(s.unitTiles[id]))
+	for _, t := range s.unitTiles[id] {
+		r = append(r, t)
 	}
 	return r
 }
 
+// SetTileUnit - the unit takes the tile, nil frees it; the unit is indexed at its current position
+func (s *StoreImpl) SetTileUnit(tile *Tile, unit *Unit) {
+	s.tilesMux.Lock()
+	if tile.Unit != nil {
+		taken := s.unitTiles[tile.Unit.Id]
+		delete(taken, tile.Point)
+		if len(taken) == 0 {
+			delete(s.unitTiles, tile.Unit.Id)
+		}
+	}
+	tile.Unit = unit
+	if unit != nil {
+		taken, ok := s.unitTiles[unit.Id]
+		if !ok {
+			taken = make(map[image.Point]*Tile)
+			s.unitTiles[unit.Id] = taken
+		}
+		taken[tile.Point] = tile
+	}
+	s.tilesMux.Unlock()
+
+	if unit == nil {
+		return
+	}
+	s.unitMux.Lock()
+	defer s.unitMux.Unlock()
+	if s.units[unit.Id] == unit {
+		s.indexPosition(unit)
+	}
+}
+
 func (s *StoreImpl) StoreTile(tile world.Tile) *Tile {
 	s.tilesMux.Lock()
 	defer s.tilesMux.Unlock()
@@ -147,14 +284,15 @@ func (s *StoreImpl) CreateTile(point image.Point) *Tile {
 	})
 }
 
+// GetTilesByRect - tiles of the rect, the max is exclusive, the missing ones are created
 func (s *StoreImpl) GetTilesByRect(rect image.Rectangle) map[image.Point]*Tile {
 	s.tilesMux.Lock()
 	defer s.tilesMux.Unlock()
 	size := rect.Size()
 	area := size.X * size.Y
 	r := make(map[image.Point]*Tile, area)
-	for x := rect.Min.X; x <= rect.Max.X; x++ {
-		for y := rect.Min.Y; y <= rect.Max.Y; y++ {
+	for x := rect.Min.X; x < rect.Max.X; x++ {
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
 			p := image.Pt(x, y)
 			t, ok := s.tiles[p]
 			if !ok {
@@ -167,3 +305,28 @@ func (s *StoreImpl) GetTilesByRect(rect image.Rectangle) map[image.Point]*Tile {
 	}
 	return r
 }
+
+func (s *StoreImpl) GetAllTiles() []*Tile {
+	s.tilesMux.Lock()
+	defer s.tilesMux.Unlock()
+	r := make([]*Tile, 0, len(s.tiles))
+	for _, t := range s.tiles {
+		r = append(r, t)
+	}
+	return r
+}
+
+// RemoveTiles - forgets the tiles of the rect, the max is exclusive.
+// Tiles taken by units or with resources gathered are kept, their state would not come back with the map.
+func (s *StoreImpl) RemoveTiles(rect image.Rectangle) {
+	s.tilesMux.Lock()
+	defer s.tilesMux.Unlock()
+	for x := rect.Min.X; x < rect.Max.X; x++ {
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			p := image.Pt(x, y)
+			if t, ok := s.tiles[p]; ok && t.Unit == nil && t.Gathered == 0 {
+				delete(s.tiles, p)
+			}
+		}
+	}
+}
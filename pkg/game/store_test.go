@@ -0,0 +1,244 @@
+package game_test
+
+import (
+	"image"
+	"testing"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/world"
+)
+
+func TestStoreImpl_RemoveTiles(t *testing.T) {
+	store := game.NewStoreImpl()
+	for x := range 4 {
+		store.StoreTile(world.Tile{Point: image.Pt(x, 0), LandType: world.LandPlain})
+	}
+	player := createTestPlayer("player1")
+	taken, _ := store.GetTile(image.Pt(1, 0))
+	store.SetTileUnit(taken, createTestUnit(player.Id, image.Pt(1, 0)))
+	gathered, _ := store.GetTile(image.Pt(2, 0))
+	gathered.Gathered = 10
+
+	store.RemoveTiles(image.Rect(0, 0, 3, 1))
+
+	for x, kept := range []bool{false, true, true, true} {
+		if _, ok := store.GetTile(image.Pt(x, 0)); ok != kept {
+			t.Errorf("tile %d: expected kept %v, got %v", x, kept, ok)
+		}
+	}
+}
+
+func TestStoreImpl_GetTilesByRect_MaxIsExclusive(t *testing.T) {
+	store := game.NewStoreImpl()
+	store.StoreTile(world.Tile{Point: image.Pt(2, 1), LandType: world.LandForest})
+
+	tiles := store.GetTilesByRect(image.Rect(0, 0, 2, 1))
+
+	if len(tiles) != 2 {
+		t.Errorf("expected 2 tiles, got %d", len(tiles))
+	}
+	for _, p := range []image.Point{image.Pt(2, 0), image.Pt(0, 1), image.Pt(2, 1)} {
+		if _, ok := tiles[p]; ok {
+			t.Errorf("expected the tile %v on the max edge left out", p)
+		}
+	}
+}
+
+func TestStoreImpl_GetTilesByUnitId(t *testing.T) {
+	store := game.NewStoreImpl()
+	player := createTestPlayer("player1")
+	unit := createTestUnit(player.Id, image.Pt(0, 0))
+	store.StoreUnit(unit)
+	store.SetTileUnit(store.CreateTile(image.Pt(0, 0)), unit)
+	next := store.CreateTile(image.Pt(1, 0))
+	store.SetTileUnit(next, unit)
+
+	if tiles := store.GetTilesByUnitId(unit.Id); len(tiles) != 2 {
+		t.Fatalf("expected 2 tiles, got %d", len(tiles))
+	}
+	store.SetTileUnit(next, nil)
+
+	tiles := store.GetTilesByUnitId(unit.Id)
+	if len(tiles) != 1 || tiles[0].Point != image.Pt(0, 0) {
+		t.Errorf("expected the unit on (0,0) only, got %v", tiles)
+	}
+	if next.Unit != nil {
+		t.Error("expected the tile to be free")
+	}
+}
+
+func TestStoreImpl_GetUnitsByPlayerId(t *testing.T) {
+	store := game.NewStoreImpl()
+	player1 := createTestPlayer("player1")
+	player2 := createTestPlayer("player2")
+	unit1 := createTestUnit(player1.Id, image.Pt(0, 0))
+	unit2 := createTestUnit(player1.Id, image.Pt(1, 0))
+	store.StoreUnit(unit1)
+	store.StoreUnit(unit2)
+	store.StoreUnit(createTestUnit(player2.Id, image.Pt(2, 0)))
+
+	// replaced by a copy with another owner
+	captured := *unit2
+	captured.Owner = player2.Id
+	store.StoreUnit(&captured)
+	store.RemoveUnit(unit1.Id)
+
+	if units := store.GetUnitsByPlayerId(player1.Id); len(units) != 0 {
+		t.Errorf("expected no units of player1, got %d", len(units))
+	}
+	if units := store.GetUnitsByPlayerId(player2.Id); len(units) != 2 {
+		t.Errorf("expected 2 units of player2, got %d", len(units))
+	}
+}
+
+func TestStoreImpl_GetUnitsInRect(t *testing.T) {
+	store := game.NewStoreImpl()
+	player := createTestPlayer("player1")
+	inside := createTestUnit(player.Id, image.Pt(-40, 5))
+	outside := createTestUnit(player.Id, image.Pt(-30, 5))
+	store.StoreUnit(inside)
+	store.StoreUnit(outside)
+
+	units := store.GetUnitsInRect(image.Rect(-45, 0, -30, 10))
+
+	if len(units) != 1 || units[0] != inside {
+		t.Errorf("expected the unit at (-40,5) only, got %d units", len(units))
+	}
+}
+
+func TestStoreImpl_GetUnitsInRadius_FollowsMovingUnit(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+	player := createTestPlayer("player1")
+	unit := createTestUnit(player.Id, image.Pt(30, 0))
+	store.StoreUnit(unit)
+
+	// walked into the next chunk
+	for x := 31; x <= 34; x++ {
+		logic.HandleAction(game.MoveStepAction{
+			Type: game.MoveStepActionType,
+			Payload: game.MoveStepPayload{
+				UnitId:   unit.Id,
+				Position: game.NewPF(float64(x), 0),
+				Path:     []image.Point{image.Pt(x, 0)},
+				Step:     1,
+			},
+		}, nil)
+	}
+
+	if units := store.GetUnitsInRadius(game.NewPF(36, 0), 2); len(units) != 1 {
+		t.Errorf("expected the unit near (36,0), got %d units", len(units))
+	}
+	if units := store.GetUnitsInRadius(game.NewPF(30, 0), 2); len(units) != 0 {
+		t.Errorf("expected no unit near (30,0), got %d units", len(units))
+	}
+}
+
+// benchmarkMapSize - width and height of the benchmark map, about half a million tiles
+const benchmarkMapSize = 700
+
+// createBenchmarkStore - large map with the units of four players spread over it, each taking its tile
+func createBenchmarkStore(b *testing.B, units int) (*game.StoreImpl, []game.Player) {
+	b.Helper()
+	store := game.NewStoreImpl()
+	for x := range benchmarkMapSize {
+		for y := range benchmarkMapSize {
+			store.StoreTile(world.Tile{Point: image.Pt(x, y), LandType: world.LandPlain})
+		}
+	}
+	players := []game.Player{
+		createTestPlayer("player1"), createTestPlayer("player2"),
+		createTestPlayer("player3"), createTestPlayer("player4"),
+	}
+	for i := range units {
+		p := image.Pt(i*7919%benchmarkMapSize, i*104729%benchmarkMapSize)
+		unit := createTestUnit(players[i%len(players)].Id, p)
+		store.StoreUnit(unit)
+		tile, _ := store.GetTile(p)
+		if tile.Unit == nil {
+			store.SetTileUnit(tile, unit)
+		}
+	}
+	return store, players
+}
+
+func BenchmarkStoreImpl_GetTilesByUnitId(b *testing.B) {
+	store, _ := createBenchmarkStore(b, 1000)
+	unit := store.GetAllUnits()[0]
+	b.Run("index", func(b *testing.B) {
+		for range b.N {
+			store.GetTilesByUnitId(unit.Id)
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		tiles := store.GetAllTiles()
+		b.ResetTimer()
+		for range b.N {
+			r := make([]*game.Tile, 0)
+			for _, t := range tiles {
+				if t.Unit != nil && t.Unit.Id == unit.Id {
+					r = append(r, t)
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkStoreImpl_GetUnitsByPlayerId(b *testing.B) {
+	store, players := createBenchmarkStore(b, 10000)
+	b.Run("index", func(b *testing.B) {
+		for range b.N {
+			store.GetUnitsByPlayerId(players[0].Id)
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		for range b.N {
+			r := make([]*game.Unit, 0)
+			for _, u := range store.GetAllUnits() {
+				if u.Owner == players[0].Id {
+					r = append(r, u)
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkStoreImpl_GetUnitsInRadius(b *testing.B) {
+	store, _ := createBenchmarkStore(b, 10000)
+	center := game.NewPF(benchmarkMapSize/2, benchmarkMapSize/2)
+	b.Run("index", func(b *testing.B) {
+		for range b.N {
+			store.GetUnitsInRadius(center, 8)
+		}
+	})
+	b.Run("scan", func(b *testing.B) {
+		for range b.N {
+			r := make([]*game.Unit, 0)
+			for _, u := range store.GetAllUnits() {
+				if u.Position.Dist(center) <= 8 {
+					r = append(r, u)
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkGameLogic_HandleAction_MoveStep(b *testing.B) {
+	store, _ := createBenchmarkStore(b, 1000)
+	logic := game.NewGameLogic(store)
+	unit := store.GetAllUnits()[0]
+	start := unit.Position.ImagePoint()
+	path := []image.Point{start, start.Add(image.Pt(1, 0))}
+	b.ResetTimer()
+	for i := range b.N {
+		logic.HandleAction(game.MoveStepAction{
+			Type: game.MoveStepActionType,
+			Payload: game.MoveStepPayload{
+				UnitId:   unit.Id,
+				Position: game.ToPF(path[i%2]),
+				Path:     path,
+				Step:     i%2 + 1,
+			},
+		}, ignore)
+	}
+}
@@ -0,0 +1,75 @@
+package game
+
+import (
+	"math"
+	"slices"
+
+	"github.com/bmcszk/fogofgo/pkg/world"
+)
+
+const (
+	// maxClimb - highest ground level difference a unit can climb in one step
+	maxClimb = 30
+	// climbCost - additional cost per ground level of difference between tiles
+	climbCost = 0.02
+)
+
+var landCosts = map[string]float64{
+	world.LandPlain:  1,
+	world.LandSand:   1.2,
+	world.LandForest: 1.5,
+	world.LandHill:   2,
+}
+
+// Passable - whether the terrain of the tile can be entered at all
+func (t *Tile) Passable() bool {
+	if t.Tile == nil {
+		// not loaded yet
+		return true
+	}
+	if t.WaterLevel != nil && *t.WaterLevel > 0 {
+		return false
+	}
+	switch t.LandType {
+	case world.LandSea, world.LandLake, world.LandRiver, world.LandMountain:
+		return false
+	}
+	return true
+}
+
+// CanEnter - whether the unit's terrain restrictions allow entering the tile
+func (u *Unit) CanEnter(t *Tile) bool {
+	if !t.Passable() {
+		return false
+	}
+	if u == nil || len(u.Terrain) == 0 || t.Tile == nil {
+		return true
+	}
+	return slices.Contains(u.Terrain, t.LandType)
+}
+
+// MoveCost - cost of moving from one tile to the neighbouring one,
+// returns false when the step is not possible
+func MoveCost(from, to *Tile) (float64, bool) {
+	if to == nil {
+		return 1, true
+	}
+	if !to.Passable() {
+		return 0, false
+	}
+	if to.Tile == nil {
+		return 1, true
+	}
+	c, ok := landCosts[to.LandType]
+	if !ok {
+		c = 1
+	}
+	if from != nil && from.Tile != nil {
+		climb := math.Abs(float64(to.GroundLevel - from.GroundLevel))
+		if climb > maxClimb {
+			return 0, false
+		}
+		c += climb * climbCost
+	}
+	return c, true
+}
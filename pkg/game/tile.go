@@ -6,6 +6,12 @@ import (
 
 type Tile struct {
 	*world.Tile
-	Unit    *Unit
-	Visible bool
+	Unit     *Unit    // taking or reserving the tile, set through Store.SetTileUnit to keep the store's index
+	Fog      FogState // what the player knows about the tile, set by the client
+	Gathered int      // resources already taken from the tile
+}
+
+// Visible - whether the tile is in sight of the player
+func (t *Tile) Visible() bool {
+	return t.Fog == FogVisible
 }
@@ -4,36 +4,45 @@ import (
 	"image"
 	"image/color"
 	"math"
+	"slices"
 
 	"github.com/google/uuid"
 )
 
 const (
-	UnitSpeed = 0.1
+	UnitSpeed       = 0.1
+	UnitHealth      = 100
+	UnitAttackRange = 3
+	UnitDamage      = 10
+	UnitCooldown    = 60 // ticks between attacks
+	UnitSize        = 16 // width and height in pixels
 )
 
 var ZeroUnitId = UnitIdType(uuid.Nil)
 
 const defaultSight = 5
 
-var defaultISee []image.Point
+var defaultISee = sightOffsets(defaultSight)
 
-func init() {
-	defaultISee = make([]image.Point, 0, defaultSight)
-	for x := -5; x <= 5; x++ {
-		for y := -5; y <= 5; y++ {
+// sightOffsets - tiles in the circle of the radius around the unit
+func sightOffsets(radius int) []image.Point {
+	r := make([]image.Point, 0)
+	for x := -radius; x <= radius; x++ {
+		for y := -radius; y <= radius; y++ {
 			p := image.Pt(x, y)
-			if Dist(p, ZeroPoint) <= defaultSight {
-				defaultISee = append(defaultISee, image.Pt(x, y))
+			if Dist(p, ZeroPoint) <= float64(radius) {
+				r = append(r, p)
 			}
 		}
 	}
+	return r
 }
 
 type UnitIdType uuid.UUID
 
 type Unit struct {
 	Id       UnitIdType
+	Type     UnitTypeIdType
 	Owner    PlayerIdType
 	Color    color.RGBA
 	Position PF
@@ -43,6 +52,26 @@ type Unit struct {
 	Path     []image.Point
 	Step     int
 	ISee     []image.Point
+	Speed    float64
+	Pace     float64  // speed of the unit's group, zero when moving alone
+	Waiting  int      `json:"-"` // ticks spent waiting for a passing unit
+	Terrain  []string // land types the unit can enter, any passable when empty
+
+	Health      int
+	MaxHealth   int
+	AttackRange float64
+	Damage      int
+	Cooldown    int
+	Reload      int `json:"-"` // ticks until the next attack
+	Target      UnitIdType
+	Gather      *image.Point // resource node the unit gathers from
+	Orders      []Order      // queued orders, the first one is being carried out
+	Cost        Resources
+
+	Building  bool         // static structure, does not move
+	Footprint image.Point  // tiles taken by a building
+	Queue     []Production // units produced by a building
+	Rally     *image.Point // where produced units go
 }
 
 func NewUnit(owner PlayerIdType, c color.RGBA, position PF, width, height int) *Unit {
@@ -53,6 +82,13 @@ func NewUnit(owner PlayerIdType, c color.RGBA, position PF, width, height int) *
 		Position: position,
 		Size:     image.Pt(width, height),
 		ISee:     defaultISee,
+		Speed:    UnitSpeed,
+
+		Health:      UnitHealth,
+		MaxHealth:   UnitHealth,
+		AttackRange: UnitAttackRange,
+		Damage:      UnitDamage,
+		Cooldown:    UnitCooldown,
 	}
 }
 
@@ -60,30 +96,30 @@ func NewUnitId() UnitIdType {
 	return UnitIdType(uuid.New())
 }
 
-func (u *Unit) MoveTo(target image.Point) {
-	if len(u.Path) > 0 && target == u.Path[len(u.Path)-1] {
-		return
-	}
-	path := []image.Point{u.Position.ImagePoint()}
-	path = plan(path, target)
+// FollowPath - replaces the unit's path, the first point is the unit's current tile
+func (u *Unit) FollowPath(path []image.Point) {
 	u.Path = path
 	u.Step = 0
 }
 
+// Destination - last point of the unit's path
+func (u *Unit) Destination() (image.Point, bool) {
+	if len(u.Path) == 0 {
+		return image.Point{}, false
+	}
+	return u.Path[len(u.Path)-1], true
+}
+
+// Moving - whether the unit has steps left on its path
+func (u *Unit) Moving() bool {
+	return len(u.Path) > u.Step
+}
+
 func (u *Unit) Set(unit Unit) {
 	u.Step = unit.Step
 	u.Position = unit.Position
 	u.Path = unit.Path
-}
-
-func plan(path []image.Point, target image.Point) []image.Point {
-	prevStep := path[len(path)-1]
-	if prevStep == target {
-		return path
-	}
-	nextStep := NextStep(prevStep, target)
-	path = append(path, nextStep)
-	return plan(path, target)
+	u.Pace = unit.Pace
 }
 
 func (u *Unit) Update(dispatch DispatchFunc) {
@@ -94,19 +130,74 @@ func (u *Unit) Update(dispatch DispatchFunc) {
 	dx, dy := float64(u.Path[u.Step].X)-u.Position.X, float64(u.Path[u.Step].Y)-u.Position.Y
 	dist := math.Sqrt(dx*dx + dy*dy)
 
-	if dist < 0.1 {
+	// the last bit of the step is not walked past, however fast the unit is
+	if dist <= u.speed() {
 		u.Velocity = NewPF(0, 0)
 		u.Position = ToPF(u.Path[u.Step])
 		u.Step = u.Step + 1
-		dispatch(u.newMoveAction())
+		dispatch(u.NewMoveStepAction())
 	} else {
 		dx, dy = dx/dist, dy/dist
-		u.Velocity = NewPF(dx*UnitSpeed, dy*UnitSpeed)
+		speed := u.speed()
+		u.Velocity = NewPF(dx*speed, dy*speed)
 		u.Position = u.Position.Add(u.Velocity)
 	}
 }
 
-func (u *Unit) newMoveAction() MoveStepAction {
+// speed - the unit keeps the pace of its group
+func (u *Unit) speed() float64 {
+	if u.Pace > 0 && u.Pace < u.Speed {
+		return u.Pace
+	}
+	return u.Speed
+}
+
+// Seen - copy of the unit as the other players see it, its orders are secret
+// and its path is cut to the current and the next step
+func (u *Unit) Seen() Unit {
+	seen := *u
+	seen.Orders = nil
+	seen.Path, seen.Step = nextSteps(u.Path, u.Step)
+	return seen
+}
+
+// clone - copy of the unit sharing no slice or point with it, the simulation changes them in place
+func (u *Unit) clone() Unit {
+	c := *u
+	c.Path = slices.Clone(u.Path)
+	c.ISee = slices.Clone(u.ISee)
+	c.Terrain = slices.Clone(u.Terrain)
+	c.Orders = slices.Clone(u.Orders)
+	c.Queue = slices.Clone(u.Queue)
+	if u.Gather != nil {
+		p := *u.Gather
+		c.Gather = &p
+	}
+	if u.Rally != nil {
+		p := *u.Rally
+		c.Rally = &p
+	}
+	return c
+}
+
+// Seen - the move as the other players see it, they do not learn where the unit is going
+func (p MoveStepPayload) Seen() MoveStepPayload {
+	p.Path, p.Step = nextSteps(p.Path, p.Step)
+	return p
+}
+
+// nextSteps - the step reached last and the next one of the path
+func nextSteps(path []image.Point, step int) ([]image.Point, int) {
+	if len(path) == 0 {
+		return path, step
+	}
+	from := min(max(step-1, 0), len(path)-1)
+	to := min(step+1, len(path))
+	return slices.Clone(path[from:to]), step - from
+}
+
+// NewMoveStepAction - current movement state of the unit
+func (u *Unit) NewMoveStepAction() MoveStepAction {
 	return MoveStepAction{
 		Type: MoveStepActionType,
 		Payload: MoveStepPayload{
@@ -114,6 +205,7 @@ func (u *Unit) newMoveAction() MoveStepAction {
 			Position: u.Position,
 			Path:     u.Path,
 			Step:     u.Step,
+			Pace:     u.Pace,
 		},
 	}
 }
@@ -0,0 +1,164 @@
+package game
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"sync"
+)
+
+type UnitTypeIdType string
+
+// unit types the server needs to start a match
+const (
+	WorkerType       UnitTypeIdType = "worker"
+	HeadquartersType UnitTypeIdType = "hq"
+)
+
+//go:embed units.json
+var defaultUnitTypes []byte
+
+// Weapon - how the unit attacks
+type Weapon struct {
+	Range    float64 `json:"range"`
+	Damage   int     `json:"damage"`
+	Cooldown int     `json:"cooldown"` // ticks between attacks
+}
+
+// UnitType - stats shared by all units of the type
+type UnitType struct {
+	Id        UnitTypeIdType   `json:"id"`
+	Name      string           `json:"name"`
+	Speed     float64          `json:"speed"`
+	Sight     int              `json:"sight"` // radius in tiles
+	Size      int              `json:"size"`  // width and height in tiles
+	Health    int              `json:"health"`
+	Weapon    *Weapon          `json:"weapon,omitempty"`
+	Cost      Resources        `json:"cost"`
+	BuildTime int              `json:"buildTime"`         // ticks to produce the unit
+	Terrain   []string         `json:"terrain,omitempty"` // land types the unit can enter, any passable when empty
+	Building  bool             `json:"building,omitempty"`
+	Produces  []UnitTypeIdType `json:"produces,omitempty"` // unit types produced by the building
+	Builds    []UnitTypeIdType `json:"builds,omitempty"`   // building types the unit can construct
+}
+
+// UnitTypes - registry of the unit types
+type UnitTypes struct {
+	list []UnitType
+	byId map[UnitTypeIdType]*UnitType
+}
+
+func NewUnitTypes(list []UnitType) (*UnitTypes, error) {
+	t := &UnitTypes{
+		list: list,
+		byId: make(map[UnitTypeIdType]*UnitType, len(list)),
+	}
+	for i := range t.list {
+		ut := &t.list[i]
+		if ut.Id == "" {
+			return nil, errors.New("unit type without id")
+		}
+		if _, ok := t.byId[ut.Id]; ok {
+			return nil, fmt.Errorf("unit type %s defined twice", ut.Id)
+		}
+		if ut.Size < 1 {
+			return nil, fmt.Errorf("unit type %s: size must be at least 1", ut.Id)
+		}
+		if !ut.Building && ut.Speed <= 0 {
+			return nil, fmt.Errorf("unit type %s: speed must be positive", ut.Id)
+		}
+		t.byId[ut.Id] = ut
+	}
+	for _, ut := range t.list {
+		for _, id := range slices.Concat(ut.Produces, ut.Builds) {
+			if _, ok := t.byId[id]; !ok {
+				return nil, fmt.Errorf("unit type %s: unknown unit type %s", ut.Id, id)
+			}
+		}
+	}
+	return t, nil
+}
+
+func LoadUnitTypes(r io.Reader) (*UnitTypes, error) {
+	var list []UnitType
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return nil, fmt.Errorf("unit types: %w", err)
+	}
+	return NewUnitTypes(list)
+}
+
+func LoadUnitTypesFile(path string) (*UnitTypes, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unit types: %w", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("Error closing unit types: %v", err)
+		}
+	}()
+	return LoadUnitTypes(f)
+}
+
+// DefaultUnitTypes - unit types shipped with the game, the registry is read-only so it is shared
+var DefaultUnitTypes = sync.OnceValue(func() *UnitTypes {
+	var list []UnitType
+	if err := json.Unmarshal(defaultUnitTypes, &list); err != nil {
+		panic(fmt.Errorf("default unit types: %w", err))
+	}
+	t, err := NewUnitTypes(list)
+	if err != nil {
+		panic(fmt.Errorf("default unit types: %w", err))
+	}
+	return t
+})
+
+func (t *UnitTypes) Get(id UnitTypeIdType) (*UnitType, bool) {
+	ut, ok := t.byId[id]
+	return ut, ok
+}
+
+// All - unit types in the order of definition
+func (t *UnitTypes) All() []UnitType {
+	return slices.Clone(t.list)
+}
+
+// New - unit of the type with all its stats
+func (ut *UnitType) New(owner PlayerIdType, c color.RGBA, position PF) *Unit {
+	u := NewUnit(owner, c, position, ut.Size*UnitSize, ut.Size*UnitSize)
+	u.Type = ut.Id
+	u.Speed = ut.Speed
+	u.ISee = sightOffsets(ut.Sight)
+	u.Health = ut.Health
+	u.MaxHealth = ut.Health
+	u.AttackRange, u.Damage, u.Cooldown = 0, 0, 0
+	if ut.Weapon != nil {
+		u.AttackRange = ut.Weapon.Range
+		u.Damage = ut.Weapon.Damage
+		u.Cooldown = ut.Weapon.Cooldown
+	}
+	u.Cost = ut.Cost
+	u.Terrain = ut.Terrain
+	u.Building = ut.Building
+	if ut.Size > 1 || ut.Building {
+		u.Footprint = image.Pt(ut.Size, ut.Size)
+	}
+	return u
+}
+
+// CanProduce - whether the building of the type produces the unit type
+func (ut *UnitType) CanProduce(id UnitTypeIdType) bool {
+	return slices.Contains(ut.Produces, id)
+}
+
+// CanBuild - whether the unit of the type constructs the building type
+func (ut *UnitType) CanBuild(id UnitTypeIdType) bool {
+	return slices.Contains(ut.Builds, id)
+}
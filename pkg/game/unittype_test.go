@@ -0,0 +1,113 @@
+package game_test
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/world"
+)
+
+func TestDefaultUnitTypes(t *testing.T) {
+	types := game.DefaultUnitTypes()
+
+	for _, id := range []game.UnitTypeIdType{game.WorkerType, game.HeadquartersType} {
+		if _, ok := types.Get(id); !ok {
+			t.Errorf("expected bundled unit type %s", id)
+		}
+	}
+	hq, _ := types.Get(game.HeadquartersType)
+	if !hq.Building || !hq.CanProduce(game.WorkerType) {
+		t.Error("headquarters should be a building producing workers")
+	}
+	worker, _ := types.Get(game.WorkerType)
+	if !worker.CanBuild(game.HeadquartersType) || worker.CanProduce(game.WorkerType) {
+		t.Error("worker should build headquarters and produce nothing")
+	}
+}
+
+func TestLoadUnitTypes_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"malformed":      `[{"id": "worker"`,
+		"missing id":     `[{"name": "Worker", "size": 1}]`,
+		"duplicate":      `[{"id": "worker", "size": 1, "speed": 0.1}, {"id": "worker", "size": 1, "speed": 0.1}]`,
+		"zero size":      `[{"id": "worker", "speed": 0.1}]`,
+		"zero speed":     `[{"id": "worker", "size": 1}]`,
+		"negative speed": `[{"id": "worker", "size": 1, "speed": -0.1}]`,
+		"unknown type":   `[{"id": "hq", "size": 2, "building": true, "produces": ["knight"]}]`,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := game.LoadUnitTypes(strings.NewReader(data)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestUnitType_New(t *testing.T) {
+	types, err := game.LoadUnitTypes(strings.NewReader(`[{
+		"id": "knight", "speed": 0.3, "sight": 2, "size": 1, "health": 80,
+		"weapon": {"range": 2, "damage": 12, "cooldown": 30},
+		"cost": {"wood": 10, "stone": 5}, "buildTime": 100, "terrain": ["plain"]
+	}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	knight, _ := types.Get("knight")
+	player := createTestPlayer("player1")
+
+	unit := knight.New(player.Id, player.Color, game.NewPF(1, 1))
+
+	if unit.Type != "knight" || unit.Speed != 0.3 || unit.Health != 80 || unit.MaxHealth != 80 {
+		t.Errorf("stats not applied: %+v", unit)
+	}
+	if unit.AttackRange != 2 || unit.Damage != 12 || unit.Cooldown != 30 {
+		t.Errorf("weapon not applied: %+v", unit)
+	}
+	if unit.Cost != (game.Resources{Wood: 10, Stone: 5}) {
+		t.Errorf("expected cost applied, got %+v", unit.Cost)
+	}
+	if len(unit.ISee) != 13 {
+		t.Errorf("expected sight radius 2 to cover 13 tiles, got %d", len(unit.ISee))
+	}
+}
+
+func TestPathfinder_FindPath_RespectsUnitTerrain(t *testing.T) {
+	store := game.NewStoreImpl()
+	pathfinder := game.NewPathfinder(store)
+	player := createTestPlayer("player1")
+	scout, _ := game.DefaultUnitTypes().Get("scout")
+	unit := scout.New(player.Id, player.Color, game.NewPF(0, 0))
+	store.StoreUnit(unit)
+	for y := -5; y <= 5; y++ {
+		store.StoreTile(world.Tile{Point: image.Pt(2, y), LandType: world.LandForest})
+	}
+
+	path, ok := pathfinder.FindPath(unit.Id, image.Pt(0, 0), image.Pt(4, 0))
+
+	if !ok {
+		t.Fatal("expected a path around the forest")
+	}
+	for _, p := range path {
+		if p.X == 2 && p.Y >= -5 && p.Y <= 5 {
+			t.Fatalf("scout should not enter the forest, path %v", path)
+		}
+	}
+}
+
+func TestUnit_Update_FastUnitReachesStep(t *testing.T) {
+	player := createTestPlayer("player1")
+	unit := createTestUnit(player.Id, image.Pt(0, 0))
+	unit.Speed = 0.3
+	unit.FollowPath([]image.Point{{0, 0}, {1, 0}})
+
+	for range 10 {
+		unit.Update(func(game.Action) {})
+	}
+
+	if unit.Moving() || unit.Position != game.NewPF(1, 0) {
+		t.Errorf("expected the unit to stop at (1,0), got %v step %d", unit.Position, unit.Step)
+	}
+}
@@ -0,0 +1,173 @@
+package game
+
+import (
+	"image"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MaxCoordinate  = 1 << 20 // highest absolute tile coordinate accepted from clients
+	MaxMapLoadSize = 512     // longest side of the map rect a client can request, in tiles
+)
+
+// Validator - checks actions sent by the clients before the server handles them,
+// clients only express intents on behalf of their own player and units
+type Validator struct {
+	store  Store
+	vision *Vision // what the players know about, targets are not checked against it when nil
+}
+
+func NewValidator(store Store) *Validator {
+	return &Validator{
+		store: store,
+	}
+}
+
+// SetVision - targets of the attacks must be known to the sender
+func (v *Validator) SetVision(vision *Vision) {
+	v.vision = vision
+}
+
+// Validate - nil when the sender is allowed to send the action,
+// the sender is the player bound to the connection, zero before joining
+func (v *Validator) Validate(sender PlayerIdType, action Action) *Error {
+	switch a := action.(type) {
+	case PlayerJoinAction:
+		return v.validateJoin(sender, a.Payload.Id)
+	case PlayerRejoinAction:
+		return v.validateRejoin(sender, a.Payload.Player.Id)
+	}
+
+	if sender == (PlayerIdType{}) {
+		return NewError(ErrorNotJoined, "join the game first")
+	}
+	switch a := action.(type) {
+	case MapLoadAction:
+		return v.validateMapLoad(sender, a.Payload)
+	case MoveStartAction:
+		return v.validateOrder(sender, a.Payload.UnitId, a.Payload.Point)
+	case AttackAction:
+		return v.validateAttack(sender, a.Payload)
+	case GatherAction:
+		return v.validateOrder(sender, a.Payload.UnitId, a.Payload.Point)
+	case BuildAction:
+		return v.validateOrder(sender, a.Payload.UnitId, a.Payload.Point)
+	case QueueProductionAction:
+		return v.validateOwner(sender, a.Payload.BuildingId)
+	case SetRallyPointAction:
+		return v.validateOrder(sender, a.Payload.BuildingId, a.Payload.Point)
+	case OrderAction:
+		if err := validateOrderType(a.Payload.Order.Type); err != nil {
+			return err
+		}
+		return v.validateOrder(sender, a.Payload.UnitId, a.Payload.Order.Point)
+	case StopAction:
+		return v.validateOwner(sender, a.Payload.UnitId)
+	case GroupMoveAction:
+		return v.validateGroupMove(sender, a.Payload)
+	}
+	// state changes are decided by the server
+	return NewError(ErrorNotAllowed, "%s is sent by the server only", action.GetType())
+}
+
+// validateJoin - players already in the game come back with their session token
+func (v *Validator) validateJoin(sender, id PlayerIdType) *Error {
+	if id == (PlayerIdType{}) {
+		return NewError(ErrorInvalid, "player without id")
+	}
+	if sender != (PlayerIdType{}) && sender != id {
+		return NewError(ErrorImpersonation, "connection is bound to another player")
+	}
+	if _, known := v.store.GetPlayer(id); known && sender != id {
+		return NewError(ErrorImpersonation, "player already in the game, rejoin with the session token")
+	}
+	return nil
+}
+
+func (v *Validator) validateRejoin(sender, id PlayerIdType) *Error {
+	if id == (PlayerIdType{}) {
+		return NewError(ErrorInvalid, "player without id")
+	}
+	if sender != (PlayerIdType{}) && sender != id {
+		return NewError(ErrorImpersonation, "connection is bound to another player")
+	}
+	return nil
+}
+
+func (v *Validator) validateMapLoad(sender PlayerIdType, payload MapLoadPayload) *Error {
+	if payload.PlayerId != sender {
+		return NewError(ErrorImpersonation, "map requested for another player")
+	}
+	rect := payload.Rect()
+	if payload.MaxX < payload.MinX || payload.MaxY < payload.MinY {
+		return NewError(ErrorInvalid, "map rect %+v is inverted", payload.WorldRequest)
+	}
+	if !inBounds(rect.Min) || !inBounds(image.Pt(payload.MaxX, payload.MaxY)) {
+		return NewError(ErrorOutOfBounds, "map rect %v", rect)
+	}
+	if rect.Dx() > MaxMapLoadSize || rect.Dy() > MaxMapLoadSize {
+		return NewError(ErrorOutOfBounds, "map rect %v larger than %d tiles", rect, MaxMapLoadSize)
+	}
+	return nil
+}
+
+// validateOrder - order of the sender's unit to a point in the world
+func (v *Validator) validateOrder(sender PlayerIdType, unitId UnitIdType, p image.Point) *Error {
+	if !inBounds(p) {
+		return NewError(ErrorOutOfBounds, "point %v", p)
+	}
+	return v.validateOwner(sender, unitId)
+}
+
+// validateAttack - attack of the sender's unit on a unit the sender sees,
+// the ids of the units out of sight are not enough to chase them
+func (v *Validator) validateAttack(sender PlayerIdType, payload AttackPayload) *Error {
+	target := v.store.GetUnitById(payload.TargetId)
+	if target == nil || v.vision != nil && !v.vision.Knows(sender, target.Id) {
+		return NewError(ErrorUnknownUnit, "target %s", uuid.UUID(payload.TargetId))
+	}
+	return v.validateOwner(sender, payload.UnitId)
+}
+
+// validateGroupMove - order of the sender's units moving together
+func (v *Validator) validateGroupMove(sender PlayerIdType, payload GroupMovePayload) *Error {
+	if len(payload.UnitIds) == 0 || len(payload.UnitIds) > MaxGroupSize {
+		return NewError(ErrorInvalid, "group of %d units, 1 to %d allowed", len(payload.UnitIds), MaxGroupSize)
+	}
+	if !payload.Formation.Valid() {
+		return NewError(ErrorInvalid, "unknown formation %q", payload.Formation)
+	}
+	if err := validateOrderType(payload.Order.Type); err != nil {
+		return err
+	}
+	for _, id := range payload.UnitIds {
+		if err := v.validateOrder(sender, id, payload.Order.Point); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateOrderType(t OrderType) *Error {
+	switch t {
+	case OrderMove, OrderAttackMove, OrderPatrol, OrderHold:
+		return nil
+	}
+	return NewError(ErrorInvalid, "unknown order %q", t)
+}
+
+func (v *Validator) validateOwner(sender PlayerIdType, unitId UnitIdType) *Error {
+	unit := v.store.GetUnitById(unitId)
+	if unit == nil {
+		return NewError(ErrorUnknownUnit, "unit %s", uuid.UUID(unitId))
+	}
+	if unit.Owner != sender {
+		return NewError(ErrorNotOwner, "unit %s", uuid.UUID(unitId))
+	}
+	return nil
+}
+
+func inBounds(p image.Point) bool {
+	return p.X >= -MaxCoordinate && p.X <= MaxCoordinate && p.Y >= -MaxCoordinate && p.Y <= MaxCoordinate
+}
@@ -0,0 +1,226 @@
+package game_test
+
+import (
+	"image"
+	"testing"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+)
+
+func TestValidator_Validate(t *testing.T) {
+	store := game.NewStoreImpl()
+	validator := game.NewValidator(store)
+	player1 := createTestPlayer("player1")
+	player2 := createTestPlayer("player2")
+	store.StorePlayer(player1)
+	store.StorePlayer(player2)
+	own := game.NewUnit(player1.Id, player1.Color, game.NewPF(0, 0), 16, 16)
+	other := game.NewUnit(player2.Id, player2.Color, game.NewPF(5, 5), 16, 16)
+	store.StoreUnit(own)
+	store.StoreUnit(other)
+	nobody := game.PlayerIdType{}
+
+	tests := []struct {
+		name   string
+		sender game.PlayerIdType
+		action game.Action
+		code   game.ErrorCode // empty when accepted
+	}{
+		{
+			name:   "move own unit",
+			sender: player1.Id,
+			action: game.MoveStartAction{Type: game.MoveStartActionType, Payload: game.MoveStartPayload{
+				UnitId: own.Id, Point: image.Pt(3, 3),
+			}},
+		},
+		{
+			name:   "move unit of another player",
+			sender: player1.Id,
+			action: game.MoveStartAction{Type: game.MoveStartActionType, Payload: game.MoveStartPayload{
+				UnitId: other.Id, Point: image.Pt(3, 3),
+			}},
+			code: game.ErrorNotOwner,
+		},
+		{
+			name:   "move unknown unit",
+			sender: player1.Id,
+			action: game.MoveStartAction{Type: game.MoveStartActionType, Payload: game.MoveStartPayload{
+				UnitId: game.NewUnitId(), Point: image.Pt(3, 3),
+			}},
+			code: game.ErrorUnknownUnit,
+		},
+		{
+			name:   "move out of the world",
+			sender: player1.Id,
+			action: game.MoveStartAction{Type: game.MoveStartActionType, Payload: game.MoveStartPayload{
+				UnitId: own.Id, Point: image.Pt(game.MaxCoordinate+1, 0),
+			}},
+			code: game.ErrorOutOfBounds,
+		},
+		{
+			name:   "order before joining",
+			sender: nobody,
+			action: game.MoveStartAction{Type: game.MoveStartActionType, Payload: game.MoveStartPayload{
+				UnitId: own.Id, Point: image.Pt(3, 3),
+			}},
+			code: game.ErrorNotJoined,
+		},
+		{
+			name:   "attack the enemy",
+			sender: player1.Id,
+			action: game.AttackAction{Type: game.AttackActionType, Payload: game.AttackPayload{
+				UnitId: own.Id, TargetId: other.Id,
+			}},
+		},
+		{
+			name:   "queue patrol",
+			sender: player1.Id,
+			action: game.OrderAction{Type: game.OrderActionType, Payload: game.OrderPayload{
+				UnitId: own.Id, Order: game.Order{Type: game.OrderPatrol, Point: image.Pt(3, 3)}, Queue: true,
+			}},
+		},
+		{
+			name:   "unknown order",
+			sender: player1.Id,
+			action: game.OrderAction{Type: game.OrderActionType, Payload: game.OrderPayload{
+				UnitId: own.Id, Order: game.Order{Type: "dance", Point: image.Pt(3, 3)},
+			}},
+			code: game.ErrorInvalid,
+		},
+		{
+			name:   "stop unit of another player",
+			sender: player1.Id,
+			action: game.StopAction{Type: game.StopActionType, Payload: game.StopPayload{UnitId: other.Id}},
+			code:   game.ErrorNotOwner,
+		},
+		{
+			name:   "group move",
+			sender: player1.Id,
+			action: game.GroupMoveAction{Type: game.GroupMoveActionType, Payload: game.GroupMovePayload{
+				UnitIds: []game.UnitIdType{own.Id}, Order: game.Order{Type: game.OrderMove, Point: image.Pt(3, 3)},
+				Formation: game.FormationWedge,
+			}},
+		},
+		{
+			name:   "group move with unit of another player",
+			sender: player1.Id,
+			action: game.GroupMoveAction{Type: game.GroupMoveActionType, Payload: game.GroupMovePayload{
+				UnitIds: []game.UnitIdType{own.Id, other.Id}, Order: game.Order{Type: game.OrderMove, Point: image.Pt(3, 3)},
+			}},
+			code: game.ErrorNotOwner,
+		},
+		{
+			name:   "group move in unknown formation",
+			sender: player1.Id,
+			action: game.GroupMoveAction{Type: game.GroupMoveActionType, Payload: game.GroupMovePayload{
+				UnitIds: []game.UnitIdType{own.Id}, Order: game.Order{Type: game.OrderMove, Point: image.Pt(3, 3)},
+				Formation: "circle",
+			}},
+			code: game.ErrorInvalid,
+		},
+		{
+			name:   "group move too large",
+			sender: player1.Id,
+			action: game.GroupMoveAction{Type: game.GroupMoveActionType, Payload: game.GroupMovePayload{
+				UnitIds: make([]game.UnitIdType, game.MaxGroupSize+1), Order: game.Order{Type: game.OrderMove},
+			}},
+			code: game.ErrorInvalid,
+		},
+		{
+			name:   "spawn unit",
+			sender: player1.Id,
+			action: game.SpawnUnitAction{Type: game.SpawnUnitActionType, Payload: *own},
+			code:   game.ErrorNotAllowed,
+		},
+		{
+			name:   "join as new player",
+			sender: nobody,
+			action: game.PlayerJoinAction{Type: game.PlayerJoinActionType, Payload: createTestPlayer("player3")},
+		},
+		{
+			name:   "join as player in the game",
+			sender: nobody,
+			action: game.PlayerJoinAction{Type: game.PlayerJoinActionType, Payload: player2},
+			code:   game.ErrorImpersonation,
+		},
+		{
+			name:   "join as another player on bound connection",
+			sender: player1.Id,
+			action: game.PlayerRejoinAction{Type: game.PlayerRejoinActionType, Payload: game.PlayerRejoinPayload{
+				Player: player2,
+			}},
+			code: game.ErrorImpersonation,
+		},
+		{
+			name:   "map load",
+			sender: player1.Id,
+			action: game.NewMapLoadAction(image.Rect(-10, -10, 30, 20), player1.Id),
+		},
+		{
+			name:   "map load for another player",
+			sender: player1.Id,
+			action: game.NewMapLoadAction(image.Rect(0, 0, 10, 10), player2.Id),
+			code:   game.ErrorImpersonation,
+		},
+		{
+			name:   "map load too large",
+			sender: player1.Id,
+			action: game.NewMapLoadAction(image.Rect(0, 0, game.MaxMapLoadSize+1, 10), player1.Id),
+			code:   game.ErrorOutOfBounds,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rejection := validator.Validate(tt.sender, tt.action)
+
+			switch {
+			case tt.code == "" && rejection != nil:
+				t.Errorf("expected the action to be accepted, got %s", rejection)
+			case tt.code != "" && rejection == nil:
+				t.Errorf("expected %s rejection", tt.code)
+			case tt.code != "" && rejection.Code != tt.code:
+				t.Errorf("expected %s rejection, got %s", tt.code, rejection)
+			}
+		})
+	}
+}
+
+func TestNewActionRejectedAction(t *testing.T) {
+	action := game.MoveStartAction{Type: game.MoveStartActionType}
+
+	rejected := game.NewActionRejectedAction(action, game.NewError(game.ErrorNotOwner, "unit"))
+
+	if rejected.Payload.Action != game.MoveStartActionType || rejected.Payload.Code != game.ErrorNotOwner {
+		t.Errorf("unexpected payload %+v", rejected.Payload)
+	}
+}
+
+func TestValidator_Validate_AttackNeedsSight(t *testing.T) {
+	store := game.NewStoreImpl()
+	vision := game.NewVision(store)
+	validator := game.NewValidator(store)
+	validator.SetVision(vision)
+	player1 := createTestPlayer("player1")
+	player2 := createTestPlayer("player2")
+	own := game.NewUnit(player1.Id, player1.Color, game.NewPF(0, 0), 16, 16)
+	near := game.NewUnit(player2.Id, player2.Color, game.NewPF(2, 0), 16, 16)
+	far := game.NewUnit(player2.Id, player2.Color, game.NewPF(30, 0), 16, 16)
+	store.StoreUnit(own)
+	store.StoreUnit(near)
+	store.StoreUnit(far)
+	vision.Refresh(player1.Id)
+
+	attack := func(target game.UnitIdType) game.Action {
+		return game.AttackAction{Type: game.AttackActionType, Payload: game.AttackPayload{
+			UnitId: own.Id, TargetId: target,
+		}}
+	}
+
+	if err := validator.Validate(player1.Id, attack(near.Id)); err != nil {
+		t.Errorf("expected the attack on the unit in sight accepted, got %v", err)
+	}
+	err := validator.Validate(player1.Id, attack(far.Id))
+	if err == nil || err.Code != game.ErrorUnknownUnit {
+		t.Errorf("expected %s for the unit out of sight, got %v", game.ErrorUnknownUnit, err)
+	}
+}
@@ -0,0 +1,123 @@
+package game
+
+import (
+	"image"
+)
+
+// VisibleTiles - tiles in the unit's sight radius, regardless of the terrain
+func (u *Unit) VisibleTiles() []image.Point {
+	r := make([]image.Point, 0, len(u.ISee))
+	position := u.Position.ImagePoint()
+	for _, v := range u.ISee {
+		r = append(r, position.Add(v))
+	}
+	return r
+}
+
+// Vision - tracks which units each player can see and has been told about
+type Vision struct {
+	store Store
+	known map[PlayerIdType]map[UnitIdType]bool
+}
+
+func NewVision(store Store) *Vision {
+	return &Vision{
+		store: store,
+		known: make(map[PlayerIdType]map[UnitIdType]bool),
+	}
+}
+
+// Sight - all tiles seen by the player's units
+func (v *Vision) Sight(playerId PlayerIdType) map[image.Point]bool {
+	m := make(map[image.Point]bool)
+	for _, u := range v.store.GetUnitsByPlayerId(playerId) {
+		for _, p := range LineOfSight(v.store, u) {
+			m[p] = true
+		}
+	}
+	return m
+}
+
+// Sees - whether any of the player's units sees the tile
+func (v *Vision) Sees(playerId PlayerIdType, p image.Point) bool {
+	for _, u := range v.store.GetUnitsByPlayerId(playerId) {
+		if SeesTile(v.store, u, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Visible - units the player can see, own units are always visible
+func (v *Vision) Visible(playerId PlayerIdType) []*Unit {
+	sight := v.Sight(playerId)
+	r := make([]*Unit, 0)
+	for _, u := range v.store.GetAllUnits() {
+		if u.Owner == playerId || sight[u.Position.ImagePoint()] {
+			r = append(r, u)
+		}
+	}
+	return r
+}
+
+// Knows - whether the player has been told about the unit and receives its updates
+func (v *Vision) Knows(playerId PlayerIdType, unitId UnitIdType) bool {
+	return v.known[playerId][unitId]
+}
+
+// Reset - forgets what the player has been told and marks currently visible units as known
+func (v *Vision) Reset(playerId PlayerIdType) []*Unit {
+	visible := v.Visible(playerId)
+	known := make(map[UnitIdType]bool, len(visible))
+	for _, u := range visible {
+		known[u.Id] = true
+	}
+	v.known[playerId] = known
+	return visible
+}
+
+// Forget - drops the player's vision state
+func (v *Vision) Forget(playerId PlayerIdType) {
+	delete(v.known, playerId)
+}
+
+// Refresh - recalculates what the player sees, returns actions for units entering and leaving the sight
+func (v *Vision) Refresh(playerId PlayerIdType) []Action {
+	known, ok := v.known[playerId]
+	if !ok {
+		known = make(map[UnitIdType]bool)
+		v.known[playerId] = known
+	}
+
+	r := make([]Action, 0)
+	visible := make(map[UnitIdType]bool)
+	for _, u := range v.Visible(playerId) {
+		visible[u.Id] = true
+		if !known[u.Id] {
+			known[u.Id] = true
+			entered := *u
+			if entered.Owner != playerId {
+				entered = u.Seen()
+			}
+			r = append(r, UnitEnteredVisionAction{
+				Type:    UnitEnteredVisionActionType,
+				Payload: UnitEnteredVisionPayload{Unit: entered},
+			})
+		}
+	}
+	for id := range known {
+		if visible[id] {
+			continue
+		}
+		delete(known, id)
+		if v.store.GetUnitById(id) == nil {
+			// removed from the game, nothing to hide
+			continue
+		}
+		r = append(r, UnitLeftVisionAction{
+			Type:    UnitLeftVisionActionType,
+			Payload: UnitLeftVisionPayload{UnitId: id},
+		})
+	}
+	return r
+}
@@ -0,0 +1,197 @@
+package game_test
+
+import (
+	"image"
+	"testing"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/world"
+)
+
+func TestUnit_VisibleTiles(t *testing.T) {
+	player := createTestPlayer("testplayer")
+	unit := createTestUnit(player.Id, image.Pt(10, 10))
+	unit.ISee = []image.Point{image.Pt(0, 0), image.Pt(1, 0)}
+
+	tiles := unit.VisibleTiles()
+
+	assertPath(t, []image.Point{image.Pt(10, 10), image.Pt(11, 10)}, tiles)
+}
+
+func TestVision_Visible(t *testing.T) {
+	store := game.NewStoreImpl()
+	vision := game.NewVision(store)
+
+	player1 := createTestPlayer("player1")
+	player2 := createTestPlayer("player2")
+	own := game.NewUnit(player1.Id, player1.Color, game.NewPF(0, 0), 16, 16)
+	near := game.NewUnit(player2.Id, player2.Color, game.NewPF(3, 0), 16, 16)
+	far := game.NewUnit(player2.Id, player2.Color, game.NewPF(30, 0), 16, 16)
+	store.StoreUnit(own)
+	store.StoreUnit(near)
+	store.StoreUnit(far)
+
+	visible := vision.Visible(player1.Id)
+
+	if len(visible) != 2 {
+		t.Fatalf("expected 2 visible units, got %d", len(visible))
+	}
+	for _, u := range visible {
+		if u.Id == far.Id {
+			t.Error("unit out of sight should not be visible")
+		}
+	}
+}
+
+func TestVision_Refresh(t *testing.T) {
+	store := game.NewStoreImpl()
+	vision := game.NewVision(store)
+
+	player1 := createTestPlayer("player1")
+	player2 := createTestPlayer("player2")
+	own := game.NewUnit(player1.Id, player1.Color, game.NewPF(0, 0), 16, 16)
+	enemy := game.NewUnit(player2.Id, player2.Color, game.NewPF(30, 0), 16, 16)
+	store.StoreUnit(own)
+	store.StoreUnit(enemy)
+
+	// own unit enters vision
+	actions := vision.Refresh(player1.Id)
+	assertEntered(t, actions, own.Id)
+
+	// nothing changed
+	if actions := vision.Refresh(player1.Id); len(actions) != 0 {
+		t.Errorf("expected no actions, got %v", actions)
+	}
+
+	// enemy comes closer
+	enemy.Position = game.NewPF(2, 0)
+	actions = vision.Refresh(player1.Id)
+	assertEntered(t, actions, enemy.Id)
+	if !vision.Knows(player1.Id, enemy.Id) {
+		t.Error("player should know about the enemy unit")
+	}
+
+	// enemy goes away
+	enemy.Position = game.NewPF(20, 0)
+	actions = vision.Refresh(player1.Id)
+	if len(actions) != 1 {
+		t.Fatalf("expected 1 action, got %d", len(actions))
+	}
+	left, ok := actions[0].(game.UnitLeftVisionAction)
+	if !ok {
+		t.Fatalf("expected UnitLeftVisionAction, got %T", actions[0])
+	}
+	if left.Payload.UnitId != enemy.Id {
+		t.Errorf("expected enemy unit to leave vision, got %v", left.Payload.UnitId)
+	}
+	if vision.Knows(player1.Id, enemy.Id) {
+		t.Error("player should no longer know about the enemy unit")
+	}
+}
+
+func TestVision_Refresh_HidesEnemyDestination(t *testing.T) {
+	store := game.NewStoreImpl()
+	vision := game.NewVision(store)
+
+	player1 := createTestPlayer("player1")
+	player2 := createTestPlayer("player2")
+	store.StoreUnit(game.NewUnit(player1.Id, player1.Color, game.NewPF(0, 0), 16, 16))
+	enemy := game.NewUnit(player2.Id, player2.Color, game.NewPF(3, 0), 16, 16)
+	enemy.FollowPath([]image.Point{{4, 0}, {3, 0}, {2, 0}, {2, 10}, {2, 20}})
+	enemy.Step = 2
+	store.StoreUnit(enemy)
+
+	for _, a := range vision.Refresh(player1.Id) {
+		entered, ok := a.(game.UnitEnteredVisionAction)
+		if !ok || entered.Payload.Unit.Id != enemy.Id {
+			continue
+		}
+		seen := entered.Payload.Unit
+		assertPath(t, []image.Point{{3, 0}, {2, 0}}, seen.Path)
+		if seen.Step != 1 {
+			t.Errorf("expected the next step to be 1, got %d", seen.Step)
+		}
+		return
+	}
+	t.Fatal("expected the enemy to enter the vision")
+}
+
+func TestVision_Sees(t *testing.T) {
+	store := game.NewStoreImpl()
+	vision := game.NewVision(store)
+	player := createTestPlayer("player1")
+	store.StoreUnit(game.NewUnit(player.Id, player.Color, game.NewPF(0, 0), 16, 16))
+	store.StoreTile(world.Tile{Point: image.Pt(2, 0), LandType: world.LandForest})
+
+	if !vision.Sees(player.Id, image.Pt(2, 0)) {
+		t.Error("expected the forest to be seen")
+	}
+	if vision.Sees(player.Id, image.Pt(3, 0)) {
+		t.Error("expected the tile behind the forest to be hidden")
+	}
+	if vision.Sees(player.Id, image.Pt(0, 30)) {
+		t.Error("expected the far tile to be out of sight")
+	}
+}
+
+func TestVision_Reset(t *testing.T) {
+	store := game.NewStoreImpl()
+	vision := game.NewVision(store)
+
+	player := createTestPlayer("player1")
+	unit := game.NewUnit(player.Id, player.Color, game.NewPF(0, 0), 16, 16)
+	store.StoreUnit(unit)
+
+	visible := vision.Reset(player.Id)
+
+	if len(visible) != 1 {
+		t.Fatalf("expected 1 visible unit, got %d", len(visible))
+	}
+	if actions := vision.Refresh(player.Id); len(actions) != 0 {
+		t.Errorf("expected units to be known after reset, got %v", actions)
+	}
+}
+
+func TestGameLogic_HandleAction_UnitVisionActions(t *testing.T) {
+	store := game.NewStoreImpl()
+	logic := game.NewGameLogic(store)
+
+	player := createTestPlayer("testplayer")
+	unit := createTestUnit(player.Id, image.Pt(4, 4))
+
+	logic.HandleAction(game.UnitEnteredVisionAction{
+		Type:    game.UnitEnteredVisionActionType,
+		Payload: game.UnitEnteredVisionPayload{Unit: *unit},
+	}, nil)
+
+	tile, ok := store.GetTile(image.Pt(4, 4))
+	if !ok || tile.Unit == nil {
+		t.Fatal("unit should be placed on its tile")
+	}
+
+	logic.HandleAction(game.UnitLeftVisionAction{
+		Type:    game.UnitLeftVisionActionType,
+		Payload: game.UnitLeftVisionPayload{UnitId: unit.Id},
+	}, nil)
+
+	if store.GetUnitById(unit.Id) != nil {
+		t.Error("unit should be removed from the store")
+	}
+	if tile.Unit != nil {
+		t.Error("tile should be released")
+	}
+}
+
+func assertEntered(t *testing.T, actions []game.Action, id game.UnitIdType) {
+	t.Helper()
+	if len(actions) != 1 {
+		t.Fatalf("expected 1 action, got %d", len(actions))
+	}
+	entered, ok := actions[0].(game.UnitEnteredVisionAction)
+	if !ok {
+		t.Fatalf("expected UnitEnteredVisionAction, got %T", actions[0])
+	}
+	if entered.Payload.Unit.Id != id {
+		t.Errorf("expected unit %v to enter vision, got %v", id, entered.Payload.Unit.Id)
+	}
+}
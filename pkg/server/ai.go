@@ -0,0 +1,141 @@
+package server
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math/rand/v2"
+
+	"github.com/bmcszk/fogofgo/pkg/ai"
+	"github.com/bmcszk/fogofgo/pkg/bot"
+	"github.com/bmcszk/fogofgo/pkg/comm"
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/google/uuid"
+)
+
+// aiTicks - ticks between the moves of the AI opponents, about as often as a quick human clicks
+const aiTicks = 30
+
+// aiColors - colors of the AI opponents by seat
+var aiColors = []color.RGBA{
+	{200, 40, 40, 255},
+	{40, 160, 40, 255},
+	{40, 80, 200, 255},
+	{200, 160, 40, 255},
+	{160, 40, 200, 255},
+	{40, 180, 180, 255},
+}
+
+// aiPeer - AI opponent hosted by the room, it gets the same actions a remote client would
+// and its orders go through the room's pipeline
+type aiPeer struct {
+	bot    *bot.Bot
+	agent  *ai.Player
+	outbox *outbox
+}
+
+// outbox - actions of the AI waiting for the room's loop
+type outbox struct {
+	actions []game.Action
+}
+
+func (o *outbox) Send(action game.Action) error {
+	o.actions = append(o.actions, action)
+	return nil
+}
+
+// Send - copies the action through the wire codec, the AI must not share the units of the server
+func (p *aiPeer) Send(action game.Action) error {
+	codec := comm.BinaryCodec{}
+	data, err := codec.Marshal(action)
+	if err != nil {
+		return fmt.Errorf("ai %s: %w", action.GetType(), err)
+	}
+	copied, err := codec.Unmarshal(data)
+	if err != nil {
+		return fmt.Errorf("ai %s: %w", action.GetType(), err)
+	}
+	return p.bot.Handle(copied, p.agent)
+}
+
+func (p *aiPeer) playerId() game.PlayerIdType {
+	return p.bot.PlayerId()
+}
+
+// bind - the AI always plays its own player
+func (p *aiPeer) bind(game.PlayerIdType) {}
+
+// addAI - seats a new AI opponent, must be called before run
+func (r *room) addAI(level game.Difficulty) {
+	player := game.Player{
+		Id:    game.PlayerIdType(uuid.New()),
+		Name:  fmt.Sprintf("AI %d (%s)", len(r.ais)+1, level),
+		Color: aiColors[len(r.ais)%len(aiColors)],
+	}
+	r.host(player, level, "")
+}
+
+// host - seats the AI opponent and joins it to the game, resuming its session when it has a token
+func (r *room) host(player game.Player, level game.Difficulty, sessionToken string) {
+	if !r.seat(player.Id) {
+		log.Printf("room %s: no seat for %s", r.id, player.Name)
+		return
+	}
+	out := &outbox{}
+	p := &aiPeer{
+		bot: bot.New(bot.Config{
+			Player:       player,
+			Room:         r.id,
+			SessionToken: sessionToken,
+		}, out),
+		agent:  ai.New(level, rand.Uint64()),
+		outbox: out,
+	}
+	r.ais[player.Id] = p
+	p.bot.Join()
+	r.flush(p)
+	log.Printf("room %s: %s joined", r.id, player.Name)
+}
+
+// playAI - lets every AI opponent make its move
+func (r *room) playAI() {
+	if r.game.tick%aiTicks != 0 || r.currentState() != game.RoomRunning {
+		return
+	}
+	for _, p := range r.ais {
+		p.bot.Step(p.agent)
+		r.flush(p)
+	}
+}
+
+// flush - processes the actions of the AI like the ones of a remote client
+func (r *room) flush(p *aiPeer) {
+	for len(p.outbox.actions) > 0 {
+		action := p.outbox.actions[0]
+		p.outbox.actions = p.outbox.actions[1:]
+		r.processAction(p, action)
+	}
+}
+
+// aiSeats - marks the seats of the snapshot played by the AI
+func (r *room) aiSeats(s *game.Snapshot) {
+	for i, seat := range s.Seats {
+		if p, ok := r.ais[seat.PlayerId]; ok {
+			s.Seats[i].AI = p.agent.Level()
+		}
+	}
+}
+
+// restoreAI - brings back the AI opponents of the restored match, must be called before run
+func (r *room) restoreAI(s *game.Snapshot) {
+	for _, seat := range s.Seats {
+		if seat.AI == "" {
+			continue
+		}
+		player, ok := r.game.store.GetPlayer(seat.PlayerId)
+		if !ok {
+			continue
+		}
+		r.host(*player, seat.AI, seat.SessionToken)
+	}
+}
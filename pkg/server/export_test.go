@@ -0,0 +1,13 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+// SetEmptyRoomTTL - shortens the wait of the empty rooms, called before the test server starts so it is restored after
+func SetEmptyRoomTTL(t testing.TB, ttl time.Duration) {
+	previous := emptyRoomTTL
+	emptyRoomTTL = ttl
+	t.Cleanup(func() { emptyRoomTTL = previous })
+}
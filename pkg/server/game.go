@@ -0,0 +1,339 @@
+package server
+
+import (
+	"image"
+	"log"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/world"
+	"github.com/google/uuid"
+)
+
+type serverGame struct {
+	*game.GameLogic
+	store       game.Store
+	vision      *game.Vision
+	validator   *game.Validator
+	fetch       fetchFunc
+	spawnPoints []image.Point
+	starting    map[image.Point]game.PlayerIdType // starting point taken by each player
+	sessions    map[game.PlayerIdType]string      // session token of each player
+	recorder    *game.Recorder                    // replay log of the match, nil when not recorded
+	tick        int64
+}
+
+func newServerGame(store game.Store, fetch fetchFunc, spawnPoints []image.Point,
+	types *game.UnitTypes) *serverGame {
+	// the simulation never waits for the clients or the world service to load the map
+	terrain := newTerrainStore(store, fetch)
+	for _, p := range spawnPoints {
+		// the bases are on the map before the players join
+		terrain.load(world.ChunkOf(p))
+	}
+	store = terrain
+	logic := game.NewGameLogic(store)
+	logic.SetUnitTypes(types)
+	vision := game.NewVision(store)
+	validator := game.NewValidator(store)
+	validator.SetVision(vision)
+	return &serverGame{
+		store:       store,
+		GameLogic:   logic,
+		vision:      vision,
+		validator:   validator,
+		fetch:       fetch,
+		spawnPoints: spawnPoints,
+		starting:    make(map[image.Point]game.PlayerIdType),
+		sessions:    make(map[game.PlayerIdType]string),
+	}
+}
+
+func (g *serverGame) HandleAction(action game.Action, dispatch game.DispatchFunc) {
+	log.Printf("server handle %s", action.GetType())
+	if a, ok := action.(game.PlayerJoinAction); ok {
+		action = g.withStockpile(a)
+	}
+	g.record(action)
+	g.GameLogic.HandleAction(action, dispatch)
+	var err *game.Error
+	switch a := action.(type) {
+	case game.PlayerJoinAction:
+		err = g.handlePlayerJoinAction(a, dispatch)
+	case game.MapLoadAction:
+		g.handleMapLoadAction(a, dispatch)
+	case game.MoveStartAction:
+		g.handleMoveStartAction(a, dispatch)
+	case game.BuildAction:
+		err = g.handleBuildAction(a, dispatch)
+	case game.QueueProductionAction:
+		err = g.handleQueueProductionAction(a, dispatch)
+	}
+	if err != nil {
+		// the sender learns why its action did nothing
+		log.Printf("%s failed: %s", action.GetType(), err)
+		dispatch(game.NewErrorAction(game.PlayerIdType{}, action, err))
+	}
+}
+
+// record - appends the accepted action to the replay log
+func (g *serverGame) record(action game.Action) {
+	if g.recorder == nil {
+		return
+	}
+	if err := g.recorder.Record(g.tick, action); err != nil {
+		log.Println(err)
+	}
+}
+
+func (g *serverGame) handlePlayerJoinAction(action game.PlayerJoinAction, dispatch game.DispatchFunc) *game.Error {
+	player := action.Payload
+	id := player.Id
+	_, existing := g.store.GetPlayer(id)
+	g.store.StorePlayer(player)
+
+	successAction := game.PlayerJoinSuccessAction{
+		Type: game.PlayerJoinSuccessActionType,
+		Payload: game.PlayerJoinSuccessPayload{
+			PlayerId:     player.Id,
+			Units:        make([]game.Unit, 0),
+			Players:      make([]game.Player, 0),
+			SessionToken: g.session(id),
+			UnitTypes:    g.UnitTypes().All(),
+		},
+	}
+	// only units in the player's sight, the rest comes with vision updates
+	for _, unit := range g.vision.Reset(id) {
+		u := *unit
+		if u.Owner != id {
+			u = unit.Seen()
+		}
+		successAction.Payload.Units = append(successAction.Payload.Units, u)
+	}
+	for _, p := range g.store.GetAllPlayers() {
+		other := *p
+		if other.Id != id {
+			// stockpiles of other players are secret
+			other.Resources = game.Resources{}
+		}
+		successAction.Payload.Players = append(successAction.Payload.Players, other)
+	}
+	dispatch(successAction)
+
+	// unit spawn only for new player
+	if existing {
+		return nil
+	}
+
+	startingP, ok := g.takeSpawnPoint(id)
+	if !ok {
+		return game.NewError(game.ErrorNoSpawnPoint, "no spawn point left for player %s", uuid.UUID(id))
+	}
+	// the headquarters is given, the first worker is paid from the starting stockpile
+	hqType, _ := g.UnitTypes().Get(game.HeadquartersType)
+	hq := hqType.New(id, player.Color, game.ToPF(startingP))
+	hq.Cost = game.Resources{}
+	if err := g.spawn(hq, dispatch); err != nil {
+		return err
+	}
+	workerType, _ := g.UnitTypes().Get(game.WorkerType)
+	worker := workerType.New(id, player.Color, hq.Position)
+	p, ok := g.FreeTileAround(hq, worker)
+	if !ok {
+		return game.NewError(game.ErrorPlacement, "no free tile around the headquarters")
+	}
+	worker.Position = game.ToPF(p)
+	return g.spawn(worker, dispatch)
+}
+
+// withStockpile - the stockpile is kept by the server, whatever the client claims
+func (g *serverGame) withStockpile(action game.PlayerJoinAction) game.PlayerJoinAction {
+	action.Payload.Resources = game.StartingResources
+	if stored, ok := g.store.GetPlayer(action.Payload.Id); ok {
+		action.Payload.Resources = stored.Resources
+	}
+	return action
+}
+
+// spawn - pays for the unit from its owner's stockpile and spawns it
+func (g *serverGame) spawn(unit *game.Unit, dispatch game.DispatchFunc) *game.Error {
+	if err := g.pay(unit.Owner, unit.Cost, dispatch); err != nil {
+		return err
+	}
+	dispatch(game.SpawnUnitAction{
+		Type:    game.SpawnUnitActionType,
+		Payload: *unit,
+	})
+	return nil
+}
+
+// pay - takes the cost from the player's stockpile, fails when the player cannot afford it
+func (g *serverGame) pay(id game.PlayerIdType, cost game.Resources, dispatch game.DispatchFunc) *game.Error {
+	if cost == (game.Resources{}) {
+		return nil
+	}
+	player, ok := g.store.GetPlayer(id)
+	if !ok || !player.Resources.Covers(cost) {
+		return game.NewError(game.ErrorCannotAfford, "cost %+v not covered", cost)
+	}
+	dispatch(game.ResourcesSpentAction{
+		Type: game.ResourcesSpentActionType,
+		Payload: game.ResourcesSpentPayload{
+			PlayerId:  id,
+			Resources: cost,
+		},
+	})
+	return nil
+}
+
+// handleBuildAction - places the building next to the builder when the player can afford it
+func (g *serverGame) handleBuildAction(action game.BuildAction, dispatch game.DispatchFunc) *game.Error {
+	builder := g.store.GetUnitById(action.Payload.UnitId)
+	if builder == nil {
+		return nil
+	}
+	builderType, ok := g.UnitTypes().Get(builder.Type)
+	if !ok || !builderType.CanBuild(action.Payload.UnitType) {
+		return game.NewError(game.ErrorCannotBuild, "%s cannot build %s", builder.Type, action.Payload.UnitType)
+	}
+	buildingType, _ := g.UnitTypes().Get(action.Payload.UnitType)
+	p := action.Payload.Point
+	if game.Dist(builder.Position.ImagePoint(), p) > game.BuildRange {
+		return game.NewError(game.ErrorOutOfRange, "%v too far from the builder", p)
+	}
+	building := buildingType.New(builder.Owner, builder.Color, game.ToPF(p))
+	if !g.CanPlace(building) {
+		return game.NewError(game.ErrorPlacement, "%v is taken", p)
+	}
+	return g.spawn(building, dispatch)
+}
+
+// handleQueueProductionAction - pays for the unit and adds it to the building's queue
+func (g *serverGame) handleQueueProductionAction(action game.QueueProductionAction,
+	dispatch game.DispatchFunc) *game.Error {
+	building := g.store.GetUnitById(action.Payload.BuildingId)
+	if building == nil {
+		return nil
+	}
+	buildingType, ok := g.UnitTypes().Get(building.Type)
+	if !ok || !buildingType.CanProduce(action.Payload.UnitType) {
+		return game.NewError(game.ErrorCannotProduce, "%s cannot produce %s", building.Type, action.Payload.UnitType)
+	}
+	if len(building.Queue) >= game.MaxQueue {
+		return game.NewError(game.ErrorQueueFull, "%d units queued", len(building.Queue))
+	}
+	unitType, _ := g.UnitTypes().Get(action.Payload.UnitType)
+	if err := g.pay(building.Owner, unitType.Cost, dispatch); err != nil {
+		return err
+	}
+	dispatch(game.ProductionQueuedAction{
+		Type: game.ProductionQueuedActionType,
+		Payload: game.ProductionQueuedPayload{
+			BuildingId: building.Id,
+			Production: game.Production{Type: unitType.Id, Time: unitType.BuildTime},
+		},
+	})
+	return nil
+}
+
+// handleMoveStartAction - publishes the path planned for the unit
+func (g *serverGame) handleMoveStartAction(action game.MoveStartAction, dispatch game.DispatchFunc) {
+	unit := g.store.GetUnitById(action.Payload.UnitId)
+	if unit == nil {
+		return
+	}
+	dispatch(unit.NewMoveStepAction())
+}
+
+func (g *serverGame) takeSpawnPoint(id game.PlayerIdType) (image.Point, bool) {
+	for _, sp := range g.spawnPoints {
+		if _, taken := g.starting[sp]; !taken {
+			g.starting[sp] = id
+			return sp, true
+		}
+	}
+	return image.Point{}, false
+}
+
+// session - token allowing the player to resume the game after the connection is lost
+func (g *serverGame) session(id game.PlayerIdType) string {
+	token, ok := g.sessions[id]
+	if !ok {
+		token = uuid.NewString()
+		g.sessions[id] = token
+	}
+	return token
+}
+
+// snapshot - state of the match with the seats of the players, the room fills in its own part
+func (g *serverGame) snapshot() *game.Snapshot {
+	s := g.Snapshot()
+	s.Tick = g.tick
+	spawnPoints := make(map[game.PlayerIdType]image.Point, len(g.starting))
+	for p, id := range g.starting {
+		spawnPoints[id] = p
+	}
+	for id, token := range g.sessions {
+		seat := game.Seat{PlayerId: id, SessionToken: token}
+		if p, ok := spawnPoints[id]; ok {
+			seat.SpawnPoint = &p
+		}
+		s.Seats = append(s.Seats, seat)
+	}
+	return s
+}
+
+// restore - continues the match from the snapshot, the game must be new
+func (g *serverGame) restore(s *game.Snapshot) *game.Error {
+	if err := g.Restore(s); err != nil {
+		return err
+	}
+	g.tick = s.Tick
+	for _, seat := range s.Seats {
+		g.sessions[seat.PlayerId] = seat.SessionToken
+		if seat.SpawnPoint != nil {
+			g.starting[*seat.SpawnPoint] = seat.PlayerId
+		}
+	}
+	return nil
+}
+
+// canResume - unknown players start a new session, known ones need their token
+func (g *serverGame) canResume(payload game.PlayerRejoinPayload) bool {
+	token, ok := g.sessions[payload.Player.Id]
+	return !ok || token == payload.SessionToken
+}
+
+// canJoin - a plain join starts a new session, players holding one come back with their token,
+// only the connection already bound to the player joins it again; a connection plays one player
+func (g *serverGame) canJoin(sender, id game.PlayerIdType) *game.Error {
+	if _, ok := g.sessions[id]; ok && sender != id {
+		return game.NewError(game.ErrorBadSession, "rejoin with the session token")
+	}
+	if sender != (game.PlayerIdType{}) && sender != id {
+		return game.NewError(game.ErrorImpersonation, "connection plays another player")
+	}
+	return nil
+}
+
+// handleMapLoadAction - answers with a MapLoadSuccess per chunk overlapping the requested rect
+// as each one arrives, the chunk's bounds tell the client which chunk arrived; a chunk which fails
+// to load is reported on its own
+func (g *serverGame) handleMapLoadAction(action game.MapLoadAction, dispatch game.DispatchFunc) {
+	p := action.Payload
+	g.fetch(world.ChunksIn(p.Rect()), func(id world.ChunkId, tiles []world.Tile, err error) {
+		if err != nil {
+			log.Printf("%s failed: chunk %v: %s", action.GetType(), id, err)
+			dispatch(game.NewErrorAction(game.PlayerIdType{}, action,
+				game.NewError(game.ErrorMapLoad, "chunk %v: %s", id, err)))
+			return
+		}
+		r := id.Request()
+		dispatch(game.MapLoadSuccessAction{
+			Type: game.MapLoadSuccessActionType,
+			Payload: game.MapLoadSuccessPayload{
+				WorldResponse: world.WorldResponse{MinX: r.MinX, MinY: r.MinY, MaxX: r.MaxX, MaxY: r.MaxY, Tiles: tiles},
+				PlayerId:      p.PlayerId,
+			},
+		})
+	})
+}
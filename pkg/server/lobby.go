@@ -0,0 +1,261 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/bmcszk/fogofgo/pkg/comm"
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/world"
+)
+
+// lobby - entry point of the connections, hosts the rooms
+type lobby struct {
+	mux       sync.Mutex
+	rooms     map[game.RoomIdType]*room
+	chunks    *world.ChunkCache // map chunks shared by the rooms
+	types     *game.UnitTypes
+	recordDir string             // directory of the replay files, matches are not recorded when empty
+	snapshots game.SnapshotStore // persistence of the matches, nil when not persisted
+}
+
+// connection - client connection and the room it is bound to
+type connection struct {
+	client *comm.Client
+	out    *sender // writes to the client, shared by the lobby and the rooms
+	room   *room
+	peer   *remote // the connection in its room, a new one for each room
+}
+
+func newLobby(chunks *world.ChunkCache, types *game.UnitTypes, recordDir string) *lobby {
+	return &lobby{
+		rooms:     make(map[game.RoomIdType]*room),
+		chunks:    chunks,
+		types:     types,
+		recordDir: recordDir,
+	}
+}
+
+func (l *lobby) handleConnections(w http.ResponseWriter, r *http.Request) {
+	// Upgrade initial GET request to a websocket
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade connection: %v", err)
+		return
+	}
+	// Make sure we close the connection when the function returns
+	defer func() {
+		if err := ws.Close(); err != nil {
+			log.Printf("Error closing websocket: %v", err)
+		}
+	}()
+
+	// Register our new client
+	client := comm.NewClient(ws)
+	conn := &connection{client: client, out: newSender(client)}
+	defer conn.out.stop()
+
+	for conn.client.Connected {
+		action, err := conn.client.HandleInMessages()
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		l.processAction(conn, action)
+	}
+	l.leave(conn)
+}
+
+func (l *lobby) processAction(conn *connection, action game.Action) {
+	switch a := action.(type) {
+	case game.RoomListAction:
+		l.send(conn, game.RoomListSuccessAction{
+			Type:    game.RoomListSuccessActionType,
+			Payload: game.RoomListSuccessPayload{Rooms: l.list()},
+		})
+	case game.RoomCreateAction:
+		if rejection := a.Payload.Validate(); rejection != nil {
+			log.Printf("rejected %s: %s", action.GetType(), rejection)
+			l.send(conn, game.Reply(game.NewActionRejectedAction(action, rejection), action.GetEnvelope().RequestId, 0))
+			return
+		}
+		l.join(conn, l.createRoom(a.Payload))
+	case game.RoomJoinAction:
+		l.joinById(conn, a.Payload.RoomId)
+	case game.RoomLeaveAction:
+		l.leave(conn)
+	case game.PlayerJoinAction:
+		l.enter(conn, a.Payload.Id, action)
+	case game.PlayerRejoinAction:
+		l.enter(conn, a.Payload.Player.Id, action)
+	default:
+		if conn.room == nil {
+			log.Printf("ignoring %s outside of a room", action.GetType())
+			return
+		}
+		conn.room.push(intent{client: conn.peer, action: action})
+	}
+}
+
+// enter - seats the player in the room, players outside of any room get a quick match
+func (l *lobby) enter(conn *connection, playerId game.PlayerIdType, action game.Action) {
+	if conn.room == nil {
+		l.join(conn, l.quickMatch())
+	}
+	if conn.room == nil {
+		return
+	}
+	if !conn.room.seatFree(playerId) {
+		l.fail(conn, conn.room.id, "room is full")
+		l.leave(conn)
+		return
+	}
+	conn.room.push(intent{client: conn.peer, action: action})
+}
+
+func (l *lobby) joinById(conn *connection, id game.RoomIdType) {
+	l.mux.Lock()
+	r, ok := l.rooms[id]
+	l.mux.Unlock()
+	if !ok {
+		l.fail(conn, id, "room not found")
+		return
+	}
+	l.join(conn, r)
+}
+
+// join - binds the connection to the room, leaving the previous one
+func (l *lobby) join(conn *connection, r *room) {
+	if conn.room == r {
+		return
+	}
+	l.leave(conn)
+	if r.currentState() == game.RoomFinished || !r.addMember() {
+		l.fail(conn, r.id, "room is closed")
+		return
+	}
+	conn.room = r
+	conn.peer = &remote{sender: conn.out}
+	l.send(conn, game.RoomJoinSuccessAction{
+		Type:    game.RoomJoinSuccessActionType,
+		Payload: r.info(),
+	})
+}
+
+// leave - unbinds the connection from its room, the player keeps the seat to come back
+func (l *lobby) leave(conn *connection) {
+	if conn.room == nil {
+		return
+	}
+	conn.room.push(intent{client: conn.peer, disconnected: true})
+	conn.room.removeMember()
+	conn.room = nil
+}
+
+func (l *lobby) createRoom(config game.RoomConfig) *room {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return l.createRoomLocked(config)
+}
+
+func (l *lobby) createRoomLocked(config game.RoomConfig) *room {
+	r := newRoom(game.NewRoomId(), config.WithDefaults(), l.chunks, l.types, l.remove)
+	r.snapshots = l.snapshots
+	if l.recordDir != "" {
+		if err := r.record(l.recordDir); err != nil {
+			log.Println(err)
+		}
+	}
+	for _, level := range r.config.AI {
+		r.addAI(level)
+	}
+	l.rooms[r.id] = r
+	go r.run(tickRate)
+	log.Printf("room %s created: %s", r.id, r.config.Name)
+	return r
+}
+
+// quickMatch - first room with a free seat, a new one when all are taken
+func (l *lobby) quickMatch() *room {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	for _, r := range l.rooms {
+		if r.open() {
+			return r
+		}
+	}
+	return l.createRoomLocked(game.RoomConfig{})
+}
+
+// persist - restores the rooms saved by the previous run and keeps saving them
+func (l *lobby) persist(snapshots game.SnapshotStore) error {
+	l.snapshots = snapshots
+	saved, err := snapshots.LoadAll()
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	for _, s := range saved {
+		r := newRoom(s.Room, s.Config, l.chunks, l.types, l.remove)
+		r.snapshots = snapshots
+		if err := r.restore(s); err != nil {
+			log.Println(err)
+			continue
+		}
+		// replays start with the match, restored rooms are not recorded
+		l.rooms[r.id] = r
+		go r.run(tickRate)
+		log.Printf("room %s restored at tick %d: %s", r.id, s.Tick, r.config.Name)
+	}
+	return err
+}
+
+// shutdown - stops all rooms, the persisted ones are saved
+func (l *lobby) shutdown() {
+	l.mux.Lock()
+	rooms := make([]*room, 0, len(l.rooms))
+	for _, r := range l.rooms {
+		rooms = append(rooms, r)
+	}
+	l.mux.Unlock()
+	for _, r := range rooms {
+		r.shutdown()
+	}
+	log.Printf("%d rooms stopped", len(rooms))
+}
+
+func (l *lobby) remove(r *room) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	delete(l.rooms, r.id)
+}
+
+func (l *lobby) list() []game.RoomInfo {
+	l.mux.Lock()
+	r := make([]game.RoomInfo, 0, len(l.rooms))
+	for _, room := range l.rooms {
+		r = append(r, room.info())
+	}
+	l.mux.Unlock()
+	slices.SortFunc(r, func(a, b game.RoomInfo) int {
+		return strings.Compare(string(a.Id), string(b.Id))
+	})
+	return r
+}
+
+func (l *lobby) fail(conn *connection, id game.RoomIdType, reason string) {
+	l.send(conn, game.RoomJoinFailedAction{
+		Type: game.RoomJoinFailedActionType,
+		Payload: game.RoomJoinFailedPayload{
+			RoomId: id,
+			Reason: reason,
+		},
+	})
+}
+
+func (l *lobby) send(conn *connection, action game.Action) {
+	if err := conn.out.Send(action); err != nil {
+		log.Println(err)
+	}
+}
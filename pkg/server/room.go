@@ -0,0 +1,672 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/world"
+	"github.com/google/uuid"
+)
+
+// room - single match with its own game state and simulation loop
+type room struct {
+	id        game.RoomIdType
+	config    game.RoomConfig
+	game      *serverGame
+	chunks    *world.ChunkCache             // map chunks shared with the other rooms, loaded off the loop
+	clients   map[game.PlayerIdType]peer    // loop-owned, connected players
+	ais       map[game.PlayerIdType]*aiPeer // loop-owned, opponents hosted by the room
+	intents   chan intent
+	done      chan struct{}
+	onClose   func(*room)
+	replay    *os.File            // replay file of the match, nil when not recorded
+	snapshots game.SnapshotStore  // persistence of the match, nil when not persisted
+	saves     chan *game.Snapshot // latest snapshot waiting for the saver
+	saved     chan struct{}       // closed when the saver wrote the last snapshot
+	quit      chan struct{}       // closed when the server shuts down
+	quitOnce  sync.Once
+	requests  *game.RequestLog
+
+	mux        sync.Mutex // guards the fields below, shared with the lobby
+	state      game.RoomState
+	seats      map[game.PlayerIdType]bool
+	members    int // connections bound to the room
+	emptySince time.Time
+	closed     bool
+}
+
+// peer - end of a player in the room, a remote client or an AI hosted by the server
+type peer interface {
+	Send(action game.Action) error
+	// playerId - player bound to the peer, empty before joining
+	playerId() game.PlayerIdType
+	bind(id game.PlayerIdType)
+}
+
+// remote - peer of a WebSocket client in one room, the player id is owned by the room's loop
+// and never written to the client, the connection's goroutine reads the client concurrently
+type remote struct {
+	*sender
+	id game.PlayerIdType
+}
+
+func (c *remote) playerId() game.PlayerIdType {
+	return c.id
+}
+
+func (c *remote) bind(id game.PlayerIdType) {
+	c.id = id
+}
+
+// intent - action received from a client, waiting for the simulation loop
+type intent struct {
+	client       peer
+	action       game.Action
+	disconnected bool
+	loaded       func() // result of a load off the loop, applied on the loop
+}
+
+func newRoom(id game.RoomIdType, config game.RoomConfig, chunks *world.ChunkCache,
+	types *game.UnitTypes, onClose func(*room)) *room {
+	r := &room{
+		id:         id,
+		config:     config,
+		chunks:     chunks,
+		clients:    make(map[game.PlayerIdType]peer, 0),
+		ais:        make(map[game.PlayerIdType]*aiPeer),
+		intents:    make(chan intent, intentsBuffer),
+		requests:   game.NewRequestLog(),
+		done:       make(chan struct{}),
+		saves:      make(chan *game.Snapshot, 1),
+		saved:      make(chan struct{}),
+		quit:       make(chan struct{}),
+		onClose:    onClose,
+		state:      game.RoomWaiting,
+		seats:      make(map[game.PlayerIdType]bool),
+		emptySince: time.Now(),
+	}
+	r.game = newServerGame(game.NewStoreImpl(), r.fetch, config.SpawnPoints, types)
+	return r
+}
+
+// fetch - loads the chunks one by one in the background, each one comes back to the loop through the intents
+func (r *room) fetch(ids []world.ChunkId, done func(id world.ChunkId, tiles []world.Tile, err error)) {
+	go func() {
+		for _, id := range ids {
+			tiles, err := r.chunks.Chunk(id)
+			r.push(intent{loaded: func() { done(id, tiles, err) }})
+		}
+	}()
+}
+
+// push - queues the intent for the simulation loop, dropped when the room is closed
+func (r *room) push(in intent) {
+	select {
+	case r.intents <- in:
+	case <-r.done:
+	}
+}
+
+func (r *room) info() game.RoomInfo {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return game.RoomInfo{
+		Id:         r.id,
+		Name:       r.config.Name,
+		State:      r.state,
+		Players:    len(r.seats),
+		MaxPlayers: r.config.MaxPlayers,
+	}
+}
+
+func (r *room) currentState() game.RoomState {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.state
+}
+
+// open - whether new players can take a seat
+func (r *room) open() bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return !r.closed && r.state != game.RoomFinished && len(r.seats) < r.config.MaxPlayers
+}
+
+// seat - reserves the seat for the player, players keep their seats after disconnecting
+func (r *room) seat(id game.PlayerIdType) bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if !r.seatFreeLocked(id) {
+		return false
+	}
+	r.seats[id] = true
+	return true
+}
+
+// seatFree - whether the player holds a seat or there is one left, the seat is taken once the join is accepted
+func (r *room) seatFree(id game.PlayerIdType) bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.seatFreeLocked(id)
+}
+
+func (r *room) seatFreeLocked(id game.PlayerIdType) bool {
+	if r.seats[id] {
+		return true
+	}
+	return !r.closed && r.state != game.RoomFinished && len(r.seats) < r.config.MaxPlayers
+}
+
+// unseat - gives the seat of the player whose join failed back
+func (r *room) unseat(id game.PlayerIdType) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	delete(r.seats, id)
+}
+
+func (r *room) addMember() bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.closed {
+		return false
+	}
+	r.members++
+	return true
+}
+
+func (r *room) removeMember() {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.members--
+	if r.members == 0 {
+		r.emptySince = time.Now()
+	}
+}
+
+// abandoned - closes the room when nobody came back for a while,
+// a persisted match with seated players waits for them until it is finished
+func (r *room) abandoned() bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.members > 0 || time.Since(r.emptySince) < emptyRoomTTL {
+		return false
+	}
+	if r.snapshots != nil && len(r.seats) > 0 && r.state != game.RoomFinished {
+		return false
+	}
+	r.closed = true
+	return true
+}
+
+// record - starts recording the match into the directory, must be called before run
+func (r *room) record(dir string) error {
+	f, err := os.Create(filepath.Join(dir, string(r.id)+".jsonl"))
+	if err != nil {
+		return fmt.Errorf("record room %s: %w", r.id, err)
+	}
+	r.replay = f
+	r.game.recorder = game.NewRecorder(f)
+	log.Printf("recording room %s to %s", r.id, f.Name())
+	return nil
+}
+
+func (r *room) stopRecording() {
+	if r.replay == nil {
+		return
+	}
+	if err := r.game.recorder.Flush(); err != nil {
+		log.Println(err)
+	}
+	if err := r.replay.Close(); err != nil {
+		log.Println(err)
+	}
+}
+
+// restore - continues the match from the snapshot, must be called before run
+func (r *room) restore(s *game.Snapshot) error {
+	if err := r.game.restore(s); err != nil {
+		return fmt.Errorf("restore room %s: %w", r.id, err)
+	}
+	r.state = s.State
+	for _, seat := range s.Seats {
+		r.seats[seat.PlayerId] = true
+	}
+	r.restoreAI(s)
+	return nil
+}
+
+// save - takes the snapshot of the match, the saver writes it off the loop
+func (r *room) save() {
+	if r.snapshots == nil {
+		return
+	}
+	s := r.game.snapshot()
+	r.aiSeats(s)
+	s.Room = r.id
+	s.Config = r.config
+	s.State = r.currentState()
+	// a snapshot the saver has not taken yet is out of date
+	select {
+	case <-r.saves:
+	default:
+	}
+	r.saves <- s
+}
+
+// saver - writes the snapshots of the room until the loop stops
+func (r *room) saver() {
+	defer close(r.saved)
+	for s := range r.saves {
+		if err := r.snapshots.Save(s); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// stopSaving - returns when the last snapshot is written
+func (r *room) stopSaving() {
+	close(r.saves)
+	<-r.saved
+}
+
+// shutdown - stops the loop after saving the match, returns when stopped, safe to repeat
+func (r *room) shutdown() {
+	r.quitOnce.Do(func() { close(r.quit) })
+	<-r.done
+}
+
+// run - simulation loop, the only goroutine touching the game state
+func (r *room) run(rate time.Duration) {
+	ticker := time.NewTicker(rate)
+	defer ticker.Stop()
+	defer close(r.done)
+	defer r.stopRecording()
+	go r.saver()
+	for {
+		select {
+		case in := <-r.intents:
+			if in.loaded != nil {
+				in.loaded()
+				continue
+			}
+			if in.disconnected {
+				r.disconnect(in.client)
+				continue
+			}
+			r.processAction(in.client, in.action)
+		case <-ticker.C:
+			r.tick()
+			if r.abandoned() {
+				log.Printf("room %s closed", r.id)
+				r.stopSaving()
+				r.forget()
+				r.onClose(r)
+				return
+			}
+		case <-r.quit:
+			r.save()
+			r.stopSaving()
+			return
+		}
+	}
+}
+
+// forget - the closed match is not restored after restart, it is finished or nobody took a seat
+func (r *room) forget() {
+	if r.snapshots == nil {
+		return
+	}
+	if err := r.snapshots.Delete(r.id); err != nil {
+		log.Println(err)
+	}
+}
+
+// tick - advances all units and publishes their new state
+func (r *room) tick() {
+	dispatch := func(a game.Action) {
+		if err := r.route(nil, a); err != nil {
+			log.Println(err)
+		}
+	}
+	r.game.tick++
+	if r.currentState() == game.RoomRunning {
+		r.game.Update(dispatch)
+	}
+	r.playAI()
+	r.syncVision()
+	r.updateState()
+	if r.game.tick%snapshotTicks == 0 {
+		r.save()
+	}
+	if r.game.recorder != nil {
+		if err := r.game.recorder.Flush(); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// updateState - room lifecycle, starts with enough players and finishes when one player is left
+func (r *room) updateState() {
+	state := r.currentState()
+	next := state
+	switch state {
+	case game.RoomWaiting:
+		if len(r.game.store.GetAllPlayers()) >= r.config.MinPlayers {
+			next = game.RoomRunning
+		}
+	case game.RoomRunning:
+		if r.conquered() {
+			next = game.RoomFinished
+		}
+	}
+	if next == state {
+		return
+	}
+
+	r.mux.Lock()
+	r.state = next
+	r.mux.Unlock()
+	log.Printf("room %s %s", r.id, next)
+	r.broadcastAll(game.RoomStateAction{
+		Type:    game.RoomStateActionType,
+		Payload: game.RoomStatePayload{Room: r.info()},
+	})
+}
+
+// conquered - all players but one have lost their units
+func (r *room) conquered() bool {
+	if len(r.game.store.GetAllPlayers()) < 2 {
+		return false
+	}
+	owners := make(map[game.PlayerIdType]bool)
+	for _, u := range r.game.store.GetAllUnits() {
+		owners[u.Owner] = true
+	}
+	return len(owners) <= 1
+}
+
+func (r *room) processAction(client peer, action game.Action) {
+	// clients only express intents on behalf of their own units, the state changes are decided by the server
+	if rejection := r.game.validator.Validate(client.playerId(), action); rejection != nil {
+		r.reject(client, action, rejection)
+		return
+	}
+	// retried after reconnect, the first copy was handled already;
+	// joins are safe to repeat and come before the connection has a player to tell them apart
+	if !joining(action) && r.requests.Handled(client.playerId(), action) {
+		r.reply(client, game.NewAckAction(action, true))
+		return
+	}
+
+	// the session token of a player is never handed out again without the token
+	if a, ok := action.(game.PlayerJoinAction); ok {
+		if rejection := r.game.canJoin(client.playerId(), a.Payload.Id); rejection != nil {
+			r.reject(client, action, rejection)
+			return
+		}
+		if !r.takeSeat(client, a.Payload.Id) {
+			return
+		}
+	}
+	// resume the session on the new connection
+	if a, ok := action.(game.PlayerRejoinAction); ok {
+		if !r.game.canResume(a.Payload) {
+			r.reject(client, action, game.NewError(game.ErrorBadSession, "invalid session token"))
+			return
+		}
+		if !r.takeSeat(client, a.Payload.Player.Id) {
+			return
+		}
+		action = game.PlayerJoinAction{
+			Type:    game.PlayerJoinActionType,
+			Payload: a.Payload.Player,
+		}
+	}
+
+	// register new player, rebinds the player to the connection after rejoin
+	if action.GetType() == game.PlayerJoinActionType {
+		client.bind(action.GetPayload().(game.Player).Id)
+		r.clients[client.playerId()] = client
+	}
+
+	// synchronous dispatch func, replies to the sender carry the request id
+	requestId := action.GetEnvelope().RequestId
+	failed := false
+	dispatch := func(a game.Action) {
+		switch a.(type) {
+		case game.ErrorAction:
+			failed = true
+			a = game.Reply(a, requestId, r.game.tick)
+		case game.PlayerJoinSuccessAction, game.MapLoadSuccessAction:
+			a = game.Reply(a, requestId, r.game.tick)
+		}
+		if err := r.route(client, a); err != nil {
+			log.Println(err)
+		}
+	}
+
+	// action handling
+	r.game.HandleAction(action, dispatch)
+	if failed && action.GetType() == game.PlayerJoinActionType {
+		// no spawn point left for the new player
+		r.unseat(client.playerId())
+	}
+	if requestId != 0 && !failed {
+		r.reply(client, game.NewAckAction(action, false))
+	}
+	r.syncVision()
+}
+
+// takeSeat - seats the player of the accepted join, the client learns when the room is full
+func (r *room) takeSeat(client peer, id game.PlayerIdType) bool {
+	if r.seat(id) {
+		return true
+	}
+	if err := client.Send(game.RoomJoinFailedAction{
+		Type:    game.RoomJoinFailedActionType,
+		Payload: game.RoomJoinFailedPayload{RoomId: r.id, Reason: "room is full"},
+	}); err != nil {
+		log.Println(err)
+	}
+	return false
+}
+
+// joining - whether the action joins the player to the game
+func joining(action game.Action) bool {
+	t := action.GetType()
+	return t == game.PlayerJoinActionType || t == game.PlayerRejoinActionType
+}
+
+// reply - sends the answer to the client's request
+func (r *room) reply(client peer, action game.Action) {
+	if err := client.Send(r.stamp(action)); err != nil {
+		log.Println(err)
+	}
+}
+
+// stamp - marks the outgoing action with the current tick
+func (r *room) stamp(action game.Action) game.Action {
+	e := action.GetEnvelope()
+	if e.Tick != 0 {
+		return action
+	}
+	e.Tick = r.game.tick
+	return action.WithEnvelope(e)
+}
+
+// disconnect - unbinds the lost connection, the player and units stay in the game
+func (r *room) disconnect(client peer) {
+	id := client.playerId()
+	if r.clients[id] != client {
+		// never joined or already rebound to a new connection
+		return
+	}
+	delete(r.clients, id)
+	r.game.vision.Forget(id)
+	log.Printf("player %s disconnected", uuid.UUID(id))
+}
+
+// reject - tells the client why its action was refused
+func (r *room) reject(client peer, action game.Action, rejection *game.Error) {
+	log.Printf("rejected %s from player %s: %s", action.GetType(), uuid.UUID(client.playerId()), rejection)
+	requestId := action.GetEnvelope().RequestId
+	r.reply(client, game.Reply(game.NewActionRejectedAction(action, rejection), requestId, r.game.tick))
+}
+
+// syncVision - tells every client about units entering and leaving its sight
+func (r *room) syncVision() {
+	for id, c := range r.clients {
+		for _, a := range r.game.vision.Refresh(id) {
+			if err := c.Send(r.stamp(a)); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
+
+// broadcastVisible - sends the unit's update to clients that know about the unit
+func (r *room) broadcastVisible(unitId game.UnitIdType, action game.Action) {
+	for id, c := range r.clients {
+		if !r.game.vision.Knows(id, unitId) {
+			continue
+		}
+		if err := c.Send(action); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// broadcastMove - sends the move to clients that know about the unit,
+// only its owner learns the whole path
+func (r *room) broadcastMove(action game.MoveStepAction) {
+	owner := game.PlayerIdType{}
+	if unit := r.game.store.GetUnitById(action.Payload.UnitId); unit != nil {
+		owner = unit.Owner
+	}
+	seen := action
+	seen.Payload = action.Payload.Seen()
+	for id, c := range r.clients {
+		if !r.game.vision.Knows(id, action.Payload.UnitId) {
+			continue
+		}
+		a := seen
+		if id == owner {
+			a = action
+		}
+		if err := c.Send(a); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// broadcastDepletion - the other players seeing the node learn what is left in it,
+// not who gathered it
+func (r *room) broadcastDepletion(action game.ResourceGatheredAction) {
+	seen := action
+	seen.Payload.UnitId = game.ZeroUnitId
+	seen.Payload.PlayerId = game.PlayerIdType{}
+	for id, c := range r.clients {
+		if id == action.Payload.PlayerId || !r.game.vision.Sees(id, action.Payload.Point) {
+			continue
+		}
+		if err := c.Send(seen); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// sendTo - sends the action to the player only
+func (r *room) sendTo(id game.PlayerIdType, action game.Action) {
+	c, ok := r.clients[id]
+	if !ok {
+		return
+	}
+	if err := c.Send(action); err != nil {
+		log.Println(err)
+	}
+}
+
+func (r *room) broadcastAll(action game.Action) {
+	action = r.stamp(action)
+	for _, c := range r.clients {
+		err := c.Send(action)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// route - handler of outgoing actions
+func (r *room) route(c peer, action game.Action) error {
+	dispatch := func(a game.Action) {
+		if err := r.route(c, a); err != nil {
+			log.Println(err)
+		}
+	}
+	action = r.stamp(action)
+	switch a := action.(type) {
+	case game.MoveStepAction:
+		r.broadcastMove(a)
+		r.game.HandleAction(a, dispatch)
+	case game.MoveStopAction:
+		r.broadcastVisible(a.Payload, a)
+		r.game.HandleAction(a, dispatch)
+	case game.UnitDamagedAction:
+		r.broadcastVisible(a.Payload.UnitId, a)
+		r.game.HandleAction(a, dispatch)
+	case game.UnitDestroyedAction:
+		r.broadcastVisible(a.Payload.UnitId, a)
+		r.game.HandleAction(a, dispatch)
+	case game.ResourceGatheredAction:
+		r.sendTo(a.Payload.PlayerId, a)
+		r.broadcastDepletion(a)
+		r.game.HandleAction(a, dispatch)
+	case game.ResourcesSpentAction:
+		r.sendTo(a.Payload.PlayerId, a)
+		r.game.HandleAction(a, dispatch)
+	case game.ProductionQueuedAction:
+		if building := r.game.store.GetUnitById(a.Payload.BuildingId); building != nil {
+			r.sendTo(building.Owner, a)
+		}
+		r.game.HandleAction(a, dispatch)
+	case game.UnitOrdersAction:
+		if unit := r.game.store.GetUnitById(a.Payload.UnitId); unit != nil {
+			r.sendTo(unit.Owner, a)
+		}
+		r.game.HandleAction(a, dispatch)
+	case game.ProductionCompleteAction:
+		r.sendTo(a.Payload.Unit.Owner, a)
+		r.game.HandleAction(a, dispatch)
+	case game.SpawnUnitAction:
+		// announced with vision updates, to the owner as well
+		r.game.HandleAction(a, dispatch)
+	case game.ErrorAction:
+		// to the player of the failed unit, the sender otherwise
+		if a.Payload.PlayerId == (game.PlayerIdType{}) && c != nil {
+			a.Payload.PlayerId = c.playerId()
+			if err := c.Send(a); err != nil {
+				return fmt.Errorf("route %w", err)
+			}
+			return nil
+		}
+		r.sendTo(a.Payload.PlayerId, a)
+	case game.PlayerJoinSuccessAction:
+		if err := c.Send(action); err != nil {
+			return fmt.Errorf("route %w", err)
+		}
+	case game.MapLoadSuccessAction:
+		if err := c.Send(action); err != nil {
+			return fmt.Errorf("route %w", err)
+		}
+		r.game.HandleAction(a, dispatch)
+	default:
+		r.broadcastAll(a)
+	}
+
+	return nil
+}
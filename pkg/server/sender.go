@@ -0,0 +1,75 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/bmcszk/fogofgo/pkg/comm"
+	"github.com/bmcszk/fogofgo/pkg/game"
+)
+
+// sender - outbound actions of a connection written by its own goroutine, the room's loop never waits
+// for a slow client; a client falling behind by a full queue is disconnected
+type sender struct {
+	client  *comm.Client
+	actions chan game.Action
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newSender(client *comm.Client) *sender {
+	s := &sender{
+		client:  client,
+		actions: make(chan game.Action, sendBuffer),
+		stopped: make(chan struct{}),
+	}
+	go s.write()
+	return s
+}
+
+// Send - queues the action, the connection is dropped when its queue is full
+func (s *sender) Send(action game.Action) error {
+	select {
+	case <-s.stopped:
+		return nil
+	default:
+	}
+	select {
+	case s.actions <- action:
+		return nil
+	default:
+		s.drop()
+		return fmt.Errorf("send %s: queue full, connection dropped", action.GetType())
+	}
+}
+
+// write - writes the queued actions until the sender stops
+func (s *sender) write() {
+	for {
+		select {
+		case action := <-s.actions:
+			if err := s.client.Send(action); err != nil {
+				log.Println(err)
+				s.drop()
+				return
+			}
+		case <-s.stopped:
+			return
+		}
+	}
+}
+
+// stop - the actions left in the queue are not written
+func (s *sender) stop() {
+	s.once.Do(func() { close(s.stopped) })
+}
+
+// drop - stops the sender and closes the connection, its reader leaves the room
+func (s *sender) drop() {
+	s.stop()
+	// the writer may be holding the client until its write deadline, the caller does not wait
+	if err := s.client.Abort(); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,57 @@
+// Package server - game server hosting the matches, each room runs its own simulation loop.
+// The players connect over WebSocket, the server can run in-process for tests and load tests.
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/bmcszk/fogofgo/pkg/comm"
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/world"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	tickRate      = time.Second / 60
+	intentsBuffer = 256
+	// sendBuffer - actions queued for a connection before it is dropped as too slow
+	sendBuffer = 1024
+	// snapshotTicks - how often the persisted rooms are saved, 30 seconds
+	snapshotTicks = int64(30 * time.Second / tickRate)
+)
+
+// emptyRoomTTL - how long a room without connections waits for its players to come back, shortened by the tests
+var emptyRoomTTL = time.Minute
+
+var upgrader = websocket.Upgrader{Subprotocols: comm.Subprotocols}
+
+// Server - lobby of the matches, serves the WebSocket connections of the players
+type Server struct {
+	lobby *lobby
+}
+
+// New - server of the map from the chunks, the matches are recorded into recordDir unless it is empty
+func New(chunks *world.ChunkCache, types *game.UnitTypes, recordDir string) *Server {
+	return &Server{lobby: newLobby(chunks, types, recordDir)}
+}
+
+// Persist - restores the matches saved by the previous run and keeps saving them
+func (s *Server) Persist(snapshots game.SnapshotStore) error {
+	return s.lobby.persist(snapshots)
+}
+
+// ServeHTTP - upgrades the request to the WebSocket connection of a player, returns when it is closed
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lobby.handleConnections(w, r)
+}
+
+// Rooms - matches hosted by the server
+func (s *Server) Rooms() []game.RoomInfo {
+	return s.lobby.list()
+}
+
+// Shutdown - stops all rooms, the persisted ones are saved, safe to call again
+func (s *Server) Shutdown() {
+	s.lobby.shutdown()
+}
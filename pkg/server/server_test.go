@@ -0,0 +1,607 @@
+package server_test
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmcszk/fogofgo/pkg/comm"
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/server"
+	"github.com/bmcszk/fogofgo/pkg/world"
+	"github.com/gorilla/websocket"
+)
+
+// landFunc - map with the land type of each tile given by the func
+type landFunc func(p image.Point) string
+
+func (f landFunc) Load(r world.WorldRequest) (*world.WorldResponse, error) {
+	tiles := make([]world.Tile, 0, (r.MaxX-r.MinX+1)*(r.MaxY-r.MinY+1))
+	for x := r.MinX; x <= r.MaxX; x++ {
+		for y := r.MinY; y <= r.MaxY; y++ {
+			p := image.Pt(x, y)
+			tiles = append(tiles, world.Tile{Point: p, LandType: f(p)})
+		}
+	}
+	return &world.WorldResponse{MinX: r.MinX, MinY: r.MinY, MaxX: r.MaxX, MaxY: r.MaxY, Tiles: tiles}, nil
+}
+
+func plain(image.Point) string {
+	return world.LandPlain
+}
+
+// plainUntil - plain land with the land type from x to the east
+func plainUntil(x int, land string) landFunc {
+	return func(p image.Point) string {
+		if p.X >= x {
+			return land
+		}
+		return world.LandPlain
+	}
+}
+
+// startTestServer - in-process server of the map, stopped with the test
+func startTestServer(t *testing.T, provider world.WorldProvider) (*server.Server, string) {
+	t.Helper()
+	srv := server.New(world.NewChunkCache(provider, 64), game.DefaultUnitTypes(), "")
+	hs := httptest.NewServer(srv)
+	t.Cleanup(func() {
+		hs.Close()
+		srv.Shutdown()
+	})
+	return srv, "ws" + strings.TrimPrefix(hs.URL, "http")
+}
+
+// testClient - player connected to the test server, the received actions wait in the channel
+type testClient struct {
+	*comm.Client
+	player   game.Player
+	received chan game.Action
+}
+
+func dialTestClient(t *testing.T, url string, name string) *testClient {
+	t.Helper()
+	c := dialStalledClient(t, url, name)
+	go func() {
+		defer close(c.received)
+		for {
+			action, err := c.HandleInMessages()
+			if err != nil {
+				return
+			}
+			c.received <- action
+		}
+	}()
+	return c
+}
+
+// dialStalledClient - player connected to the test server who never reads what the server sends
+func dialStalledClient(t *testing.T, url string, name string) *testClient {
+	t.Helper()
+	dialer := websocket.Dialer{Subprotocols: comm.Subprotocols}
+	ws, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &testClient{
+		Client:   comm.NewClient(ws),
+		player:   game.Player{Id: game.PlayerIdType(game.NewUnitId()), Name: name, Color: color.RGBA{255, 0, 0, 255}},
+		received: make(chan game.Action, 1024),
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func (c *testClient) send(t *testing.T, action game.Action) {
+	t.Helper()
+	if err := c.Send(action); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// createRoom - creates the room and enters it, returns its id
+func (c *testClient) createRoom(t *testing.T, config game.RoomConfig) game.RoomIdType {
+	t.Helper()
+	c.send(t, game.RoomCreateAction{Type: game.RoomCreateActionType, Payload: config})
+	return await(t, c, func(game.RoomJoinSuccessAction) bool { return true }).Payload.Id
+}
+
+// joinRoom - enters the room
+func (c *testClient) joinRoom(t *testing.T, id game.RoomIdType) {
+	t.Helper()
+	c.send(t, game.RoomJoinAction{Type: game.RoomJoinActionType, Payload: game.RoomJoinPayload{RoomId: id}})
+	await(t, c, func(game.RoomJoinSuccessAction) bool { return true })
+}
+
+// join - joins the player, returns the session
+func (c *testClient) join(t *testing.T) game.PlayerJoinSuccessAction {
+	t.Helper()
+	c.send(t, game.PlayerJoinAction{Type: game.PlayerJoinActionType, Payload: c.player})
+	return await(t, c, func(game.PlayerJoinSuccessAction) bool { return true })
+}
+
+// ownUnit - waits for the player's unit of the type to come into sight
+func (c *testClient) ownUnit(t *testing.T, unitType game.UnitTypeIdType) game.Unit {
+	t.Helper()
+	return await(t, c, func(a game.UnitEnteredVisionAction) bool {
+		return a.Payload.Unit.Owner == c.player.Id && a.Payload.Unit.Type == unitType
+	}).Payload.Unit
+}
+
+// await - first received action of the type matching, the others are skipped
+func await[A game.Action](t *testing.T, c *testClient, match func(A) bool) A {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case action, ok := <-c.received:
+			if !ok {
+				t.Fatal("connection closed")
+			}
+			if a, ok := action.(A); ok && match(a) {
+				return a
+			}
+		case <-timeout:
+			var zero A
+			t.Fatalf("expected %T", zero)
+		}
+	}
+}
+
+func TestServer_MoveStart_AvoidsTerrainNoClientLoaded(t *testing.T) {
+	_, url := startTestServer(t, plainUntil(6, world.LandSea))
+	c := dialTestClient(t, url, "player1")
+	c.join(t)
+	worker := c.ownUnit(t, game.WorkerType)
+
+	// no MapLoad was sent, the server loads the terrain on its own
+	c.send(t, game.MoveStartAction{
+		Type:    game.MoveStartActionType,
+		Payload: game.MoveStartPayload{UnitId: worker.Id, Point: image.Pt(12, worker.Position.ImagePoint().Y)},
+	})
+	step := await(t, c, func(a game.MoveStepAction) bool { return a.Payload.UnitId == worker.Id })
+
+	if len(step.Payload.Path) == 0 {
+		t.Fatal("expected the worker to walk towards the shore")
+	}
+	for _, p := range step.Payload.Path {
+		if p.X >= 6 {
+			t.Fatalf("expected the path to stay on the land, got %v", step.Payload.Path)
+		}
+	}
+}
+
+// seesEnemy - whether an enemy unit came into the player's sight while waiting
+func (c *testClient) seesEnemy(wait time.Duration) bool {
+	timeout := time.After(wait)
+	for {
+		select {
+		case action, ok := <-c.received:
+			if !ok {
+				return false
+			}
+			if a, ok := action.(game.UnitEnteredVisionAction); ok && a.Payload.Unit.Owner != c.player.Id {
+				return true
+			}
+		case <-timeout:
+			return false
+		}
+	}
+}
+
+func TestServer_Vision_TerrainNoClientLoaded(t *testing.T) {
+	tests := []struct {
+		name string
+		land string
+		sees bool
+	}{
+		{name: "plain", land: world.LandPlain, sees: true},
+		{name: "forest", land: world.LandForest, sees: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// a strip of the land between the bases, the map is never requested by the clients
+			_, url := startTestServer(t, landFunc(func(p image.Point) string {
+				if p.X == 3 || p.X == 4 {
+					return tt.land
+				}
+				return world.LandPlain
+			}))
+			c1, _ := joinRoomOfTwo(t, url)
+
+			if sees := c1.seesEnemy(time.Second); sees != tt.sees {
+				t.Errorf("expected the enemy seen %v, got %v", tt.sees, sees)
+			}
+		})
+	}
+}
+
+// joinRoomOfTwo - two players with their bases next to each other in a new room
+func joinRoomOfTwo(t *testing.T, url string) (*testClient, *testClient) {
+	t.Helper()
+	c1 := dialTestClient(t, url, "player1")
+	room := c1.createRoom(t, game.RoomConfig{SpawnPoints: []image.Point{{0, 0}, {6, 0}}})
+	c1.join(t)
+	c2 := dialTestClient(t, url, "player2")
+	c2.joinRoom(t, room)
+	c2.join(t)
+	return c1, c2
+}
+
+func TestServer_MoveStep_HidesDestinationFromEnemies(t *testing.T) {
+	_, url := startTestServer(t, landFunc(plain))
+	c1, c2 := joinRoomOfTwo(t, url)
+	worker := c2.ownUnit(t, game.WorkerType)
+	await(t, c1, func(a game.UnitEnteredVisionAction) bool { return a.Payload.Unit.Id == worker.Id })
+
+	c2.send(t, game.MoveStartAction{
+		Type:    game.MoveStartActionType,
+		Payload: game.MoveStartPayload{UnitId: worker.Id, Point: image.Pt(6, 20)},
+	})
+	own := await(t, c2, func(a game.MoveStepAction) bool { return a.Payload.UnitId == worker.Id })
+	seen := await(t, c1, func(a game.MoveStepAction) bool { return a.Payload.UnitId == worker.Id })
+
+	if dest := own.Payload.Path[len(own.Payload.Path)-1]; dest != image.Pt(6, 20) {
+		t.Errorf("expected the owner to get the whole path, got %v", own.Payload.Path)
+	}
+	if len(seen.Payload.Path) > 2 {
+		t.Errorf("expected the enemy to see the next step only, got %v", seen.Payload.Path)
+	}
+}
+
+func TestServer_PlayerJoin_KnownPlayerNeedsToken(t *testing.T) {
+	_, url := startTestServer(t, landFunc(plain))
+	c1 := dialTestClient(t, url, "player1")
+	room := c1.createRoom(t, game.RoomConfig{})
+	session := c1.join(t).Payload.SessionToken
+
+	thief := dialTestClient(t, url, "thief")
+	thief.joinRoom(t, room)
+	thief.send(t, game.PlayerJoinAction{Type: game.PlayerJoinActionType, Payload: c1.player})
+	await(t, thief, func(a game.ActionRejectedAction) bool { return a.Payload.Action == game.PlayerJoinActionType })
+
+	c2 := dialTestClient(t, url, "player1")
+	c2.joinRoom(t, room)
+	c2.send(t, game.PlayerRejoinAction{
+		Type:    game.PlayerRejoinActionType,
+		Payload: game.PlayerRejoinPayload{Player: c1.player, SessionToken: session},
+	})
+	resumed := await(t, c2, func(game.PlayerJoinSuccessAction) bool { return true })
+
+	if resumed.Payload.SessionToken != session {
+		t.Errorf("expected the session %s resumed, got %s", session, resumed.Payload.SessionToken)
+	}
+}
+
+func TestServer_ResourceGathered_SeenByOtherPlayers(t *testing.T) {
+	node := image.Pt(3, 3)
+	_, url := startTestServer(t, landFunc(func(p image.Point) string {
+		if p == node {
+			return world.LandForest
+		}
+		return world.LandPlain
+	}))
+	c1, c2 := joinRoomOfTwo(t, url)
+	worker := c1.ownUnit(t, game.WorkerType)
+
+	c1.send(t, game.GatherAction{
+		Type:    game.GatherActionType,
+		Payload: game.GatherPayload{UnitId: worker.Id, Point: node},
+	})
+	seen := await(t, c2, func(a game.ResourceGatheredAction) bool { return a.Payload.Point == node })
+
+	if seen.Payload.Amount == 0 {
+		t.Error("expected the gathered amount")
+	}
+	if seen.Payload.PlayerId != (game.PlayerIdType{}) || seen.Payload.UnitId != game.ZeroUnitId {
+		t.Errorf("expected the gatherer hidden, got %+v", seen.Payload)
+	}
+}
+
+func TestServer_MapLoad_SingleTile(t *testing.T) {
+	_, url := startTestServer(t, landFunc(plain))
+	c := dialTestClient(t, url, "player1")
+	c.join(t)
+
+	c.send(t, game.NewMapLoadAction(image.Rect(40, 40, 41, 41), c.player.Id))
+	loaded := await(t, c, func(game.MapLoadSuccessAction) bool { return true })
+
+	if chunk := world.ChunkOf(image.Pt(40, 40)).Request(); loaded.Payload.MinX != chunk.MinX ||
+		loaded.Payload.MaxY != chunk.MaxY {
+		t.Errorf("expected the chunk of the tile, got %+v", loaded.Payload.WorldResponse)
+	}
+}
+
+func TestServer_Shutdown_Twice(t *testing.T) {
+	srv, url := startTestServer(t, landFunc(plain))
+	c := dialTestClient(t, url, "player1")
+	c.join(t)
+
+	srv.Shutdown()
+	// the cleanup shuts the server down again
+}
+
+// startPersistedServer - in-process server saving its matches into the store and restoring the saved ones
+func startPersistedServer(t *testing.T, snapshots game.SnapshotStore) (*server.Server, string) {
+	t.Helper()
+	srv, url := startTestServer(t, landFunc(plain))
+	if err := srv.Persist(snapshots); err != nil {
+		t.Fatal(err)
+	}
+	return srv, url
+}
+
+// rejoin - resumes the session of the player in the room on the new connection
+func (c *testClient) rejoin(t *testing.T, room game.RoomIdType, player game.Player,
+	session string) game.PlayerJoinSuccessAction {
+	t.Helper()
+	c.player = player
+	c.joinRoom(t, room)
+	c.send(t, game.PlayerRejoinAction{
+		Type:    game.PlayerRejoinActionType,
+		Payload: game.PlayerRejoinPayload{Player: player, SessionToken: session},
+	})
+	resumed := await(t, c, func(game.PlayerJoinSuccessAction) bool { return true })
+	if resumed.Payload.SessionToken != session {
+		t.Fatalf("expected the session %s resumed, got %s", session, resumed.Payload.SessionToken)
+	}
+	return resumed
+}
+
+func hosts(srv *server.Server, id game.RoomIdType) bool {
+	for _, info := range srv.Rooms() {
+		if info.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestServer_Persist_RejoinRestoredMatch(t *testing.T) {
+	snapshots, err := game.NewFileSnapshots(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, url := startPersistedServer(t, snapshots)
+	c1 := dialTestClient(t, url, "player1")
+	room := c1.createRoom(t, game.RoomConfig{})
+	session := c1.join(t).Payload.SessionToken
+	worker := c1.ownUnit(t, game.WorkerType)
+	before.Shutdown()
+
+	_, url = startPersistedServer(t, snapshots)
+	c2 := dialTestClient(t, url, "player1")
+	resumed := c2.rejoin(t, room, c1.player, session)
+
+	if !slices.ContainsFunc(resumed.Payload.Units, func(u game.Unit) bool { return u.Id == worker.Id }) {
+		t.Errorf("expected the worker restored, got %d units", len(resumed.Payload.Units))
+	}
+}
+
+func TestServer_EmptyRoom_PersistedMatchWaitsForPlayers(t *testing.T) {
+	server.SetEmptyRoomTTL(t, 50*time.Millisecond)
+	snapshots, err := game.NewFileSnapshots(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, url := startPersistedServer(t, snapshots)
+	c1 := dialTestClient(t, url, "player1")
+	seated := c1.createRoom(t, game.RoomConfig{})
+	c1.join(t)
+	before.Shutdown()
+
+	// nobody is connected to the restored match, nobody took a seat in the new room
+	srv, url := startPersistedServer(t, snapshots)
+	c2 := dialTestClient(t, url, "player2")
+	unseated := c2.createRoom(t, game.RoomConfig{})
+	_ = c2.Close()
+	time.Sleep(500 * time.Millisecond)
+
+	if !hosts(srv, seated) {
+		t.Error("expected the restored match to wait for its players")
+	}
+	if hosts(srv, unseated) {
+		t.Error("expected the room without seats closed")
+	}
+	saved, err := snapshots.LoadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 1 || saved[0].Room != seated {
+		t.Errorf("expected the snapshot of the restored match kept, got %d snapshots", len(saved))
+	}
+}
+
+func TestServer_Request_RetryAfterRejoinIsDuplicate(t *testing.T) {
+	_, url := startTestServer(t, landFunc(plain))
+	c1 := dialTestClient(t, url, "player1")
+	room := c1.createRoom(t, game.RoomConfig{})
+	session := c1.join(t).Payload.SessionToken
+	worker := c1.ownUnit(t, game.WorkerType)
+	move := game.MoveStartAction{
+		Type:     game.MoveStartActionType,
+		Payload:  game.MoveStartPayload{UnitId: worker.Id, Point: image.Pt(3, 3)},
+		Envelope: game.Envelope{RequestId: 7},
+	}
+	c1.send(t, move)
+	await(t, c1, func(a game.AckAction) bool { return a.RequestId == 7 && !a.Payload.Duplicate })
+	_ = c1.Close()
+
+	c2 := dialTestClient(t, url, "player1")
+	c2.rejoin(t, room, c1.player, session)
+	c2.send(t, move)
+	retried := await(t, c2, func(a game.AckAction) bool { return a.RequestId == 7 })
+
+	if !retried.Payload.Duplicate {
+		t.Error("expected the retried request acknowledged as a duplicate")
+	}
+}
+
+func TestServer_StalledClient_DoesNotStopTheRoom(t *testing.T) {
+	_, url := startTestServer(t, landFunc(plain))
+	c2 := dialTestClient(t, url, "player2")
+	room := c2.createRoom(t, game.RoomConfig{SpawnPoints: []image.Point{{0, 0}, {6, 0}}})
+	c2.join(t)
+	worker := c2.ownUnit(t, game.WorkerType)
+
+	// c1 never reads while its maps pile up in the connection
+	c1 := dialStalledClient(t, url, "player1")
+	c1.send(t, game.RoomJoinAction{Type: game.RoomJoinActionType, Payload: game.RoomJoinPayload{RoomId: room}})
+	c1.send(t, game.PlayerJoinAction{Type: game.PlayerJoinActionType, Payload: c1.player})
+	for range 1000 {
+		c1.send(t, game.NewMapLoadAction(image.Rect(0, 0, 64, 64), c1.player.Id))
+	}
+	time.Sleep(time.Second)
+	c2.send(t, game.MoveStartAction{
+		Type:    game.MoveStartActionType,
+		Payload: game.MoveStartPayload{UnitId: worker.Id, Point: image.Pt(6, 10)},
+	})
+
+	await(t, c2, func(a game.MoveStepAction) bool { return a.Payload.UnitId == worker.Id })
+}
+
+// stallingProvider - plain map whose chunks from x to the east do not load until released
+type stallingProvider struct {
+	x       int
+	release chan struct{}
+}
+
+func (p stallingProvider) Load(r world.WorldRequest) (*world.WorldResponse, error) {
+	if r.MaxX >= p.x {
+		<-p.release
+	}
+	return landFunc(plain).Load(r)
+}
+
+func TestServer_StalledWorldService_DoesNotStopTheRoom(t *testing.T) {
+	provider := stallingProvider{x: world.ChunkSize, release: make(chan struct{})}
+	_, url := startTestServer(t, provider)
+	t.Cleanup(func() { close(provider.release) })
+	c := dialTestClient(t, url, "player1")
+	c.join(t)
+	worker := c.ownUnit(t, game.WorkerType)
+
+	// the path goes over the chunk which never arrives
+	c.send(t, game.MoveStartAction{
+		Type:    game.MoveStartActionType,
+		Payload: game.MoveStartPayload{UnitId: worker.Id, Point: image.Pt(world.ChunkSize+8, 0)},
+	})
+	c.send(t, game.MoveStartAction{
+		Type:    game.MoveStartActionType,
+		Payload: game.MoveStartPayload{UnitId: worker.Id, Point: image.Pt(3, 3)},
+	})
+
+	await(t, c, func(a game.MoveStepAction) bool {
+		path := a.Payload.Path
+		return a.Payload.UnitId == worker.Id && len(path) > 0 && path[len(path)-1] == image.Pt(3, 3)
+	})
+}
+
+// failingWest - plain map whose chunks west of x=0 fail to load
+type failingWest struct{}
+
+func (failingWest) Load(r world.WorldRequest) (*world.WorldResponse, error) {
+	if r.MinX < 0 {
+		return nil, errors.New("world service down")
+	}
+	return landFunc(plain).Load(r)
+}
+
+func TestServer_MapLoad_ReportsFailedChunksAlone(t *testing.T) {
+	_, url := startTestServer(t, failingWest{})
+	c := dialTestClient(t, url, "player1")
+	c.join(t)
+
+	c.send(t, game.NewMapLoadAction(image.Rect(-world.ChunkSize, 0, world.ChunkSize, world.ChunkSize), c.player.Id))
+
+	await(t, c, func(a game.ErrorAction) bool { return a.Payload.Code == game.ErrorMapLoad })
+	await(t, c, func(a game.MapLoadSuccessAction) bool { return a.Payload.MinX == 0 })
+}
+
+func TestServer_MapLoad_SlowChunkDoesNotStopTheRoom(t *testing.T) {
+	provider := stallingProvider{x: 4 * world.ChunkSize, release: make(chan struct{})}
+	_, url := startTestServer(t, provider)
+	t.Cleanup(func() { close(provider.release) })
+	c := dialTestClient(t, url, "player1")
+	c.join(t)
+	worker := c.ownUnit(t, game.WorkerType)
+
+	c.send(t, game.NewMapLoadAction(image.Rect(0, 0, 5*world.ChunkSize, world.ChunkSize), c.player.Id))
+	await(t, c, func(a game.MapLoadSuccessAction) bool { return a.Payload.MinX == 0 })
+	c.send(t, game.MoveStartAction{
+		Type:    game.MoveStartActionType,
+		Payload: game.MoveStartPayload{UnitId: worker.Id, Point: image.Pt(3, 3)},
+	})
+
+	await(t, c, func(a game.MoveStepAction) bool { return a.Payload.UnitId == worker.Id })
+}
+
+func TestServer_PlayerJoin_RejectedJoinsHoldNoSeat(t *testing.T) {
+	_, url := startTestServer(t, landFunc(plain))
+	c1 := dialTestClient(t, url, "player1")
+	room := c1.createRoom(t, game.RoomConfig{MaxPlayers: 3, SpawnPoints: []image.Point{{0, 0}, {6, 0}, {0, 6}}})
+	c1.join(t)
+
+	thief := dialTestClient(t, url, "thief")
+	thief.joinRoom(t, room)
+	thief.join(t)
+	for range 3 {
+		// a connection plays one player, one without a session or another one's
+		other := game.Player{Id: game.PlayerIdType(game.NewUnitId()), Name: "thief"}
+		thief.send(t, game.PlayerJoinAction{Type: game.PlayerJoinActionType, Payload: other})
+		thief.send(t, game.PlayerJoinAction{Type: game.PlayerJoinActionType, Payload: c1.player})
+	}
+	for range 6 {
+		await(t, thief, func(a game.ActionRejectedAction) bool { return a.Payload.Action == game.PlayerJoinActionType })
+	}
+
+	c2 := dialTestClient(t, url, "player2")
+	c2.joinRoom(t, room)
+	c2.join(t)
+}
+
+func TestServer_PlayerRejoin_BadSessionHoldsNoSeat(t *testing.T) {
+	_, url := startTestServer(t, landFunc(plain))
+	c1 := dialTestClient(t, url, "player1")
+	room := c1.createRoom(t, game.RoomConfig{MaxPlayers: 2, SpawnPoints: []image.Point{{0, 0}, {6, 0}}})
+	c1.join(t)
+
+	thief := dialTestClient(t, url, "thief")
+	thief.joinRoom(t, room)
+	for range 2 {
+		thief.send(t, game.PlayerRejoinAction{
+			Type:    game.PlayerRejoinActionType,
+			Payload: game.PlayerRejoinPayload{Player: c1.player, SessionToken: "stolen"},
+		})
+		rejected := await(t, thief, func(a game.ActionRejectedAction) bool {
+			return a.Payload.Action == game.PlayerRejoinActionType
+		})
+		if rejected.Payload.Code != game.ErrorBadSession {
+			t.Errorf("expected %s, got %s", game.ErrorBadSession, rejected.Payload.Code)
+		}
+	}
+
+	c2 := dialTestClient(t, url, "player2")
+	c2.joinRoom(t, room)
+	c2.join(t)
+}
+
+func TestServer_RoomCreate_RejectsSpawnPointsOutOfBounds(t *testing.T) {
+	_, url := startTestServer(t, landFunc(plain))
+	c := dialTestClient(t, url, "player1")
+
+	c.send(t, game.RoomCreateAction{
+		Type:    game.RoomCreateActionType,
+		Payload: game.RoomConfig{SpawnPoints: []image.Point{{0, 0}, {-game.MaxCoordinate - 1, 0}}},
+	})
+	rejected := await(t, c, func(a game.ActionRejectedAction) bool { return a.Payload.Action == game.RoomCreateActionType })
+
+	if rejected.Payload.Code != game.ErrorInvalid {
+		t.Errorf("expected %s, got %s", game.ErrorInvalid, rejected.Payload.Code)
+	}
+}
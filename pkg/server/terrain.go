@@ -0,0 +1,79 @@
+package server
+
+import (
+	"image"
+	"log"
+	"time"
+
+	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/world"
+)
+
+// terrainRetry - how long a chunk which failed to load is left alone
+const terrainRetry = 5 * time.Second
+
+// fetchFunc - loads the chunks one by one off the room's loop, done is called on the loop for each chunk
+type fetchFunc func(ids []world.ChunkId, done func(id world.ChunkId, tiles []world.Tile, err error))
+
+// terrainStore - store of a room loading the terrain of a chunk when any of its tiles is first used,
+// paths, placement and sight follow the real map wherever the units go, not only where the clients looked;
+// the loop does not wait for the chunk, its tiles are unknown until it arrives
+type terrainStore struct {
+	game.Store
+	fetch   fetchFunc
+	loaded  map[world.ChunkId]bool      // loop-owned
+	loading map[world.ChunkId]bool      // loop-owned, chunks being fetched
+	failed  map[world.ChunkId]time.Time // loop-owned, last failed load of the chunk
+}
+
+func newTerrainStore(store game.Store, fetch fetchFunc) *terrainStore {
+	return &terrainStore{
+		Store:   store,
+		fetch:   fetch,
+		loaded:  make(map[world.ChunkId]bool),
+		loading: make(map[world.ChunkId]bool),
+		failed:  make(map[world.ChunkId]time.Time),
+	}
+}
+
+// GetTile - tile with its terrain once the chunk of the tile is loaded
+func (s *terrainStore) GetTile(p image.Point) (*game.Tile, bool) {
+	s.load(world.ChunkOf(p))
+	return s.Store.GetTile(p)
+}
+
+// CreateTile - tile taken by a unit before anything looked at it, the terrain comes with the chunk
+func (s *terrainStore) CreateTile(p image.Point) *game.Tile {
+	s.load(world.ChunkOf(p))
+	if t, ok := s.Store.GetTile(p); ok {
+		return t
+	}
+	return s.Store.CreateTile(p)
+}
+
+// load - starts loading the chunk unless it is already there or on its way
+func (s *terrainStore) load(id world.ChunkId) {
+	if s.fetch == nil || s.loaded[id] || s.loading[id] {
+		return
+	}
+	if at, ok := s.failed[id]; ok && time.Since(at) < terrainRetry {
+		return
+	}
+	s.loading[id] = true
+	s.fetch([]world.ChunkId{id}, s.store)
+}
+
+// store - stores the tiles of the loaded chunk, the units on them stay
+func (s *terrainStore) store(id world.ChunkId, tiles []world.Tile, err error) {
+	delete(s.loading, id)
+	if err != nil {
+		log.Printf("terrain of chunk %v: %v", id, err)
+		s.failed[id] = time.Now()
+		return
+	}
+	delete(s.failed, id)
+	s.loaded[id] = true
+	for _, t := range tiles {
+		s.Store.StoreTile(t)
+	}
+}
@@ -0,0 +1,155 @@
+package world
+
+import (
+	"container/list"
+	"image"
+	"sync"
+)
+
+// ChunkSize - width and height of a chunk in tiles, the map is loaded and cached by whole chunks
+const ChunkSize = 32
+
+// ChunkId - position of the chunk in the grid of chunks
+type ChunkId image.Point
+
+// ChunkOf - chunk containing the tile
+func ChunkOf(p image.Point) ChunkId {
+	return ChunkId{X: floorDiv(p.X, ChunkSize), Y: floorDiv(p.Y, ChunkSize)}
+}
+
+// ChunksIn - chunks overlapping the rect of tiles, the rect's max is exclusive
+func ChunksIn(rect image.Rectangle) []ChunkId {
+	rect = rect.Canon()
+	if rect.Empty() {
+		return nil
+	}
+	minC := ChunkOf(rect.Min)
+	maxC := ChunkOf(rect.Max.Sub(image.Pt(1, 1)))
+	r := make([]ChunkId, 0, (maxC.X-minC.X+1)*(maxC.Y-minC.Y+1))
+	for y := minC.Y; y <= maxC.Y; y++ {
+		for x := minC.X; x <= maxC.X; x++ {
+			r = append(r, ChunkId{X: x, Y: y})
+		}
+	}
+	return r
+}
+
+// Rect - tiles of the chunk, the max is exclusive
+func (c ChunkId) Rect() image.Rectangle {
+	minP := image.Pt(c.X*ChunkSize, c.Y*ChunkSize)
+	return image.Rectangle{Min: minP, Max: minP.Add(image.Pt(ChunkSize, ChunkSize))}
+}
+
+// Request - world request of the chunk, the max of the requests is inclusive
+func (c ChunkId) Request() WorldRequest {
+	return NewWorldRequest(c.Rect())
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+// ChunkCache - WorldProvider keeping the recently used chunks of another provider.
+// The least recently used chunks are evicted over the capacity, concurrent loads of a chunk are coalesced.
+type ChunkCache struct {
+	provider WorldProvider
+	capacity int
+
+	mux      sync.Mutex
+	chunks   map[ChunkId]*list.Element // values are *cachedChunk
+	lru      *list.List                // most recently used in the front
+	inflight map[ChunkId]*chunkLoad
+}
+
+type cachedChunk struct {
+	id    ChunkId
+	tiles []Tile
+}
+
+// chunkLoad - load of the chunk in progress, waited for by all its requesters
+type chunkLoad struct {
+	done  chan struct{}
+	tiles []Tile
+	err   error
+}
+
+func NewChunkCache(provider WorldProvider, capacity int) *ChunkCache {
+	return &ChunkCache{
+		provider: provider,
+		capacity: capacity,
+		chunks:   make(map[ChunkId]*list.Element),
+		lru:      list.New(),
+		inflight: make(map[ChunkId]*chunkLoad),
+	}
+}
+
+// Chunk - tiles of the chunk, the returned slice is shared and must not be modified
+func (c *ChunkCache) Chunk(id ChunkId) ([]Tile, error) {
+	c.mux.Lock()
+	if e, ok := c.chunks[id]; ok {
+		c.lru.MoveToFront(e)
+		c.mux.Unlock()
+		return e.Value.(*cachedChunk).tiles, nil
+	}
+	if load, ok := c.inflight[id]; ok {
+		c.mux.Unlock()
+		<-load.done
+		return load.tiles, load.err
+	}
+	load := &chunkLoad{done: make(chan struct{})}
+	c.inflight[id] = load
+	c.mux.Unlock()
+
+	resp, err := c.provider.Load(id.Request())
+	if err == nil {
+		load.tiles = resp.Tiles
+	}
+	load.err = err
+
+	c.mux.Lock()
+	delete(c.inflight, id)
+	if err == nil {
+		c.store(id, load.tiles)
+	}
+	c.mux.Unlock()
+	close(load.done)
+	return load.tiles, load.err
+}
+
+func (c *ChunkCache) store(id ChunkId, tiles []Tile) {
+	c.chunks[id] = c.lru.PushFront(&cachedChunk{id: id, tiles: tiles})
+	for c.lru.Len() > c.capacity {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.chunks, oldest.Value.(*cachedChunk).id)
+	}
+}
+
+// Len - number of cached chunks
+func (c *ChunkCache) Len() int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.lru.Len()
+}
+
+// Load - tiles of the rect assembled from the cached chunks
+func (c *ChunkCache) Load(request WorldRequest) (*WorldResponse, error) {
+	rect := request.Rect()
+	resp := &WorldResponse{MinX: request.MinX, MinY: request.MinY, MaxX: request.MaxX, MaxY: request.MaxY}
+	for _, id := range ChunksIn(rect) {
+		tiles, err := c.Chunk(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tiles {
+			if t.Point.In(rect) {
+				resp.Tiles = append(resp.Tiles, t)
+			}
+		}
+	}
+	return resp, nil
+}
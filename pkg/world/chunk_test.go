@@ -0,0 +1,158 @@
+package world_test
+
+import (
+	"errors"
+	"image"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bmcszk/fogofgo/pkg/world"
+)
+
+// countingProvider - generator counting the loads, blocks them until released when the gate is set
+type countingProvider struct {
+	generator *world.Generator
+	loads     atomic.Int32
+	gate      chan struct{}
+	err       error
+}
+
+func (p *countingProvider) Load(request world.WorldRequest) (*world.WorldResponse, error) {
+	p.loads.Add(1)
+	if p.gate != nil {
+		<-p.gate
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.generator.Load(request)
+}
+
+func TestChunkOf(t *testing.T) {
+	tests := []struct {
+		point image.Point
+		chunk world.ChunkId
+	}{
+		{point: image.Pt(0, 0), chunk: world.ChunkId{X: 0, Y: 0}},
+		{point: image.Pt(world.ChunkSize-1, world.ChunkSize), chunk: world.ChunkId{X: 0, Y: 1}},
+		{point: image.Pt(-1, -world.ChunkSize), chunk: world.ChunkId{X: -1, Y: -1}},
+		{point: image.Pt(-world.ChunkSize-1, 5), chunk: world.ChunkId{X: -2, Y: 0}},
+	}
+	for _, tt := range tests {
+		chunk := world.ChunkOf(tt.point)
+
+		if chunk != tt.chunk {
+			t.Errorf("expected %v to be in chunk %v, got %v", tt.point, tt.chunk, chunk)
+		}
+		if !tt.point.In(chunk.Rect()) {
+			t.Errorf("expected %v in rect of its chunk %v", tt.point, chunk.Rect())
+		}
+	}
+}
+
+func TestChunksIn(t *testing.T) {
+	chunks := world.ChunksIn(image.Rect(-1, 0, world.ChunkSize, 1))
+
+	expected := []world.ChunkId{{X: -1, Y: 0}, {X: 0, Y: 0}}
+	if len(chunks) != len(expected) || chunks[0] != expected[0] || chunks[1] != expected[1] {
+		t.Errorf("expected %v, got %v", expected, chunks)
+	}
+	if chunks := world.ChunksIn(image.Rectangle{}); len(chunks) != 0 {
+		t.Errorf("expected no chunks in empty rect, got %v", chunks)
+	}
+}
+
+func TestChunkCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	provider := &countingProvider{generator: world.NewGenerator(42)}
+	cache := world.NewChunkCache(provider, 2)
+	first, second, third := world.ChunkId{X: 0, Y: 0}, world.ChunkId{X: 1, Y: 0}, world.ChunkId{X: 2, Y: 0}
+
+	for _, id := range []world.ChunkId{first, second, first, third} {
+		if _, err := cache.Chunk(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if provider.loads.Load() != 3 {
+		t.Fatalf("expected 3 loads, got %d", provider.loads.Load())
+	}
+	if cache.Len() != 2 {
+		t.Fatalf("expected 2 cached chunks, got %d", cache.Len())
+	}
+
+	// the second chunk was the least recently used one
+	if _, err := cache.Chunk(first); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Chunk(second); err != nil {
+		t.Fatal(err)
+	}
+	if provider.loads.Load() != 4 {
+		t.Errorf("expected only the evicted chunk to be loaded again, got %d loads", provider.loads.Load())
+	}
+}
+
+func TestChunkCache_CoalescesConcurrentLoads(t *testing.T) {
+	provider := &countingProvider{generator: world.NewGenerator(42), gate: make(chan struct{})}
+	cache := world.NewChunkCache(provider, 8)
+
+	var wg sync.WaitGroup
+	results := make([][]world.Tile, 5)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tiles, err := cache.Chunk(world.ChunkId{X: 3, Y: -2})
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = tiles
+		}()
+	}
+	// let the requesters queue up behind the first load
+	time.Sleep(10 * time.Millisecond)
+	close(provider.gate)
+	wg.Wait()
+
+	if provider.loads.Load() != 1 {
+		t.Errorf("expected a single load, got %d", provider.loads.Load())
+	}
+	for _, tiles := range results {
+		if len(tiles) != world.ChunkSize*world.ChunkSize {
+			t.Errorf("expected a full chunk, got %d tiles", len(tiles))
+		}
+	}
+}
+
+func TestChunkCache_DoesNotCacheErrors(t *testing.T) {
+	provider := &countingProvider{generator: world.NewGenerator(42), err: errors.New("world service down")}
+	cache := world.NewChunkCache(provider, 8)
+
+	if _, err := cache.Chunk(world.ChunkId{}); err == nil {
+		t.Fatal("expected error")
+	}
+	provider.err = nil
+	if _, err := cache.Chunk(world.ChunkId{}); err != nil {
+		t.Fatalf("expected the retry to succeed, got %v", err)
+	}
+	if provider.loads.Load() != 2 {
+		t.Errorf("expected 2 loads, got %d", provider.loads.Load())
+	}
+}
+
+func TestChunkCache_Load(t *testing.T) {
+	cache := world.NewChunkCache(world.NewGenerator(42), 8)
+
+	resp, err := cache.Load(world.WorldRequest{MinX: -5, MinY: -5, MaxX: 4, MaxY: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.Tiles) != 100 {
+		t.Fatalf("expected 100 tiles, got %d", len(resp.Tiles))
+	}
+	if cache.Len() != 4 {
+		t.Errorf("expected the 4 chunks around the origin to be cached, got %d", cache.Len())
+	}
+}
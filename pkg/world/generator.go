@@ -0,0 +1,166 @@
+package world
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+)
+
+const (
+	maxGeneratedTiles = 1 << 16
+	// maxGroundLevel - ground level of the highest peaks
+	maxGroundLevel = 200
+	seaLevel       = 80
+	// scales of the noise layers, in tiles
+	elevationScale = 48
+	moistureScale  = 24
+	riverScale     = 64
+	riverWidth     = 0.006
+	styleVariants  = 3
+)
+
+// Generator - WorldProvider generating a seeded, reproducible world in-process
+type Generator struct {
+	seed uint64
+}
+
+func NewGenerator(seed int64) *Generator {
+	return &Generator{
+		seed: uint64(seed),
+	}
+}
+
+func (g *Generator) Load(request WorldRequest) (*WorldResponse, error) {
+	width, height := request.MaxX-request.MinX+1, request.MaxY-request.MinY+1
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("empty request")
+	}
+	if width*height > maxGeneratedTiles {
+		return nil, fmt.Errorf("request too large: %dx%d", width, height)
+	}
+	tiles := make([]Tile, 0, width*height)
+	for x := request.MinX; x <= request.MaxX; x++ {
+		for y := request.MinY; y <= request.MaxY; y++ {
+			tiles = append(tiles, g.Tile(image.Pt(x, y)))
+		}
+	}
+	return &WorldResponse{
+		Tiles: tiles,
+		MinX:  request.MinX,
+		MinY:  request.MinY,
+		MaxX:  request.MaxX,
+		MaxY:  request.MaxY,
+	}, nil
+}
+
+// Tile - generates a single tile, the same seed and point always give the same tile
+func (g *Generator) Tile(p image.Point) Tile {
+	elevation := g.fractal(p, elevationScale, 0)
+	moisture := g.fractal(p, moistureScale, 1)
+	river := math.Abs(g.fractal(p, riverScale, 2) - 0.5)
+
+	groundLevel := int(elevation * maxGroundLevel)
+	landType := landType(groundLevel, moisture, river)
+
+	t := Tile{
+		Point:           p,
+		Value:           landType,
+		LandType:        landType,
+		FrontStyleClass: fmt.Sprintf("%s%d", landType, 1+g.hash(p.X, p.Y, 3)%styleVariants),
+		BackStyleClass:  backStyleClass(landType),
+		GroundLevel:     groundLevel,
+	}
+	switch landType {
+	case LandSea, LandLake:
+		depth := max(seaLevel-groundLevel, 1)
+		t.WaterLevel = &depth
+		t.PostGlacial = landType == LandLake && moisture > 0.5
+	case LandRiver:
+		depth := 1
+		t.WaterLevel = &depth
+	}
+	return t
+}
+
+func landType(groundLevel int, moisture, river float64) string {
+	switch {
+	case groundLevel < seaLevel-15:
+		return LandSea
+	case groundLevel < seaLevel:
+		return LandLake
+	case groundLevel < seaLevel+5:
+		return LandSand
+	case groundLevel > 155:
+		return LandMountain
+	case river < riverWidth && groundLevel < 140:
+		return LandRiver
+	case groundLevel > 138:
+		return LandHill
+	case moisture > 0.55:
+		return LandForest
+	default:
+		return LandPlain
+	}
+}
+
+func backStyleClass(landType string) string {
+	switch landType {
+	case LandSea, LandLake, LandRiver:
+		return "water"
+	case LandSand:
+		return LandSand
+	default:
+		return "grass"
+	}
+}
+
+// fractal - sum of value noise octaves in range [0, 1)
+func (g *Generator) fractal(p image.Point, scale float64, layer uint64) float64 {
+	sum, amplitude, total := 0.0, 1.0, 0.0
+	for octave := range uint64(4) {
+		frequency := float64(uint64(1) << octave)
+		// shift the octaves against each other to hide the lattice
+		shift := float64(octave) * 31.7
+		x, y := float64(p.X)*frequency/scale+shift, float64(p.Y)*frequency/scale-shift
+		sum += amplitude * g.noise(x, y, layer*8+octave)
+		total += amplitude
+		amplitude /= 2
+	}
+	return sum / total
+}
+
+// noise - smoothly interpolated value noise
+func (g *Generator) noise(x, y float64, layer uint64) float64 {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	ix, iy := int(x0), int(y0)
+	fx, fy := smoothstep(x-x0), smoothstep(y-y0)
+
+	v00 := g.lattice(ix, iy, layer)
+	v10 := g.lattice(ix+1, iy, layer)
+	v01 := g.lattice(ix, iy+1, layer)
+	v11 := g.lattice(ix+1, iy+1, layer)
+
+	top := v00 + (v10-v00)*fx
+	bottom := v01 + (v11-v01)*fx
+	return top + (bottom-top)*fy
+}
+
+func (g *Generator) lattice(x, y int, layer uint64) float64 {
+	return float64(g.hash(x, y, layer)>>11) / float64(uint64(1)<<53)
+}
+
+// hash - splitmix64 of the seed, coordinates and layer
+func (g *Generator) hash(x, y int, layer uint64) uint64 {
+	h := g.seed ^ uint64(int64(x))*0x9E3779B97F4A7C15 ^ uint64(int64(y))*0xC2B2AE3D27D4EB4F ^ layer*0x165667B19E3779F9
+	h ^= h >> 30
+	h *= 0xBF58476D1CE4E5B9
+	h ^= h >> 27
+	h *= 0x94D049BB133111EB
+	h ^= h >> 31
+	return h
+}
+
+func smoothstep(t float64) float64 {
+	return t * t * (3 - 2*t)
+}
@@ -0,0 +1,120 @@
+package world_test
+
+import (
+	"encoding/json"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bmcszk/fogofgo/pkg/world"
+)
+
+var landTypes = []string{
+	world.LandPlain, world.LandForest, world.LandHill, world.LandMountain,
+	world.LandSand, world.LandSea, world.LandLake, world.LandRiver,
+}
+
+func TestGenerator_Load(t *testing.T) {
+	var provider world.WorldProvider = world.NewGenerator(42)
+
+	resp, err := provider.Load(world.WorldRequest{MinX: -5, MinY: -5, MaxX: 4, MaxY: 4})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(resp.Tiles) != 100 {
+		t.Fatalf("expected 100 tiles, got %d", len(resp.Tiles))
+	}
+	seen := make(map[image.Point]bool)
+	for _, tile := range resp.Tiles {
+		if !tile.Point.In(image.Rect(-5, -5, 5, 5)) {
+			t.Errorf("tile %v outside of requested rect", tile.Point)
+		}
+		seen[tile.Point] = true
+	}
+	if len(seen) != 100 {
+		t.Errorf("expected 100 distinct points, got %d", len(seen))
+	}
+}
+
+func TestGenerator_Reproducible(t *testing.T) {
+	request := world.WorldRequest{MinX: 100, MinY: 100, MaxX: 120, MaxY: 120}
+
+	resp1, _ := world.NewGenerator(7).Load(request)
+	resp2, _ := world.NewGenerator(7).Load(request)
+	resp3, _ := world.NewGenerator(8).Load(request)
+
+	same, different := true, false
+	for i := range resp1.Tiles {
+		same = same && resp1.Tiles[i].FrontStyleClass == resp2.Tiles[i].FrontStyleClass &&
+			resp1.Tiles[i].GroundLevel == resp2.Tiles[i].GroundLevel
+		different = different || resp1.Tiles[i].GroundLevel != resp3.Tiles[i].GroundLevel
+	}
+	if !same {
+		t.Error("same seed should generate the same map")
+	}
+	if !different {
+		t.Error("different seeds should generate different maps")
+	}
+}
+
+func TestGenerator_TileFields(t *testing.T) {
+	generator := world.NewGenerator(1)
+
+	counts := make(map[string]int)
+	for x := range 200 {
+		for y := range 200 {
+			tile := generator.Tile(image.Pt(x, y))
+			counts[tile.LandType]++
+			assertTileConsistent(t, tile)
+		}
+	}
+
+	for _, landType := range landTypes {
+		if counts[landType] == 0 {
+			t.Errorf("expected some %s tiles", landType)
+		}
+	}
+}
+
+func assertTileConsistent(t *testing.T, tile world.Tile) {
+	t.Helper()
+	water := tile.LandType == world.LandSea || tile.LandType == world.LandLake || tile.LandType == world.LandRiver
+	if water != (tile.WaterLevel != nil) {
+		t.Fatalf("tile %v of type %s has water level %v", tile.Point, tile.LandType, tile.WaterLevel)
+	}
+	if water && tile.BackStyleClass != "water" {
+		t.Fatalf("water tile %v has back style %s", tile.Point, tile.BackStyleClass)
+	}
+	variant := tile.FrontStyleClass[len(tile.LandType):]
+	if tile.FrontStyleClass[:len(tile.LandType)] != tile.LandType || variant < "1" || variant > "3" {
+		t.Fatalf("unexpected front style %s for %s", tile.FrontStyleClass, tile.LandType)
+	}
+}
+
+func TestGenerator_Load_TooLarge(t *testing.T) {
+	_, err := world.NewGenerator(1).Load(world.WorldRequest{MinX: 0, MinY: 0, MaxX: 1000, MaxY: 1000})
+	if err == nil {
+		t.Error("expected error for too large request")
+	}
+}
+
+func TestWorldService_Load_FromServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(world.WorldResponse{
+			Tiles: []world.Tile{{Point: image.Pt(0, 0), LandType: world.LandPlain}},
+		})
+	}))
+	defer server.Close()
+
+	var provider world.WorldProvider = world.NewWorldServiceAt(server.URL)
+
+	resp, err := provider.Load(world.WorldRequest{MinX: 0, MinY: 0, MaxX: 0, MaxY: 0})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(resp.Tiles) != 1 || resp.Tiles[0].LandType != world.LandPlain {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
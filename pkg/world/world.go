@@ -8,17 +8,50 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"time"
 )
 
+// worldTimeout - how long a request to the world service may take, a stalled service fails the chunk
+const worldTimeout = 10 * time.Second
+
+// Land types of the tiles
+const (
+	LandPlain    = "plain"
+	LandForest   = "forest"
+	LandHill     = "hill"
+	LandMountain = "mountain"
+	LandSand     = "sand"
+	LandSea      = "sea"
+	LandLake     = "lake"
+	LandRiver    = "river"
+)
+
+// WorldProvider - source of the map tiles
+type WorldProvider interface {
+	Load(request WorldRequest) (*WorldResponse, error)
+}
+
+// WorldService - WorldProvider backed by the external world service HTTP API
 type WorldService struct {
 	serverAddress string
 	client        *http.Client
 }
 
+// WorldRequest - rect of the tiles to load, both its min and its max are inclusive
 type WorldRequest struct {
 	MinX, MinY, MaxX, MaxY int
 }
 
+// NewWorldRequest - request of the tiles of the rect, the rect's max is exclusive
+func NewWorldRequest(rect image.Rectangle) WorldRequest {
+	return WorldRequest{MinX: rect.Min.X, MinY: rect.Min.Y, MaxX: rect.Max.X - 1, MaxY: rect.Max.Y - 1}
+}
+
+// Rect - requested tiles as a rect with the exclusive max
+func (r WorldRequest) Rect() image.Rectangle {
+	return image.Rect(r.MinX, r.MinY, r.MaxX+1, r.MaxY+1)
+}
+
 type WorldResponse struct {
 	Tiles []Tile `json:"map"`
 	MinX  int    `json:"minX"`
@@ -39,9 +72,13 @@ type Tile struct {
 }
 
 func NewWorldService() WorldService {
+	return NewWorldServiceAt("http://localhost:8080")
+}
+
+func NewWorldServiceAt(serverAddress string) WorldService {
 	return WorldService{
-		serverAddress: "http://localhost:8080",
-		client:        http.DefaultClient,
+		serverAddress: serverAddress,
+		client:        &http.Client{Timeout: worldTimeout},
 	}
 }
 
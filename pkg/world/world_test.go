@@ -30,6 +30,20 @@ func TestWorldRequest(t *testing.T) {
 	}
 }
 
+func TestNewWorldRequest(t *testing.T) {
+	rect := image.Rect(-3, 2, -2, 5)
+
+	request := world.NewWorldRequest(rect)
+
+	// a column of three tiles
+	if request != (world.WorldRequest{MinX: -3, MinY: 2, MaxX: -3, MaxY: 4}) {
+		t.Errorf("expected the max inclusive, got %+v", request)
+	}
+	if request.Rect() != rect {
+		t.Errorf("expected %v back, got %v", rect, request.Rect())
+	}
+}
+
 func TestTile(t *testing.T) {
 	waterLevel := 5
 	tile := world.Tile{
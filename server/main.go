@@ -1,139 +1,105 @@
 package main
 
 import (
+	"expvar"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/bmcszk/fogofgo/pkg/comm"
 	"github.com/bmcszk/fogofgo/pkg/game"
+	"github.com/bmcszk/fogofgo/pkg/server"
 	"github.com/bmcszk/fogofgo/pkg/world"
-	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{}
-
-type server struct {
-	game    *serverGame
-	clients map[game.PlayerIdType]*comm.Client
-}
-
-func newServer(g *serverGame) *server {
-	return &server{
-		game:    g,
-		clients: make(map[game.PlayerIdType]*comm.Client, 0), // connected clients,
-	}
-}
-
 func main() {
-	s := newServer(newServerGame(game.NewStoreImpl(), world.NewWorldService()))
-
-	// Configure websocket route
-	http.HandleFunc("/ws", s.handleConnections)
-
-	// Start the server on localhost port 8000 and log any errors
-	log.Println("http server started on :8000")
-	err := http.ListenAndServe(":8000", nil)
+	worldFlag := flag.String("world", "local",
+		`world provider: "local" to generate the map in-process or the world service address`)
+	seed := flag.Int64("seed", 1, "seed of the locally generated world")
+	recordDir := flag.String("record", "", "directory to record the matches to, for replay in the client")
+	unitsFile := flag.String("units", "", "JSON file with the unit types, the bundled ones when empty")
+	chunkCache := flag.Int("chunk-cache", 1024, "number of map chunks kept in memory for all rooms")
+	dataDir := flag.String("data", "", "directory to persist the matches to, restored on start")
+	debugAddr := flag.String("debug", "",
+		"address of the debug listener serving the memory on /debug/vars for the load test, off when empty")
+	flag.Parse()
+
+	types, err := loadUnitTypes(*unitsFile)
 	if err != nil {
-		log.Fatal("ListenAndServe: ", err)
-	}
-}
-
-func (s *server) handleConnections(w http.ResponseWriter, r *http.Request) {
-	// Upgrade initial GET request to a websocket
-	ws, err := upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		log.Printf("Failed to upgrade connection: %v", err)
-		return
+		log.Fatal(err)
 	}
-	// Make sure we close the connection when the function returns
-	defer func() {
-		if err := ws.Close(); err != nil {
-			log.Printf("Error closing websocket: %v", err)
-		}
-	}()
-
-	// Register our new client
-	client := comm.NewClient(ws)
-
-	for client.Connected {
-		action, err := client.HandleInMessages()
+	chunks := world.NewChunkCache(newWorldProvider(*worldFlag, *seed), *chunkCache)
+	srv := server.New(chunks, types, *recordDir)
+	if *dataDir != "" {
+		snapshots, err := game.NewFileSnapshots(*dataDir)
 		if err != nil {
+			log.Fatal(err)
+		}
+		if err := srv.Persist(snapshots); err != nil {
 			log.Println(err)
-			continue
 		}
-		s.processAction(client, action)
 	}
-}
 
-func (s *server) processAction(client *comm.Client, action game.Action) {
-	// register new player
-	if action.GetType() == game.PlayerJoinActionType {
-		client.PlayerId = action.GetPayload().(game.Player).Id
-		s.clients[client.PlayerId] = client
+	// save the matches before the server goes down
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		srv.Shutdown()
+		os.Exit(0)
+	}()
+
+	if *debugAddr != "" {
+		go serveDebug(*debugAddr)
 	}
 
-	// broadcast action to others
-	s.broadcastOthers(client, action)
+	// Configure websocket route, not on the default mux where expvar publishes its variables
+	mux := http.NewServeMux()
+	mux.Handle("/ws", srv)
 
-	// synchronous dispatch func
-	dispatch := func(a game.Action) {
-		if err := s.route(client, a); err != nil {
-			log.Println(err)
-		}
+	// Start the server on localhost port 8000 and log any errors
+	log.Println("http server started on :8000")
+	if err := http.ListenAndServe(":8000", mux); err != nil {
+		log.Fatal("ListenAndServe: ", err)
 	}
-
-	// action handling
-	s.game.HandleAction(action, dispatch)
 }
 
-func (s *server) broadcastOthers(client *comm.Client, action game.Action) {
-	switch action.(type) {
-	case game.PlayerJoinAction, game.MapLoadAction:
-		return
-	}
-	for _, c := range s.clients {
-		if c != client {
-			err := c.Send(action)
-			if err != nil {
-				log.Println(err)
-			}
-		}
+// serveDebug - memory and runtime variables of the server on a listener apart from the game port
+func serveDebug(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	log.Printf("debug server started on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Println("debug server: ", err)
 	}
 }
 
-func (s *server) broadcastAll(action game.Action) {
-	for _, c := range s.clients {
-		err := c.Send(action)
-		if err != nil {
-			log.Println(err)
-		}
+func newWorldProvider(address string, seed int64) world.WorldProvider {
+	if address == "local" {
+		log.Printf("generating world with seed %d", seed)
+		return world.NewGenerator(seed)
 	}
+	log.Printf("using world service at %s", address)
+	return world.NewWorldServiceAt(address)
 }
 
-// route - handler of outgoing actions
-func (s *server) route(c *comm.Client, action game.Action) error {
-	dispatch := func(a game.Action) {
-		if err := s.route(c, a); err != nil {
-			log.Println(err)
-		}
+// loadUnitTypes - unit types of the matches, every match needs the headquarters and the worker
+func loadUnitTypes(path string) (*game.UnitTypes, error) {
+	if path == "" {
+		return game.DefaultUnitTypes(), nil
 	}
-	switch a := action.(type) {
-	case game.MoveStepAction, game.MoveStopAction, game.SpawnUnitAction:
-		s.broadcastAll(a)
-		s.game.HandleAction(a, dispatch)
-	case game.PlayerJoinSuccessAction:
-		if err := c.Send(action); err != nil {
-			return fmt.Errorf("route %w", err)
-		}
-	case game.MapLoadSuccessAction:
-		if err := c.Send(action); err != nil {
-			return fmt.Errorf("route %w", err)
+	types, err := game.LoadUnitTypesFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range []game.UnitTypeIdType{game.HeadquartersType, game.WorkerType} {
+		if _, ok := types.Get(id); !ok {
+			return nil, fmt.Errorf("unit types in %s: missing %s", path, id)
 		}
-		s.game.HandleAction(a, dispatch)
-	default:
-		s.broadcastAll(a)
 	}
-
-	return nil
+	log.Printf("loaded %d unit types from %s", len(types.All()), path)
+	return types, nil
 }